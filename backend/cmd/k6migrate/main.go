@@ -0,0 +1,73 @@
+// k6migrate converts the k6 JavaScript files in the scripts directory
+// into step scripts. Each <id>.js is stored as <id>.json, keeping its ID;
+// scripts that already have a .json file are left alone unless -force
+// is given.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/repository"
+	"k6clone/internal/service"
+)
+
+func main() {
+	dir := flag.String("dir", "./scripts", "scripts directory")
+	force := flag.Bool("force", false, "overwrite scripts that already have a .json file")
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.js"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	repo := repository.NewFileScriptRepository(*dir)
+	scripts := service.NewScriptService(generator.NewHttpGenerator(), repo, repository.NewFileScriptVersionRepository(filepath.Join(*dir, "versions")), nil)
+	parser := generator.NewK6Parser()
+
+	failed := 0
+	for _, path := range files {
+		id := strings.TrimSuffix(filepath.Base(path), ".js")
+
+		if _, err := os.Stat(filepath.Join(*dir, id+".json")); err == nil && !*force {
+			fmt.Printf("skip    %s (already stored)\n", id)
+			continue
+		}
+
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Printf("fail    %s: %v\n", id, err)
+			failed++
+			continue
+		}
+
+		parsed, err := parser.Parse(string(source))
+		if err != nil {
+			fmt.Printf("fail    %s: %v\n", id, err)
+			failed++
+			continue
+		}
+
+		parsed.Script.ID = id
+		if _, err := scripts.Import(parsed.Script); err != nil {
+			fmt.Printf("fail    %s: %v\n", id, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("migrate %s (%d steps, %d VUs, %ds)\n", id, len(parsed.Script.Steps), parsed.Config.VUs, parsed.Config.Duration)
+		for _, w := range parsed.Warnings {
+			fmt.Printf("        %s\n", w)
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
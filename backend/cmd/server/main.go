@@ -3,11 +3,15 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"strings"
 
 	"k6clone/internal/api/handlers"
 	"k6clone/internal/api/middleware"
 	"k6clone/internal/core/engine"
 	"k6clone/internal/core/generator"
+	"k6clone/internal/core/recorder"
+	"k6clone/internal/core/runner"
 	"k6clone/internal/repository"
 	"k6clone/internal/service"
 )
@@ -18,29 +22,56 @@ func main() {
 	// Initialize generators
 	httpGen := generator.NewHttpGenerator()
 	k6JSGen := generator.NewK6JSGenerator()
+	graphqlGen := generator.NewGraphQLGenerator()
+	wsdlGen := generator.NewWSDLGenerator()
+	harGen := generator.NewHARGenerator()
+	openapiGen := generator.NewOpenAPIGenerator()
+	postmanGen := generator.NewPostmanGenerator()
+	curlGen := generator.NewCurlGenerator()
+	jmxGen := generator.NewJMXGenerator()
+	k6Parser := generator.NewK6Parser()
+	logGen := generator.NewAccessLogGenerator()
 
 	// Initialize file-based repositories
 	scriptRepo := repository.NewFileScriptRepository("./scripts")
+	versionRepo := repository.NewFileScriptVersionRepository("./scripts/versions")
 	historyRepo := repository.NewFileTestResultRepository("./scripts/results")
+	protoRepo := repository.NewFileProtoRepository("./scripts/protos")
 
 	// Initialize services
-	scriptService := service.NewScriptService(httpGen, scriptRepo)
+	scriptService := service.NewScriptService(httpGen, scriptRepo, versionRepo, historyRepo)
 
 	// Initialize K6 executor
-	loadEngine := engine.NewLoadEngine()
+	loadEngine := engine.NewLoadEngine("./scripts/protos", "./scripts/data")
 
 
+	// External k6 binary, used for tests run with "engine": "k6"
+	k6Runner := runner.NewK6Runner(os.Getenv("K6_BINARY"), k6JSGen)
+
+	// Progress of k6 runs, for clients as well as the log
+	progressHub := runner.NewProgressHub()
+	logProgress := k6Runner.OnProgress
+	k6Runner.OnProgress = func(p runner.Progress) {
+		logProgress(p)
+		progressHub.Publish(p)
+	}
+
 	// Initialize test service with K6 executor
 	testService := service.NewTestService(
 		scriptRepo,
 		historyRepo,
 		loadEngine,
+		k6Runner,
 	)
 
 	// Initialize handlers
 	scriptHandler := handlers.NewScriptHandler(scriptService, k6JSGen)
 	testHandler := handlers.NewTestHandler(testService)
 	historyHandler := handlers.NewHistoryHandler(historyRepo)
+	progressHandler := handlers.NewProgressHandler(progressHub)
+	protoHandler := handlers.NewProtoHandler(protoRepo)
+	recordingHandler := handlers.NewRecordingHandler(scriptService, recorder.NewRecorder("./scripts/recorder"), harGen)
+	importHandler := handlers.NewImportHandler(scriptService, graphqlGen, wsdlGen, harGen, openapiGen, postmanGen, curlGen, jmxGen, k6Parser, logGen)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -68,10 +99,37 @@ func main() {
 		
 		// Extract ID from path
 		scriptID := r.URL.Path[len("/scripts/"):]
-		
+
+		// Actions on a script, e.g. /scripts/:id/correlations
+		if id, action, ok := strings.Cut(scriptID, "/"); ok {
+			if rest, ok := strings.CutPrefix(action, "versions"); ok && (rest == "" || rest[0] == '/') {
+				scriptHandler.Versions(w, r, id, strings.TrimPrefix(rest, "/"))
+				return
+			}
+
+			var handle func(http.ResponseWriter, *http.Request, string)
+			switch action {
+			case "correlations":
+				handle = scriptHandler.AnalyzeCorrelations
+			case "correlations/apply":
+				handle = scriptHandler.ApplyCorrelations
+			case "clone":
+				handle = scriptHandler.CloneScript
+			default:
+				http.NotFound(w, r)
+				return
+			}
+			postOnly(func(w http.ResponseWriter, r *http.Request) { handle(w, r, id) })(w, r)
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			scriptHandler.GetScriptByID(w, r, scriptID)
+		case http.MethodPut:
+			scriptHandler.UpdateScript(w, r, scriptID)
+		case http.MethodDelete:
+			scriptHandler.DeleteScript(w, r, scriptID)
 		case http.MethodOptions:
 			w.WriteHeader(http.StatusOK)
 		default:
@@ -82,6 +140,23 @@ func main() {
 	// Get generated k6 script
 	mux.HandleFunc("/scripts/k6", scriptHandler.GetK6Script)
 
+	// Import scripts from other formats
+	mux.HandleFunc("/scripts/import/graphql", postOnly(importHandler.ImportGraphQL))
+	mux.HandleFunc("/scripts/import/wsdl", postOnly(importHandler.ImportWSDL))
+	mux.HandleFunc("/scripts/import/k6", postOnly(importHandler.ImportK6))
+	mux.HandleFunc("/scripts/import/har", postOnly(importHandler.ImportHAR))
+	mux.HandleFunc("/scripts/import/openapi", postOnly(importHandler.ImportOpenAPI))
+	mux.HandleFunc("/scripts/import/postman", postOnly(importHandler.ImportPostman))
+	mux.HandleFunc("/scripts/import/curl", postOnly(importHandler.ImportCurl))
+	mux.HandleFunc("/scripts/import/jmx", postOnly(importHandler.ImportJMX))
+	mux.HandleFunc("/scripts/import/accesslog", postOnly(importHandler.ImportAccessLog))
+
+	// Recording proxy
+	mux.HandleFunc("/recordings/start", postOnly(recordingHandler.Start))
+	mux.HandleFunc("/recordings/stop", postOnly(recordingHandler.Stop))
+	mux.HandleFunc("/recordings/status", getOnly(recordingHandler.Status))
+	mux.HandleFunc("/recordings/ca.pem", getOnly(recordingHandler.CACert))
+
 	// Run test
 	mux.HandleFunc("/tests/run", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -94,6 +169,26 @@ func main() {
 		}
 	})
 
+	// Running k6 tests
+	mux.HandleFunc("/tests/running", getOnly(progressHandler.Running))
+
+	// Actions on a test, e.g. /tests/:id/rerun
+	mux.HandleFunc("/tests/", func(w http.ResponseWriter, r *http.Request) {
+		id, action, _ := strings.Cut(r.URL.Path[len("/tests/"):], "/")
+		if id == "" {
+			http.NotFound(w, r)
+			return
+		}
+		switch action {
+		case "rerun":
+			postOnly(func(w http.ResponseWriter, r *http.Request) { testHandler.Rerun(w, r, id) })(w, r)
+		case "progress":
+			getOnly(func(w http.ResponseWriter, r *http.Request) { progressHandler.Stream(w, r, id) })(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
 	// Test history
 	mux.HandleFunc("/history", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -106,6 +201,20 @@ func main() {
 		}
 	})
 
+	// Proto files for GRPC steps
+	mux.HandleFunc("/protos", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			protoHandler.UploadProto(w, r)
+		case http.MethodGet:
+			protoHandler.GetProtos(w, r)
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -118,14 +227,68 @@ func main() {
 	fmt.Println("📊 Results directory: ./scripts/results")
 	fmt.Println("\n📖 API Endpoints:")
 	fmt.Println("   POST   /scripts       - Create new test script")
-	fmt.Println("   GET    /scripts       - List all scripts")
+	fmt.Println("   GET    /scripts       - List scripts (?q=&tag=&project=&sort=&page=&pageSize=)")
 	fmt.Println("   GET    /scripts/:id   - Get specific script")
+	fmt.Println("   PUT    /scripts/:id   - Update script (If-Match: version ETag)")
+	fmt.Println("   DELETE /scripts/:id   - Delete script (?cascade=true removes its results)")
+	fmt.Println("   POST   /scripts/:id/clone              - Copy script under a new ID")
+	fmt.Println("   GET    /scripts/:id/versions           - List saved versions")
+	fmt.Println("   GET    /scripts/:id/versions/:n        - Get one version")
+	fmt.Println("   GET    /scripts/:id/versions/diff      - Diff two versions (?from=1&to=2)")
+	fmt.Println("   POST   /scripts/:id/versions/:n/rollback - Restore a version as the newest")
 	fmt.Println("   GET    /scripts/k6    - Get k6 JavaScript for script")
+	fmt.Println("   POST   /scripts/:id/correlations       - Propose extractions for hardcoded values")
+	fmt.Println("   POST   /scripts/:id/correlations/apply - Rewrite the script with accepted proposals")
+	fmt.Println("   POST   /scripts/import/graphql - Generate script from GraphQL schema")
+	fmt.Println("   POST   /scripts/import/wsdl    - Generate SOAP script from WSDL")
+	fmt.Println("   POST   /scripts/import/k6      - Upload a k6 JavaScript script (?convert=true for steps)")
+	fmt.Println("   POST   /scripts/import/har     - Generate script from a HAR recording")
+	fmt.Println("   POST   /scripts/import/openapi - Generate script from an OpenAPI 3 document")
+	fmt.Println("   POST   /scripts/import/postman - Generate script from a Postman collection")
+	fmt.Println("   POST   /scripts/import/curl    - Generate script from curl commands")
+	fmt.Println("   POST   /scripts/import/jmx     - Generate script from a JMeter test plan")
+	fmt.Println("   POST   /scripts/import/accesslog - Generate script from nginx/Apache access logs")
+	fmt.Println("   POST   /recordings/start  - Start the recording proxy")
+	fmt.Println("   POST   /recordings/stop   - Stop recording and save the script")
+	fmt.Println("   GET    /recordings/status - Current recording session")
+	fmt.Println("   GET    /recordings/ca.pem - CA certificate for recording HTTPS")
 	fmt.Println("   POST   /tests/run     - Execute load test")
+	fmt.Println("   POST   /tests/:id/rerun - Repeat a test with its recorded script and config")
+	fmt.Println("   GET    /tests/running - Progress of running k6 tests")
+	fmt.Println("   GET    /tests/:id/progress - Stream a k6 test's progress (server-sent events)")
 	fmt.Println("   GET    /history       - View test history")
+	fmt.Println("   POST   /protos        - Upload .proto file for gRPC steps")
+	fmt.Println("   GET    /protos        - List uploaded .proto files")
 	fmt.Println("   GET    /health        - Health check")
 
 	if err := http.ListenAndServe(":8080", middleware.CORSMiddleware(mux)); err != nil {
 		panic(err)
 	}
-}
\ No newline at end of file
+}
+
+// postOnly routes POST requests to h and answers CORS preflights
+func postOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			h(w, r)
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func getOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h(w, r)
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
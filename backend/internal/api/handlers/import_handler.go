@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/model"
+	"k6clone/internal/service"
+)
+
+// ImportHandler turns external descriptions of traffic (schemas,
+// recordings, other tools' scripts) into stored scripts
+type ImportHandler struct {
+	service    *service.ScriptService
+	graphqlGen *generator.GraphQLGenerator
+	wsdlGen    *generator.WSDLGenerator
+	harGen     *generator.HARGenerator
+	openapiGen *generator.OpenAPIGenerator
+	postmanGen *generator.PostmanGenerator
+	curlGen    *generator.CurlGenerator
+	jmxGen     *generator.JMXGenerator
+	k6Parser   *generator.K6Parser
+	logGen     *generator.AccessLogGenerator
+}
+
+// maxImportSize caps uploaded documents
+const maxImportSize = 20 << 20
+
+func NewImportHandler(
+	s *service.ScriptService,
+	graphqlGen *generator.GraphQLGenerator,
+	wsdlGen *generator.WSDLGenerator,
+	harGen *generator.HARGenerator,
+	openapiGen *generator.OpenAPIGenerator,
+	postmanGen *generator.PostmanGenerator,
+	curlGen *generator.CurlGenerator,
+	jmxGen *generator.JMXGenerator,
+	k6Parser *generator.K6Parser,
+	logGen *generator.AccessLogGenerator,
+) *ImportHandler {
+	return &ImportHandler{
+		service:    s,
+		graphqlGen: graphqlGen,
+		wsdlGen:    wsdlGen,
+		harGen:     harGen,
+		openapiGen: openapiGen,
+		postmanGen: postmanGen,
+		curlGen:    curlGen,
+		jmxGen:     jmxGen,
+		k6Parser:   k6Parser,
+		logGen:     logGen,
+	}
+}
+
+/*
+POST /scripts/import/graphql
+Body: { "url": "http://localhost:4000/graphql", "schema": {...}, "includeMutations": false }
+"schema" is an optional introspection result; without it the endpoint
+is introspected directly.
+*/
+func (h *ImportHandler) ImportGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string          `json:"url"`
+		Schema json.RawMessage `json:"schema"`
+		generator.GraphQLOptions
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if req.URL == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+
+	var script *model.Script
+	var err error
+	if len(req.Schema) > 0 {
+		script, err = h.graphqlGen.FromIntrospection(req.URL, req.Schema, req.GraphQLOptions)
+	} else {
+		script, err = h.graphqlGen.Introspect(req.URL, req.GraphQLOptions)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.save(w, script)
+}
+
+/*
+POST /scripts/import/wsdl
+Body: raw WSDL 1.1 document
+*/
+func (h *ImportHandler) ImportWSDL(w http.ResponseWriter, r *http.Request) {
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, err := h.wsdlGen.FromWSDL(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.save(w, script)
+}
+
+/*
+POST /scripts/import/har
+Query: includeStatic=false, includeThirdParty=false,
+allowedDomain=cdn.example.com, keepVolatileHeaders=false,
+stripHeader=X-Debug and maxThinkTimeMs=10000.
+Body: raw HAR 1.2 document
+allowedDomain and stripHeader may be repeated.
+*/
+func (h *ImportHandler) ImportHAR(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := generator.HAROptions{
+		IncludeStatic:       q.Get("includeStatic") == "true",
+		IncludeThirdParty:   q.Get("includeThirdParty") == "true",
+		AllowedDomains:      q["allowedDomain"],
+		KeepVolatileHeaders: q.Get("keepVolatileHeaders") == "true",
+		StripHeaders:        q["stripHeader"],
+	}
+	if v := q.Get("maxThinkTimeMs"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "maxThinkTimeMs must be a number", http.StatusBadRequest)
+			return
+		}
+		opts.MaxThinkTimeMs = n
+	}
+
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, err := h.harGen.FromHAR(data, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.save(w, script)
+}
+
+/*
+POST /scripts/import/openapi?tag=pets&operation=getPetById&operation=POST /pets
+Body: raw OpenAPI 3 document, JSON or YAML
+tag and operation may be repeated; without either, every operation is
+imported.
+Response: { "script": {...}, "warnings": ["..."] }
+*/
+func (h *ImportHandler) ImportOpenAPI(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := generator.OpenAPIOptions{
+		Tags:       q["tag"],
+		Operations: q["operation"],
+	}
+
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, warnings, err := h.openapiGen.FromOpenAPI(data, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script":   saved,
+		"warnings": warnings,
+	})
+}
+
+/*
+POST /scripts/import/postman
+Body: raw Postman Collection v2.1, or
+{ "collection": {...}, "environment": {...} } to add an environment
+Response: { "script": {...}, "warnings": ["..."] }
+*/
+func (h *ImportHandler) ImportPostman(w http.ResponseWriter, r *http.Request) {
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Collection  json.RawMessage `json:"collection"`
+		Environment json.RawMessage `json:"environment"`
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Collection) == 0 {
+		req.Collection = data
+	}
+
+	script, warnings, err := h.postmanGen.FromPostman(req.Collection, req.Environment)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if warnings == nil {
+		warnings = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script":   saved,
+		"warnings": warnings,
+	})
+}
+
+/*
+POST /scripts/import/curl
+Body: one or more curl commands as plain text, e.g. from the browser's
+"Copy as cURL"; each command becomes a step
+*/
+func (h *ImportHandler) ImportCurl(w http.ResponseWriter, r *http.Request) {
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, err := h.curlGen.FromCurl(string(data))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.save(w, script)
+}
+
+/*
+POST /scripts/import/jmx
+Body: raw JMeter .jmx test plan
+Response: { "script": {...}, "report": { "unsupported": [...] } }
+CSV files used by the plan are expected in the data directory under
+their file names.
+*/
+func (h *ImportHandler) ImportJMX(w http.ResponseWriter, r *http.Request) {
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, report, err := h.jmxGen.FromJMX(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script": saved,
+		"report": report,
+	})
+}
+
+/*
+POST /scripts/import/k6?convert=true
+Body: raw k6 JavaScript; the script runs in the engine's JS runtime.
+With convert=true the script is parsed into steps instead, and the
+response also carries the test config taken from its options and
+warnings for the parts that could not be converted.
+*/
+func (h *ImportHandler) ImportK6(w http.ResponseWriter, r *http.Request) {
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	if r.URL.Query().Get("convert") != "true" {
+		h.save(w, &model.Script{Source: string(data)})
+		return
+	}
+
+	parsed, err := h.k6Parser.Parse(string(data))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(parsed.Script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	parsed.Config.ScriptID = saved.ID
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script":   saved,
+		"config":   parsed.Config,
+		"warnings": parsed.Warnings,
+	})
+}
+
+/*
+POST /scripts/import/accesslog
+Query: baseUrl=https://staging.example.com, top=20, replay=false,
+speed=1, maxReplay=10000 and includeStatic=false.
+Body: raw nginx or Apache access log in common or combined format
+By default the script is a weighted mix of the top endpoints; with
+replay=true it sends every request at its logged time, sped up by speed.
+*/
+func (h *ImportHandler) ImportAccessLog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := generator.AccessLogOptions{
+		BaseURL:       q.Get("baseUrl"),
+		Replay:        q.Get("replay") == "true",
+		IncludeStatic: q.Get("includeStatic") == "true",
+	}
+	for name, dst := range map[string]*int{"top": &opts.Top, "maxReplay": &opts.MaxReplay} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, name+" must be a number", http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+	if v := q.Get("speed"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 {
+			http.Error(w, "speed must be a positive number", http.StatusBadRequest)
+			return
+		}
+		opts.Speed = f
+	}
+
+	data, ok := readUpload(w, r)
+	if !ok {
+		return
+	}
+
+	script, report, err := h.logGen.FromAccessLog(data, opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script": saved,
+		"report": report,
+	})
+}
+
+// readUpload reads a raw document body, answering the request itself
+// when the body is missing or too large
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxImportSize+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	if len(data) > maxImportSize {
+		http.Error(w, "document too large", http.StatusRequestEntityTooLarge)
+		return nil, false
+	}
+	if len(data) == 0 {
+		http.Error(w, "body is required", http.StatusBadRequest)
+		return nil, false
+	}
+	return data, true
+}
+
+func (h *ImportHandler) save(w http.ResponseWriter, script *model.Script) {
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"k6clone/internal/core/runner"
+)
+
+// ProgressHandler lets clients follow tests run with the k6 engine
+type ProgressHandler struct {
+	hub *runner.ProgressHub
+}
+
+func NewProgressHandler(hub *runner.ProgressHub) *ProgressHandler {
+	return &ProgressHandler{hub: hub}
+}
+
+/*
+GET /tests/running
+Latest progress of every running k6 test, to find the test ID of a run
+started with POST /tests/run
+*/
+func (h *ProgressHandler) Running(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.hub.Running())
+}
+
+/*
+GET /tests/:id/progress
+Server-sent events: a "progress" event about once a second while the
+test runs, then a "done" event with the final counts.
+*/
+func (h *ProgressHandler) Stream(w http.ResponseWriter, r *http.Request, testID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	updates, cancel, ok := h.hub.Subscribe(testID)
+	if !ok {
+		http.Error(w, "test is not running", http.StatusNotFound)
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	for {
+		select {
+		case p, open := <-updates:
+			if !open {
+				return
+			}
+			data, err := json.Marshal(p)
+			if err != nil {
+				return
+			}
+			event := "progress"
+			if p.Done {
+				event = "done"
+			}
+			w.Write([]byte("event: " + event + "\ndata: " + string(data) + "\n\n"))
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"k6clone/internal/repository"
+)
+
+const maxProtoSize = 1 << 20
+
+type ProtoHandler struct {
+	repo repository.ProtoRepository
+}
+
+func NewProtoHandler(r repository.ProtoRepository) *ProtoHandler {
+	return &ProtoHandler{repo: r}
+}
+
+/*
+POST /protos?name=helloworld.proto
+Body: raw .proto source
+*/
+func (h *ProtoHandler) UploadProto(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r.Body, maxProtoSize+1))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if len(content) > maxProtoSize {
+		http.Error(w, "proto file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err := h.repo.Save(name, content); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"name": name})
+}
+
+/*
+GET /protos
+*/
+func (h *ProtoHandler) GetProtos(w http.ResponseWriter, r *http.Request) {
+	names, err := h.repo.FindAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Never return null arrays
+	if names == nil {
+		names = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(names)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/recorder"
+	"k6clone/internal/service"
+)
+
+// RecordingHandler manages recording sessions: a proxy that captures
+// browser or test traffic and turns it into a script when stopped
+type RecordingHandler struct {
+	service  *service.ScriptService
+	recorder *recorder.Recorder
+	harGen   *generator.HARGenerator
+}
+
+func NewRecordingHandler(s *service.ScriptService, rec *recorder.Recorder, harGen *generator.HARGenerator) *RecordingHandler {
+	return &RecordingHandler{service: s, recorder: rec, harGen: harGen}
+}
+
+/*
+POST /recordings/start
+Body (optional): { "addr": "127.0.0.1:8888", "hosts": ["example.com"], "insecure": false }
+Only requests to "hosts" and their subdomains are recorded; other
+traffic still passes through the proxy. The proxy only listens on a
+loopback address; any other "addr" is rejected with 400. 409 means a
+recording is already running.
+*/
+func (h *RecordingHandler) Start(w http.ResponseWriter, r *http.Request) {
+	var opts recorder.Options
+	if err := decodeOptional(r, &opts); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.recorder.Start(opts)
+	switch {
+	case errors.Is(err, recorder.ErrRecording):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, recorder.ErrAddr):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+/*
+POST /recordings/stop
+Body (optional): the HAR import filters, e.g.
+{ "includeStatic": false, "includeThirdParty": false, "maxThinkTimeMs": 10000 }
+Stops the proxy and saves the recording as a script. The recorded hosts
+count as first-party. If the recording cannot be converted or saved it
+is kept, and calling stop again, e.g. with other filters, retries.
+*/
+func (h *RecordingHandler) Stop(w http.ResponseWriter, r *http.Request) {
+	var opts generator.HAROptions
+	if err := decodeOptional(r, &opts); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	har, status, err := h.recorder.Stop()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.AllowedDomains = append(opts.AllowedDomains, status.Hosts...)
+
+	// The recording is kept until it is saved, so a failed conversion
+	// can be retried with other filters
+	script, err := h.harGen.FromHAR(har, opts)
+	if err != nil {
+		http.Error(w, err.Error()+"; the recording is kept, stop again to retry", http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.service.Import(script)
+	if err != nil {
+		http.Error(w, err.Error()+"; the recording is kept, stop again to retry", http.StatusBadRequest)
+		return
+	}
+	h.recorder.Discard()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script": saved,
+		"status": status,
+	})
+}
+
+// GET /recordings/status
+func (h *RecordingHandler) Status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.recorder.Status())
+}
+
+/*
+GET /recordings/ca.pem
+The certificate browsers and test clients must trust for HTTPS to be
+recorded.
+*/
+func (h *RecordingHandler) CACert(w http.ResponseWriter, r *http.Request) {
+	cert, err := h.recorder.CACert()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.Header().Set("Content-Disposition", `attachment; filename="k6clone-recorder-ca.pem"`)
+	w.Write(cert)
+}
+
+// decodeOptional decodes a JSON body, leaving v alone when there is none
+func decodeOptional(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
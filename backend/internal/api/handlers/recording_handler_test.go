@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/recorder"
+)
+
+func TestStartRecording(t *testing.T) {
+	rec := recorder.NewRecorder(t.TempDir())
+	h := NewRecordingHandler(nil, rec, generator.NewHARGenerator())
+
+	start := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.Start(w, httptest.NewRequest(http.MethodPost, "/recordings/start", strings.NewReader(body)))
+		return w
+	}
+
+	// Taken, so listening on it fails
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	for _, tt := range []struct {
+		addr string
+		want int
+	}{
+		{"0.0.0.0:0", http.StatusBadRequest},
+		{":0", http.StatusBadRequest},
+		{"[::]:0", http.StatusBadRequest},
+		{"192.0.2.1:0", http.StatusBadRequest},
+		{"example.com:0", http.StatusBadRequest},
+		{"127.0.0.1", http.StatusBadRequest},
+		{busy.Addr().String(), http.StatusInternalServerError},
+	} {
+		if w := start(`{"addr": "` + tt.addr + `"}`); w.Code != tt.want {
+			t.Errorf("addr %s = %d %s, want %d", tt.addr, w.Code, w.Body, tt.want)
+		}
+	}
+	if rec.Status().Recording {
+		t.Fatal("a rejected start left a recording running")
+	}
+
+	w := start(`{"addr": "127.0.0.1:0"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("start on loopback = %d %s", w.Code, w.Body)
+	}
+	defer func() {
+		rec.Stop()
+		rec.Discard()
+	}()
+
+	var status recorder.Status
+	json.NewDecoder(w.Body).Decode(&status)
+	if host, _, _ := net.SplitHostPort(status.Addr); !status.Recording || host != "127.0.0.1" {
+		t.Errorf("status = %+v, want recording on 127.0.0.1", status)
+	}
+
+	if w := start(`{"addr": "localhost:0"}`); w.Code != http.StatusConflict {
+		t.Errorf("second start = %d %s, want 409", w.Code, w.Body)
+	}
+}
@@ -2,10 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"k6clone/internal/core/engine"
 	"k6clone/internal/core/generator"
 	"k6clone/internal/core/model"
+	"k6clone/internal/repository"
 	"k6clone/internal/service"
 )
 
@@ -27,6 +32,8 @@ func NewScriptHandler(
 /*
 POST /scripts
 Body: { "url": "https://example.com" }
+With "crawl": { "maxDepth": 2, "maxPages": 20, "includeAssets": false }
+the site is crawled from the URL into a multi-step browsing script.
 */
 func (h *ScriptHandler) CreateScript(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -35,7 +42,8 @@ func (h *ScriptHandler) CreateScript(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		URL string `json:"url"`
+		URL   string                  `json:"url"`
+		Crawl *generator.CrawlOptions `json:"crawl"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -48,7 +56,13 @@ func (h *ScriptHandler) CreateScript(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	script, err := h.service.CreateFromURL(req.URL)
+	var script *model.Script
+	var err error
+	if req.Crawl != nil {
+		script, err = h.service.CreateFromCrawl(req.URL, *req.Crawl)
+	} else {
+		script, err = h.service.CreateFromURL(req.URL)
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -60,6 +74,10 @@ func (h *ScriptHandler) CreateScript(w http.ResponseWriter, r *http.Request) {
 
 /*
 GET /scripts
+Query: q (search in name, description and tags), tag, project, owner,
+sort (name, createdAt or updatedAt; "-" for descending, default
+-updatedAt), page (from 1) and pageSize (all when unset).
+The number of matching scripts is in the X-Total-Count header.
 */
 func (h *ScriptHandler) GetAllScripts(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -67,11 +85,46 @@ func (h *ScriptHandler) GetAllScripts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	scripts, err := h.service.GetAll()
+	query := r.URL.Query()
+	q := repository.ScriptQuery{
+		Search:  query.Get("q"),
+		Tag:     query.Get("tag"),
+		Project: query.Get("project"),
+		Owner:   query.Get("owner"),
+		Sort:    query.Get("sort"),
+	}
+
+	page, pageSize := 1, 0
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "page must be a positive number", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+	if v := query.Get("pageSize"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "pageSize must be a positive number", http.StatusBadRequest)
+			return
+		}
+		pageSize = n
+	}
+	if pageSize > 0 {
+		q.Offset, q.Limit = (page-1)*pageSize, pageSize
+	}
+
+	scripts, total, err := h.service.List(q)
+	if errors.Is(err, repository.ErrInvalidSort) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 
 	// Never return null arrays
 	if scripts == nil {
@@ -84,6 +137,7 @@ func (h *ScriptHandler) GetAllScripts(w http.ResponseWriter, r *http.Request) {
 
 /*
 GET /scripts/:id
+The ETag header carries the script's version for use in If-Match.
 */
 func (h *ScriptHandler) GetScriptByID(w http.ResponseWriter, r *http.Request, id string) {
 	if id == "" {
@@ -98,9 +152,178 @@ func (h *ScriptHandler) GetScriptByID(w http.ResponseWriter, r *http.Request, id
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(script.Version))
 	json.NewEncoder(w).Encode(script)
 }
 
+/*
+PUT /scripts/:id
+Body: the edited script
+The version it was edited from comes from the If-Match header (the
+ETag of GET /scripts/:id) or else the script's "version" field. If the
+script has been saved since, nothing is written and the response is
+412 for If-Match or 409 for the field. An X-Author header names who
+made the change in the version history.
+*/
+func (h *ScriptHandler) UpdateScript(w http.ResponseWriter, r *http.Request, id string) {
+	var script model.Script
+	if err := json.NewDecoder(r.Body).Decode(&script); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	expected, conflict := script.Version, http.StatusConflict
+	if match := r.Header.Get("If-Match"); match != "" {
+		v, ok := parseVersionETag(match)
+		if !ok {
+			http.Error(w, "If-Match must be a script ETag", http.StatusBadRequest)
+			return
+		}
+		expected, conflict = v, http.StatusPreconditionFailed
+	}
+
+	updated, err := h.service.Update(id, &script, expected, r.Header.Get("X-Author"))
+	if err != nil {
+		h.writeSaveError(w, id, err, conflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(updated.Version))
+	json.NewEncoder(w).Encode(updated)
+}
+
+/*
+DELETE /scripts/:id
+DELETE /scripts/:id?cascade=true also deletes the script's test results
+*/
+func (h *ScriptHandler) DeleteScript(w http.ResponseWriter, r *http.Request, id string) {
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+
+	deleted, err := h.service.Delete(id, cascade)
+	if errors.Is(err, repository.ErrScriptNotFound) {
+		http.Error(w, "script not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":             id,
+		"deleted":        true,
+		"resultsDeleted": deleted,
+	})
+}
+
+/*
+POST /scripts/:id/clone
+Stores a copy of the script under a new ID and returns it
+*/
+func (h *ScriptHandler) CloneScript(w http.ResponseWriter, r *http.Request, id string) {
+	script, err := h.service.Clone(id, r.Header.Get("X-Author"))
+	if errors.Is(err, repository.ErrScriptNotFound) {
+		http.Error(w, "script not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(script.Version))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(script)
+}
+
+// writeSaveError reports a failed update; a version conflict is
+// answered with the current version's ETag
+func (h *ScriptHandler) writeSaveError(w http.ResponseWriter, id string, err error, conflict int) {
+	switch {
+	case errors.Is(err, repository.ErrScriptNotFound):
+		http.Error(w, "script not found", http.StatusNotFound)
+	case errors.Is(err, repository.ErrVersionConflict):
+		current := 0
+		if stored, err := h.service.GetByID(id); err == nil {
+			current = stored.Version
+		}
+		w.Header().Set("ETag", versionETag(current))
+		http.Error(w, err.Error()+"; reload version "+strconv.Itoa(current)+" and reapply your changes", conflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func parseVersionETag(tag string) (int, bool) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	v, err := strconv.Atoi(strings.Trim(tag, `"`))
+	return v, err == nil
+}
+
+/*
+POST /scripts/:id/correlations
+Runs the script once and proposes extraction rules for values its
+requests hardcode, such as session IDs, CSRF tokens and created IDs.
+*/
+func (h *ScriptHandler) AnalyzeCorrelations(w http.ResponseWriter, r *http.Request, id string) {
+	if _, err := h.service.GetByID(id); err != nil {
+		http.Error(w, "script not found", http.StatusNotFound)
+		return
+	}
+
+	report, err := h.service.Correlate(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+/*
+POST /scripts/:id/correlations/apply
+Body: { "proposals": [ ...accepted proposals from the analysis... ] }
+Adds the extractions and replaces the hardcoded values with {{var}}.
+*/
+func (h *ScriptHandler) ApplyCorrelations(w http.ResponseWriter, r *http.Request, id string) {
+	var req struct {
+		Proposals []engine.Correlation `json:"proposals"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if len(req.Proposals) == 0 {
+		http.Error(w, "proposals are required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.GetByID(id); err != nil {
+		http.Error(w, "script not found", http.StatusNotFound)
+		return
+	}
+
+	script, replaced, err := h.service.ApplyCorrelations(id, req.Proposals)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"script":   script,
+		"replaced": replaced,
+	})
+}
+
 /*
 GET /scripts/k6?id=<scriptId>
 Returns plain text k6 script
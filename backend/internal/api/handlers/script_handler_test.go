@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/model"
+	"k6clone/internal/repository"
+	"k6clone/internal/service"
+)
+
+func newScriptHandler(t *testing.T) (*ScriptHandler, *service.ScriptService, *repository.MemoryTestResultRepository) {
+	t.Helper()
+
+	results := repository.NewMemoryTestResultRepository()
+	svc := service.NewScriptService(
+		generator.NewHttpGenerator(),
+		repository.NewMemoryScriptRepository(),
+		repository.NewMemoryScriptVersionRepository(),
+		results,
+	)
+	return NewScriptHandler(svc, generator.NewK6JSGenerator()), svc, results
+}
+
+func importScript(t *testing.T, svc *service.ScriptService, id string) *model.Script {
+	t.Helper()
+
+	script, err := svc.Import(&model.Script{
+		ID:    id,
+		Name:  "orders",
+		Steps: []model.Step{{Type: model.HTTP, Method: "GET", URL: "http://localhost/orders"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+// putScript sends PUT /scripts/:id with the script renamed to name
+func putScript(h *ScriptHandler, id, name string, version int, ifMatch string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(model.Script{
+		Name:    name,
+		Version: version,
+		Steps:   []model.Step{{Type: model.HTTP, Method: "GET", URL: "http://localhost/orders"}},
+	})
+	req := httptest.NewRequest(http.MethodPut, "/scripts/"+id, strings.NewReader(string(body)))
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	h.UpdateScript(w, req, id)
+	return w
+}
+
+func TestUpdateScriptVersionConflicts(t *testing.T) {
+	h, svc, _ := newScriptHandler(t)
+	importScript(t, svc, "s1")
+
+	w := putScript(h, "s1", "first", 0, `"1"`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update from the current ETag = %d %s", w.Code, w.Body)
+	}
+	if etag := w.Header().Get("ETag"); etag != `"2"` {
+		t.Errorf("ETag = %s, want \"2\"", etag)
+	}
+
+	tests := []struct {
+		name    string
+		version int
+		ifMatch string
+		want    int
+	}{
+		{"stale If-Match", 0, `"1"`, http.StatusPreconditionFailed},
+		{"stale weak If-Match", 0, `W/"1"`, http.StatusPreconditionFailed},
+		{"stale body version", 1, "", http.StatusConflict},
+		{"If-Match wins over the body", 2, `"1"`, http.StatusPreconditionFailed},
+		{"bad If-Match", 2, "latest", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := putScript(h, "s1", "stale", tt.version, tt.ifMatch)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body, tt.want)
+			}
+			if tt.want != http.StatusBadRequest {
+				if etag := w.Header().Get("ETag"); etag != `"2"` {
+					t.Errorf("ETag = %s, want the current version \"2\"", etag)
+				}
+			}
+		})
+	}
+
+	stored, err := svc.GetByID("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Name != "first" || stored.Version != 2 {
+		t.Errorf("stored %q version %d, want first version 2", stored.Name, stored.Version)
+	}
+
+	if w := putScript(h, "s1", "second", 2, ""); w.Code != http.StatusOK {
+		t.Errorf("update from the current body version = %d %s", w.Code, w.Body)
+	}
+	if w := putScript(h, "nope", "x", 1, ""); w.Code != http.StatusNotFound {
+		t.Errorf("update of a missing script = %d, want 404", w.Code)
+	}
+}
+
+func TestDeleteScriptCascade(t *testing.T) {
+	h, svc, results := newScriptHandler(t)
+	importScript(t, svc, "kept")
+	importScript(t, svc, "cascaded")
+	for _, id := range []string{"kept", "kept", "cascaded", "cascaded", "other"} {
+		results.Save(model.TestResult{TestID: id + "-run", ScriptID: id})
+	}
+
+	del := func(target, id string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		h.DeleteScript(w, httptest.NewRequest(http.MethodDelete, target, nil), id)
+		var body map[string]interface{}
+		json.NewDecoder(w.Body).Decode(&body)
+		return w.Code, body
+	}
+
+	code, body := del("/scripts/kept", "kept")
+	if code != http.StatusOK || body["resultsDeleted"] != float64(0) {
+		t.Errorf("delete = %d %v, want 200 with no results deleted", code, body)
+	}
+	if got := len(results.FindByScriptID("kept")); got != 2 {
+		t.Errorf("%d results left for kept, want 2", got)
+	}
+
+	code, body = del("/scripts/cascaded?cascade=true", "cascaded")
+	if code != http.StatusOK || body["resultsDeleted"] != float64(2) {
+		t.Errorf("cascade delete = %d %v, want 200 with 2 results deleted", code, body)
+	}
+	if got := len(results.FindByScriptID("cascaded")); got != 0 {
+		t.Errorf("%d results left for cascaded, want 0", got)
+	}
+	if got := len(results.FindAll()); got != 3 {
+		t.Errorf("%d results left in total, want 3", got)
+	}
+
+	for _, id := range []string{"kept", "cascaded"} {
+		if _, err := svc.GetByID(id); err == nil {
+			t.Errorf("script %s still stored", id)
+		}
+	}
+	if code, _ := del("/scripts/kept?cascade=true", "kept"); code != http.StatusNotFound {
+		t.Errorf("deleting again = %d, want 404", code)
+	}
+}
+
+func TestGetAllScriptsPages(t *testing.T) {
+	h, svc, _ := newScriptHandler(t)
+	for _, s := range []struct{ id, project, tag string }{
+		{"a", "shop", "smoke"},
+		{"b", "shop/web", "smoke"},
+		{"c", "shop", "load"},
+		{"d", "api", "smoke"},
+		{"e", "shop", "smoke"},
+	} {
+		if _, err := svc.Import(&model.Script{
+			ID:      s.id,
+			Name:    "script " + s.id,
+			Project: s.project,
+			Tags:    []string{s.tag},
+			Steps:   []model.Step{{Type: model.HTTP, Method: "GET", URL: "http://localhost/"}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		ids   []string
+		total string
+	}{
+		{"?sort=-name", []string{"e", "d", "c", "b", "a"}, "5"},
+		{"?sort=name&pageSize=2", []string{"a", "b"}, "5"},
+		{"?sort=name&pageSize=2&page=3", []string{"e"}, "5"},
+		{"?sort=name&pageSize=2&page=4", []string{}, "5"},
+		{"?project=shop&tag=smoke&sort=-name&pageSize=2", []string{"e", "b"}, "3"},
+		{"?project=shop&tag=smoke&sort=-name&pageSize=2&page=2", []string{"a"}, "3"},
+		{"?tag=load", []string{"c"}, "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.GetAllScripts(w, httptest.NewRequest(http.MethodGet, "/scripts"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d %s", w.Code, w.Body)
+			}
+
+			var scripts []model.Script
+			if err := json.NewDecoder(w.Body).Decode(&scripts); err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, s := range scripts {
+				ids = append(ids, s.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.ids, ",") {
+				t.Errorf("scripts = %v, want %v", ids, tt.ids)
+			}
+			if got := w.Header().Get("X-Total-Count"); got != tt.total {
+				t.Errorf("X-Total-Count = %s, want %s", got, tt.total)
+			}
+		})
+	}
+
+	for _, query := range []string{"?sort=owner", "?page=0&pageSize=2", "?pageSize=x"} {
+		w := httptest.NewRecorder()
+		h.GetAllScripts(w, httptest.NewRequest(http.MethodGet, "/scripts"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k6clone/internal/core/model"
+	"k6clone/internal/repository"
+)
+
+/*
+Version history of a script:
+
+GET  /scripts/:id/versions                  - list versions, without their content
+GET  /scripts/:id/versions/:n               - one version with its script
+GET  /scripts/:id/versions/diff?from=1&to=3 - changes between two versions
+POST /scripts/:id/versions/:n/rollback      - save version n as the next version
+
+The diff's "to" defaults to the current version. Rollback honours
+If-Match like PUT /scripts/:id.
+
+path is what follows /scripts/:id/versions/.
+*/
+func (h *ScriptHandler) Versions(w http.ResponseWriter, r *http.Request, id, path string) {
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.listVersions(w, id)
+		return
+	}
+	if path == "diff" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.diffVersions(w, r, id)
+		return
+	}
+
+	n, action, _ := strings.Cut(path, "/")
+	version, err := strconv.Atoi(n)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.getVersion(w, id, version)
+	case action == "rollback" && r.Method == http.MethodPost:
+		h.rollback(w, r, id, version)
+	case action == "" || action == "rollback":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *ScriptHandler) listVersions(w http.ResponseWriter, id string) {
+	versions, err := h.service.Versions(id)
+	if errors.Is(err, repository.ErrScriptNotFound) {
+		http.Error(w, "script not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The content is fetched one version at a time
+	list := make([]model.ScriptVersion, len(versions))
+	for i, v := range versions {
+		v.Script = nil
+		list[i] = v
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func (h *ScriptHandler) getVersion(w http.ResponseWriter, id string, version int) {
+	v, err := h.service.Version(id, version)
+	if errors.Is(err, repository.ErrScriptNotFound) || errors.Is(err, repository.ErrVersionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *ScriptHandler) diffVersions(w http.ResponseWriter, r *http.Request, id string) {
+	q := r.URL.Query()
+	from, err := strconv.Atoi(q.Get("from"))
+	if err != nil || from < 0 {
+		http.Error(w, "from must be a version number", http.StatusBadRequest)
+		return
+	}
+	to := 0
+	if q.Get("to") != "" {
+		if to, err = strconv.Atoi(q.Get("to")); err != nil || to <= 0 {
+			http.Error(w, "to must be a version number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	diff, err := h.service.Diff(id, from, to)
+	if errors.Is(err, repository.ErrScriptNotFound) || errors.Is(err, repository.ErrVersionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(diff)
+}
+
+func (h *ScriptHandler) rollback(w http.ResponseWriter, r *http.Request, id string, version int) {
+	expected := 0
+	if match := r.Header.Get("If-Match"); match != "" {
+		v, ok := parseVersionETag(match)
+		if !ok {
+			http.Error(w, "If-Match must be a script ETag", http.StatusBadRequest)
+			return
+		}
+		expected = v
+	}
+
+	script, err := h.service.Rollback(id, version, expected, r.Header.Get("X-Author"))
+	if errors.Is(err, repository.ErrVersionNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.writeSaveError(w, id, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", versionETag(script.Version))
+	json.NewEncoder(w).Encode(script)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"k6clone/internal/core/model"
@@ -33,12 +34,14 @@ func (h *TestHandler) RunTest(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "scriptId is required", http.StatusBadRequest)
 		return
 	}
-	if config.VUs <= 0 {
-		http.Error(w, "vus must be greater than 0", http.StatusBadRequest)
+	// Zero means "use the script's options" for k6 scripts; the
+	// service rejects it for step scripts
+	if config.VUs < 0 {
+		http.Error(w, "vus must not be negative", http.StatusBadRequest)
 		return
 	}
-	if config.Duration <= 0 {
-		http.Error(w, "duration must be greater than 0", http.StatusBadRequest)
+	if config.Duration < 0 {
+		http.Error(w, "duration must not be negative", http.StatusBadRequest)
 		return
 	}
 
@@ -50,4 +53,24 @@ func (h *TestHandler) RunTest(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
-}
\ No newline at end of file
+}
+
+/*
+POST /tests/:id/rerun
+Runs a stored test again with the script and config snapshot taken
+when it first ran
+*/
+func (h *TestHandler) Rerun(w http.ResponseWriter, r *http.Request, testID string) {
+	result, err := h.service.Rerun(testID)
+	if errors.Is(err, service.ErrTestNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -7,7 +7,8 @@ func CORSMiddleware(next http.Handler) http.Handler {
 
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, X-Author")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Total-Count")
 
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"k6clone/internal/core/model"
+)
+
+// validateAuth checks the credentials a step carries
+func validateAuth(auth *model.Auth) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case model.BasicAuth:
+		if auth.Username == "" {
+			return errors.New("basic auth username is empty")
+		}
+	case model.BearerAuth:
+		if auth.Token == "" {
+			return errors.New("bearer auth token is empty")
+		}
+	case model.APIKeyAuth:
+		if auth.Key == "" {
+			return errors.New("api key auth key is empty")
+		}
+		if auth.In != "" && auth.In != "header" && auth.In != "query" {
+			return errors.New("api key auth must be sent in header or query")
+		}
+	default:
+		return errors.New("unsupported auth type: " + string(auth.Type))
+	}
+	return nil
+}
+
+// keepPlaceholders is an expand function that leaves values as they are
+func keepPlaceholders(s string) string { return s }
+
+// ApplyAuth returns a copy of a step with its credentials turned into a
+// header or query parameter. Credentials are resolved with expand
+// first, since basic auth encodes them.
+func ApplyAuth(step *model.Step, expand func(string) string) *model.Step {
+	auth := step.Auth
+	if auth == nil {
+		return step
+	}
+
+	out := *step
+	out.Auth = nil
+	out.Header = make(map[string]string, len(step.Header)+1)
+	for k, v := range step.Header {
+		out.Header[k] = v
+	}
+
+	switch auth.Type {
+	case model.BasicAuth:
+		creds := expand(auth.Username) + ":" + expand(auth.Password)
+		out.Header["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(creds))
+	case model.BearerAuth:
+		out.Header["Authorization"] = "Bearer " + expand(auth.Token)
+	case model.APIKeyAuth:
+		if auth.In == "query" {
+			sep := "?"
+			if strings.Contains(out.URL, "?") {
+				sep = "&"
+			}
+			out.URL += sep + url.QueryEscape(auth.Key) + "=" + url.QueryEscape(expand(auth.Value))
+		} else {
+			out.Header[auth.Key] = expand(auth.Value)
+		}
+	}
+	return &out
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+func init() {
+	Register(model.Batch, batchExecutor{})
+}
+
+type batchExecutor struct{}
+
+func (batchExecutor) Validate(step *model.Step) error {
+	batch := step.Batch
+	if batch == nil || len(batch.Requests) == 0 {
+		return errors.New("batch step has no requests")
+	}
+	if batch.Parallelism < 0 {
+		return errors.New("batch parallelism must not be negative")
+	}
+
+	for i := range batch.Requests {
+		req := &batch.Requests[i]
+		if req.Type != "" && req.Type != model.HTTP {
+			return errors.New("batch requests must be HTTP steps")
+		}
+		if err := (httpExecutor{}).Validate(req); err != nil {
+			return err
+		}
+		if err := validateAuth(req.Auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run fires the batch's requests concurrently, at most Parallelism at
+// a time. Each sub-request counts as a regular request and is also
+// tracked under its own tagged trend.
+func (batchExecutor) Run(v *VU, index int, step *model.Step) {
+	batch := step.Batch
+	if batch == nil || len(batch.Requests) == 0 {
+		return
+	}
+	stepTag := strconv.Itoa(index + 1)
+
+	limit := batch.Parallelism
+	if limit <= 0 || limit > len(batch.Requests) {
+		limit = len(batch.Requests)
+	}
+	sem := make(chan struct{}, limit)
+
+	// Resolve placeholders up front; the VU's variables are not safe
+	// to read from the request goroutines
+	reqs := make([]*model.Step, len(batch.Requests))
+	for i := range batch.Requests {
+		reqs[i] = v.ExpandStep(ApplyAuth(&batch.Requests[i], v.Expand))
+	}
+
+	start := time.Now()
+	wg := sync.WaitGroup{}
+
+	for i := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			latency, ok := DoHTTP(v.Client, reqs[i])
+			v.AddRequest(latency, ok)
+			v.AddDuration(MetricName("batch_req_duration",
+				"step", stepTag, "req", strconv.Itoa(i+1)), latency)
+		}(i)
+	}
+
+	wg.Wait()
+
+	v.AddDuration(MetricName("batch_duration", "step", stepTag), time.Since(start))
+}
+
+// K6JS emits http.batch. k6 only supports a global batch limit, so the
+// step's parallelism becomes the "batch" option.
+func (batchExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: batch of %d requests\n", index+1, len(step.Batch.Requests))
+	fmt.Fprintf(&b, "  const res%d = http.batch([\n", index)
+	for _, req := range step.Batch.Requests {
+		body := "null"
+		if req.Body != "" {
+			body = jsValue(req.Body)
+		}
+		fmt.Fprintf(&b, "    [%s, %s, %s", jsValue(req.Method), jsValue(req.URL), body)
+		if len(req.Header) > 0 {
+			fmt.Fprintf(&b, ", { headers: %s }", jsValue(req.Header))
+		}
+		b.WriteString("],\n")
+	}
+	b.WriteString("  ]);\n")
+	fmt.Fprintf(&b, "  for (const r of res%d) {\n", index)
+	b.WriteString("    check(r, {\n")
+	b.WriteString("      \"status is 2xx\": (r) => r.status >= 200 && r.status < 300,\n")
+	b.WriteString("    });\n")
+	b.WriteString("  }\n")
+
+	snippet := K6Snippet{Code: b.String()}
+	if step.Batch.Parallelism > 0 {
+		snippet.Options = map[string]int{"batch": step.Batch.Parallelism}
+	}
+	return snippet, nil
+}
@@ -0,0 +1,796 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+const (
+	// minCorrelatedLen keeps short values such as "en" or "true" from
+	// matching by accident
+	minCorrelatedLen = 4
+
+	// maxContext bounds the text around a value used to build a regex
+	maxContext = 40
+)
+
+// Correlation proposes replacing a value hardcoded in later requests
+// with one extracted from an earlier response
+type Correlation struct {
+	// Step is the index of the step whose response has the value
+	Step       int              `json:"step"`
+	Extraction model.Extraction `json:"extraction"`
+
+	// Original is the hardcoded value; Value is what the analysis run
+	// returned in its place
+	Original string `json:"original"`
+	Value    string `json:"value"`
+
+	UsedBy []CorrelationUse `json:"usedBy"`
+
+	// Confidence is "high" when the value itself was found in the
+	// response, "medium" when a field of the same name and shape was
+	Confidence string `json:"confidence"`
+}
+
+// CorrelationUse is one place a correlated value is sent
+type CorrelationUse struct {
+	Step int `json:"step"`
+
+	// Location is "path" for a path segment, "query:name" or
+	// "body:name" for a query, form or JSON value, "header:Name" or
+	// "auth" for the step's bearer token
+	Location string `json:"location"`
+}
+
+// CorrelationReport is the result of analyzing a script
+type CorrelationReport struct {
+	Proposals []Correlation `json:"proposals"`
+
+	// Skipped lists steps the analysis did not run; only HTTP steps
+	// are analyzed
+	Skipped []int `json:"skipped"`
+
+	// Errors lists requests that failed during the analysis run
+	Errors []string `json:"errors"`
+}
+
+// sentValue is a value a request sends, with the name it is sent under
+type sentValue struct {
+	location string
+	name     string
+	value    string
+}
+
+// receivedValue is a named value found in a response, such as a JSON
+// field or a hidden form input
+type receivedValue struct {
+	name  string
+	value string
+	rule  model.RuleType
+	expr  string
+}
+
+type analyzedResponse struct {
+	step   int
+	doc    *responseDoc
+	fields []receivedValue
+}
+
+// Correlate runs the HTTP steps of a script once, in order, and looks
+// for values later requests send that earlier responses provide:
+// session IDs, CSRF tokens, IDs of created resources. Existing
+// extractions are applied, so values already correlated are not
+// proposed again.
+func Correlate(client *http.Client, script *model.Script) (*CorrelationReport, error) {
+	if script.Source != "" {
+		return nil, errors.New("k6 JavaScript scripts cannot be analyzed")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	report := &CorrelationReport{Skipped: []int{}, Errors: []string{}}
+	v := &VU{Vars: copyVars(script.Variables)}
+
+	var responses []analyzedResponse
+	byOriginal := make(map[string]*Correlation)
+	var order []string
+
+	// sentBefore holds what requests sent up to the current step;
+	// values a user typed in and the server echoes back are not
+	// correlations
+	sentBefore := make(map[string]bool)
+
+	for i := range script.Steps {
+		step := &script.Steps[i]
+		if step.Type != model.HTTP {
+			report.Skipped = append(report.Skipped, i)
+			continue
+		}
+
+		// Values hardcoded in this request that an earlier response
+		// provides
+		for _, sent := range sentValues(step) {
+			if c, ok := byOriginal[sent.value]; ok {
+				// A short number sent under another name, such as a page
+				// number, is most likely a coincidence
+				if len(sent.value) >= minCorrelatedLen || isIDName(sent.name) {
+					c.UsedBy = appendUse(c.UsedBy, CorrelationUse{Step: i, Location: sent.location})
+				}
+				continue
+			}
+			if c := findSource(sent, responses, sentBefore); c != nil {
+				c.Original = sent.value
+				c.UsedBy = []CorrelationUse{{Step: i, Location: sent.location}}
+				byOriginal[sent.value] = c
+				order = append(order, sent.value)
+			}
+		}
+		for _, sent := range sentValues(step) {
+			sentBefore[sent.value] = true
+		}
+
+		s := v.ExpandStep(ApplyAuth(step, v.Expand))
+		resp, _, err := SendHTTP(client, s.Method, s.URL, s.Header, s.Body, true)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("step %d: %v", i+1, err))
+			continue
+		}
+		if resp.Status >= 400 {
+			report.Errors = append(report.Errors, fmt.Sprintf("step %d: status %d", i+1, resp.Status))
+		}
+
+		doc := newResponseDoc(resp.Body, nil)
+		doc.extract(step.Extract, v.Vars)
+		responses = append(responses, analyzedResponse{step: i, doc: doc, fields: receivedValues(doc)})
+	}
+
+	taken := make(map[string]bool)
+	for name := range script.Variables {
+		taken[name] = true
+	}
+	for _, step := range script.Steps {
+		for _, ex := range step.Extract {
+			taken[ex.Var] = true
+		}
+	}
+
+	report.Proposals = []Correlation{}
+	for _, original := range order {
+		c := byOriginal[original]
+		c.Extraction.Var = uniqueVar(c.Extraction.Var, taken)
+		report.Proposals = append(report.Proposals, *c)
+	}
+	return report, nil
+}
+
+func appendUse(uses []CorrelationUse, use CorrelationUse) []CorrelationUse {
+	for _, u := range uses {
+		if u == use {
+			return uses
+		}
+	}
+	return append(uses, use)
+}
+
+// findSource looks for the response a sent value came from, latest
+// first
+func findSource(sent sentValue, responses []analyzedResponse, sentBefore map[string]bool) *Correlation {
+	if sentBefore[sent.value] || strings.Contains(sent.value, "{{") {
+		return nil
+	}
+
+	for r := len(responses) - 1; r >= 0; r-- {
+		resp := responses[r]
+
+		// The value itself is in the response. Short numbers turn up
+		// everywhere, so they only count in a field named like the ID.
+		if looksDynamic(sent.value) || isIDName(sent.name) && isNumeric(sent.value) {
+			if f, ok := bestField(resp.fields, sent, func(f receivedValue) bool {
+				return f.value == sent.value && (len(sent.value) >= minCorrelatedLen || idNameMatches(f.name, sent.name))
+			}); ok {
+				return proposal(resp.step, f, sent, f.value, "high")
+			}
+			if looksDynamic(sent.value) && strings.Contains(string(resp.doc.body), sent.value) {
+				if expr, ok := contextRegex(resp.doc.body, sent.value); ok {
+					f := receivedValue{name: sent.name, rule: model.RegexRule, expr: expr}
+					return proposal(resp.step, f, sent, sent.value, "high")
+				}
+			}
+		}
+
+		// A field of the same name returned a value of the same shape,
+		// as when a recorded token was replaced by a fresh one
+		if sent.name != "" && len(sent.value) >= minCorrelatedLen {
+			if f, ok := bestField(resp.fields, sent, func(f receivedValue) bool {
+				return sameName(f.name, sent.name) && sameShape(f.value, sent.value)
+			}); ok {
+				return proposal(resp.step, f, sent, f.value, "medium")
+			}
+		}
+	}
+	return nil
+}
+
+func proposal(step int, f receivedValue, sent sentValue, value, confidence string) *Correlation {
+	name := f.name
+	if name == "" {
+		name = sent.name
+	}
+	return &Correlation{
+		Step:       step,
+		Extraction: model.Extraction{Var: varName(name), Type: f.rule, Expr: f.expr},
+		Value:      value,
+		Confidence: confidence,
+	}
+}
+
+// bestField returns the matching field, preferring one named like the
+// sent value
+func bestField(fields []receivedValue, sent sentValue, match func(receivedValue) bool) (receivedValue, bool) {
+	var found []receivedValue
+	for _, f := range fields {
+		if match(f) {
+			found = append(found, f)
+		}
+	}
+	if len(found) == 0 {
+		return receivedValue{}, false
+	}
+	for _, f := range found {
+		if sameName(f.name, sent.name) {
+			return f, true
+		}
+	}
+	return found[0], true
+}
+
+// sentValues lists the values a request sends: path segments, query
+// and form parameters, JSON fields, headers and cookies
+func sentValues(step *model.Step) []sentValue {
+	var out []sentValue
+	add := func(location, name, value string) {
+		if value != "" && !strings.Contains(value, "{{") {
+			out = append(out, sentValue{location: location, name: name, value: value})
+		}
+	}
+
+	if u, err := url.Parse(step.URL); err == nil {
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		for i, s := range segments {
+			prev := ""
+			if i > 0 {
+				prev = strings.TrimSuffix(segments[i-1], "s") + "Id"
+			}
+			if s, err := url.PathUnescape(s); err == nil {
+				add("path", prev, s)
+			}
+		}
+		for name, values := range u.Query() {
+			for _, val := range values {
+				add("query:"+name, name, val)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(step.Header))
+	for name := range step.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		val := step.Header[name]
+		location := "header:" + name
+		switch strings.ToLower(name) {
+		case "authorization":
+			if scheme, token, ok := strings.Cut(val, " "); ok && strings.EqualFold(scheme, "bearer") {
+				add(location, "token", token)
+			}
+		case "cookie":
+			for _, pair := range strings.Split(val, ";") {
+				if k, cv, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+					add(location, k, cv)
+				}
+			}
+		case "content-type", "accept", "user-agent", "content-length", "host", "origin", "referer":
+		default:
+			add(location, name, val)
+		}
+	}
+
+	body := strings.TrimSpace(step.Body)
+	switch {
+	case body == "":
+	case body[0] == '{' || body[0] == '[':
+		var doc interface{}
+		dec := json.NewDecoder(strings.NewReader(body))
+		dec.UseNumber()
+		if dec.Decode(&doc) == nil {
+			walkJSON(doc, "$", "", func(path, name, value string) {
+				add("body:"+name, name, value)
+			})
+		}
+	default:
+		if form, err := url.ParseQuery(body); err == nil && strings.Contains(body, "=") {
+			for name, values := range form {
+				for _, val := range values {
+					add("body:"+name, name, val)
+				}
+			}
+		}
+	}
+
+	if a := step.Auth; a != nil && a.Type == model.BearerAuth {
+		add("auth", "token", a.Token)
+	}
+	return out
+}
+
+// hiddenInput and metaTag find the values HTML pages hand to later
+// requests
+var (
+	hiddenInput = regexp.MustCompile(`(?i)<input[^>]*type=["']?hidden[^>]*>`)
+	metaTag     = regexp.MustCompile(`(?i)<meta[^>]*>`)
+	htmlAttr    = regexp.MustCompile(`(?i)([\w-]+)\s*=\s*["']([^"']*)["']`)
+)
+
+// receivedValues lists the named values in a response: every JSON
+// field, hidden form inputs and meta tags
+func receivedValues(doc *responseDoc) []receivedValue {
+	var out []receivedValue
+
+	if root, err := doc.json(); err == nil {
+		walkJSON(root, "$", "", func(path, name, value string) {
+			out = append(out, receivedValue{name: name, value: value, rule: model.JSONPathRule, expr: path})
+		})
+		return out
+	}
+
+	body := string(doc.body)
+	tags := append(hiddenInput.FindAllString(body, -1), metaTag.FindAllString(body, -1)...)
+	for _, tag := range tags {
+		attrs := make(map[string]string)
+		for _, m := range htmlAttr.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[2]
+		}
+		name, value := attrs["name"], attrs["value"]
+		if _, ok := attrs["content"]; ok {
+			value = attrs["content"]
+		}
+		if name == "" || value == "" {
+			continue
+		}
+		if expr, ok := attrRegex(doc.body, tag, name, value); ok {
+			out = append(out, receivedValue{name: name, value: value, rule: model.RegexRule, expr: expr})
+		} else if expr, ok := contextRegex(doc.body, value); ok {
+			out = append(out, receivedValue{name: name, value: value, rule: model.RegexRule, expr: expr})
+		}
+	}
+	return out
+}
+
+// attrRegex captures the value of a named hidden input or meta tag,
+// with the attributes in the order the page has them
+func attrRegex(body []byte, tag, name, value string) (string, bool) {
+	valueAttr := `(?:value|content)=["']([^"']*)["']`
+	nameAttr := `name=["']` + regexp.QuoteMeta(name) + `["']`
+
+	expr := nameAttr + `[^>]*?` + valueAttr
+	if strings.Index(tag, value) < strings.Index(strings.ToLower(tag), "name=") {
+		expr = valueAttr + `[^>]*?` + nameAttr
+	}
+
+	re, err := compileRegex(expr)
+	if err != nil {
+		return "", false
+	}
+	if m := re.FindSubmatch(body); m != nil && string(m[1]) == value {
+		return expr, true
+	}
+	return "", false
+}
+
+// walkJSON calls fn for every string and number in a document with its
+// JSONPath and key
+func walkJSON(val interface{}, path, name string, fn func(path, name, value string)) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkJSON(v[k], path+jsonPathKey(k), k, fn)
+		}
+	case []interface{}:
+		for i, item := range v {
+			walkJSON(item, fmt.Sprintf("%s[%d]", path, i), name, fn)
+		}
+	case string:
+		fn(path, name, v)
+	case json.Number:
+		fn(path, name, v.String())
+	}
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+func jsonPathKey(k string) string {
+	if plainKey.MatchString(k) {
+		return "." + k
+	}
+	return "['" + k + "']"
+}
+
+// contextRegex builds a regex that captures value from the text around
+// its first occurrence, checking it finds that value
+func contextRegex(body []byte, value string) (string, bool) {
+	text := string(body)
+	idx := strings.Index(text, value)
+	if idx < 0 {
+		return "", false
+	}
+
+	capture := `([^"'<>&\s]+)`
+	if strings.ContainsAny(value, "\"'<>& \t\r\n") {
+		capture = `(.+?)`
+	}
+
+	for size := 8; size <= maxContext; size *= 2 {
+		start := max(0, idx-size)
+		left := text[start:idx]
+		if nl := strings.LastIndexAny(left, "\r\n"); nl >= 0 {
+			left = left[nl+1:]
+		}
+		if left == "" {
+			continue
+		}
+
+		expr := regexp.QuoteMeta(left) + capture
+		if capture == `(.+?)` {
+			end := idx + len(value)
+			if end >= len(text) {
+				return "", false
+			}
+			expr += regexp.QuoteMeta(text[end : end+1])
+		}
+
+		re, err := compileRegex(expr)
+		if err != nil {
+			return "", false
+		}
+		if m := re.FindStringSubmatch(text); m != nil && m[1] == value {
+			return expr, true
+		}
+	}
+	return "", false
+}
+
+var shapeClasses = []*regexp.Regexp{
+	regexp.MustCompile(`^\d+$`),
+	regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+	regexp.MustCompile(`^[0-9a-f]+$`),
+	regexp.MustCompile(`^[0-9A-F]+$`),
+	regexp.MustCompile(`^[A-Za-z0-9]+$`),
+	regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*$`), // JWT
+	regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`),
+}
+
+// sameShape reports whether two values look like the same kind of
+// token: the same character class and about the same length
+func sameShape(a, b string) bool {
+	diff := len(a) - len(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > len(b)/4 {
+		return false
+	}
+	for _, re := range shapeClasses {
+		ma, mb := re.MatchString(a), re.MatchString(b)
+		if ma || mb {
+			return ma && mb
+		}
+	}
+	return false
+}
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(s))
+}
+
+func sameName(a, b string) bool {
+	return a != "" && normalizeName(a) == normalizeName(b)
+}
+
+func isIDName(name string) bool {
+	return strings.HasSuffix(normalizeName(name), "id")
+}
+
+// idNameMatches reports whether a response field names the ID sent as
+// sent: the same name, or a plain "id" for a name like "userId"
+func idNameMatches(field, sent string) bool {
+	return sameName(field, sent) || normalizeName(field) == "id" && isIDName(sent)
+}
+
+// looksDynamic tells generated values such as tokens and IDs from the
+// words that make up paths and parameters
+func looksDynamic(s string) bool {
+	return len(s) >= minCorrelatedLen && (strings.ContainsAny(s, "0123456789") || len(s) >= 16)
+}
+
+func isNumeric(s string) bool {
+	return shapeClasses[0].MatchString(s)
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// varName turns a field name into a variable name
+func varName(name string) string {
+	parts := nonIdent.Split(name, -1)
+	var b strings.Builder
+	for _, p := range parts {
+		if p == "" {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString(strings.ToLower(p[:1]) + p[1:])
+		} else {
+			b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+		}
+	}
+	s := b.String()
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "value" + s
+	}
+	return s
+}
+
+func uniqueVar(name string, taken map[string]bool) string {
+	out := name
+	for n := 2; taken[out]; n++ {
+		out = fmt.Sprintf("%s%d", name, n)
+	}
+	taken[out] = true
+	return out
+}
+
+// ApplyCorrelations rewrites a script with accepted proposals: the
+// source step extracts the variable and the steps in UsedBy send
+// {{var}} in place of the hardcoded value. Only whole values at the
+// listed locations are replaced. It returns how many values were
+// replaced.
+func ApplyCorrelations(script *model.Script, proposals []Correlation) (int, error) {
+	for _, c := range proposals {
+		if c.Step < 0 || c.Step >= len(script.Steps) {
+			return 0, fmt.Errorf("step %d does not exist", c.Step)
+		}
+		if c.Extraction.Var == "" || c.Original == "" {
+			return 0, errors.New("correlation needs a variable and the original value")
+		}
+		if err := ValidateRule(c.Extraction.Type, c.Extraction.Expr); err != nil {
+			return 0, err
+		}
+		for _, use := range c.UsedBy {
+			if use.Step <= c.Step || use.Step >= len(script.Steps) {
+				return 0, fmt.Errorf("step %d cannot use a value extracted by step %d", use.Step, c.Step)
+			}
+		}
+	}
+
+	replaced := 0
+	for _, c := range proposals {
+		src := &script.Steps[c.Step]
+		exists := false
+		for _, ex := range src.Extract {
+			exists = exists || ex.Var == c.Extraction.Var
+		}
+		if !exists {
+			src.Extract = append(src.Extract, c.Extraction)
+		}
+
+		ref := "{{" + c.Extraction.Var + "}}"
+		for _, use := range c.UsedBy {
+			replaced += substitute(&script.Steps[use.Step], use.Location, c.Original, ref)
+		}
+	}
+	return replaced, nil
+}
+
+// substitute replaces a value where sentValues found it: a path
+// segment, a query, form or JSON value with the given name, a header,
+// bearer token or cookie value, or the auth token. Only whole values are
+// replaced.
+func substitute(step *model.Step, location, old, ref string) int {
+	n := 0
+	kind, name, _ := strings.Cut(location, ":")
+	switch kind {
+	case "path", "query":
+		step.URL, n = substituteURL(step.URL, kind, name, old, ref)
+	case "body":
+		if body := strings.TrimSpace(step.Body); body != "" && (body[0] == '{' || body[0] == '[') {
+			step.Body, n = substituteJSON(step.Body, name, old, ref)
+		} else {
+			step.Body, n = substitutePairs(step.Body, "&", name, old, ref)
+		}
+	case "auth":
+		if a := step.Auth; a != nil && a.Type == model.BearerAuth && a.Token == old {
+			a.Token, n = ref, 1
+		}
+	case "header":
+		val, ok := step.Header[name]
+		if !ok {
+			return 0
+		}
+		switch strings.ToLower(name) {
+		case "authorization":
+			if scheme, token, ok := strings.Cut(val, " "); ok && strings.EqualFold(scheme, "bearer") && token == old {
+				val, n = scheme+" "+ref, 1
+			}
+		case "cookie":
+			val, n = substitutePairs(val, ";", "", old, ref)
+		default:
+			if val == old {
+				val, n = ref, 1
+			}
+		}
+		step.Header[name] = val
+	}
+	return n
+}
+
+// substituteURL replaces path segments equal to old, or the value of
+// the named query parameter, leaving the rest of the URL as written
+func substituteURL(raw, kind, name, old, ref string) (string, int) {
+	rest, fragment, hasFragment := strings.Cut(raw, "#")
+	rest, query, hasQuery := strings.Cut(rest, "?")
+
+	n := 0
+	if kind == "query" {
+		if !hasQuery {
+			return raw, 0
+		}
+		query, n = substitutePairs(query, "&", name, old, ref)
+	} else {
+		// Leave the scheme and host alone
+		prefix, path := "", rest
+		if i := strings.Index(rest, "://"); i >= 0 {
+			if j := strings.Index(rest[i+3:], "/"); j >= 0 {
+				prefix, path = rest[:i+3+j], rest[i+3+j:]
+			} else {
+				prefix, path = rest, ""
+			}
+		}
+		segments := strings.Split(path, "/")
+		for i, seg := range segments {
+			if v, err := url.PathUnescape(seg); err == nil && v == old {
+				segments[i] = ref
+				n++
+			}
+		}
+		rest = prefix + strings.Join(segments, "/")
+	}
+
+	out := rest
+	if hasQuery {
+		out += "?" + query
+	}
+	if hasFragment {
+		out += "#" + fragment
+	}
+	return out, n
+}
+
+// substitutePairs replaces the values equal to old in name=value pairs
+// joined by sep, as in query strings, form bodies and cookies. An empty
+// name matches every pair.
+func substitutePairs(s, sep, name, old, ref string) (string, int) {
+	n := 0
+	pairs := strings.Split(s, sep)
+	for i, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		if k, err := url.QueryUnescape(strings.TrimSpace(key)); err != nil || name != "" && k != name {
+			continue
+		}
+		if v, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil && v == old {
+			pairs[i] = key + "=" + ref
+			n++
+		}
+	}
+	return strings.Join(pairs, sep), n
+}
+
+// substituteJSON replaces the string and number values under key name
+// that equal old, keeping the document's layout. A number becomes a
+// bare placeholder, so it is still sent as a number.
+func substituteJSON(body, name, old, ref string) (string, int) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	var spans []span
+
+	// open holds the objects and arrays being read: whether the next
+	// string in an object is a key, and the key values are under;
+	// array items count as under the array's key
+	type container struct {
+		object, key bool
+		name        string
+	}
+	var open []container
+
+	// Placeholders substituted before, such as a bare {{id}} number,
+	// are read as numbers of the same length so the offsets still match
+	masked := placeholder.ReplaceAllStringFunc(body, func(m string) string {
+		return "1" + strings.Repeat("0", len(m)-1)
+	})
+
+	quoted, _ := json.Marshal(old)
+	dec := json.NewDecoder(strings.NewReader(masked))
+	dec.UseNumber()
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		end := int(dec.InputOffset())
+
+		under := ""
+		if len(open) > 0 {
+			top := &open[len(open)-1]
+			if top.object && top.key {
+				if key, ok := tok.(string); ok {
+					top.key = false
+					top.name = key
+					continue
+				}
+			}
+			if top.object {
+				top.key = true
+			}
+			under = top.name
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{':
+				open = append(open, container{object: true, key: true})
+			case '[':
+				open = append(open, container{name: under})
+			default:
+				open = open[:len(open)-1]
+			}
+		case string:
+			start := end - len(quoted)
+			if under == name && t == old && start >= 0 && body[start:end] == string(quoted) {
+				spans = append(spans, span{start, end, `"` + ref + `"`})
+			}
+		case json.Number:
+			start := end - len(t)
+			if under == name && t.String() == old && start >= 0 && body[start:end] == old {
+				spans = append(spans, span{start, end, ref})
+			}
+		}
+	}
+
+	for i := len(spans) - 1; i >= 0; i-- {
+		sp := spans[i]
+		body = body[:sp.start] + sp.text + body[sp.end:]
+	}
+	return body, len(spans)
+}
@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"k6clone/internal/core/model"
+)
+
+// newLoginServer hands out a token and a user ID on /login and a short
+// page count on /stats
+func newLoginServer(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/stats":
+			fmt.Fprint(w, `{"pages":7}`)
+		case "/login":
+			fmt.Fprint(w, `{"id":42,"token":"tok3n9f8e7d6"}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCorrelateAndApply(t *testing.T) {
+	base := newLoginServer(t)
+
+	script := &model.Script{Steps: []model.Step{
+		{Type: model.HTTP, Method: "GET", URL: base + "/stats"},
+		{Type: model.HTTP, Method: "POST", URL: base + "/login", Body: `{"user":"a"}`},
+		{
+			Type: model.HTTP, Method: "PUT", URL: base + "/users/42/orders?page=7&userId=42&x=142",
+			Header: map[string]string{
+				"Authorization": "Bearer tok3n9f8e7d6",
+				"Cookie":        "lang=en; session=tok3n9f8e7d6",
+				"X-Note":        "tok3n9f8e7d6 and more",
+			},
+			Body: `{"userId": 42, "note": "user 42", "count": 42, "session": "tok3n9f8e7d6"}`,
+		},
+		{Type: model.HTTP, Method: "POST", URL: base + "/forms", Body: "userId=42&total=420&session=tok3n9f8e7d6"},
+	}}
+
+	report, err := Correlate(nil, script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+
+	// The page count 7 from /stats is never proposed
+	want := []Correlation{
+		{
+			Step:       1,
+			Extraction: model.Extraction{Var: "id", Type: model.JSONPathRule, Expr: "$.id"},
+			Original:   "42",
+			Value:      "42",
+			UsedBy: []CorrelationUse{
+				{Step: 2, Location: "path"},
+				{Step: 2, Location: "query:userId"},
+				{Step: 2, Location: "body:userId"},
+				{Step: 3, Location: "body:userId"},
+			},
+			Confidence: "high",
+		},
+		{
+			Step:       1,
+			Extraction: model.Extraction{Var: "token", Type: model.JSONPathRule, Expr: "$.token"},
+			Original:   "tok3n9f8e7d6",
+			Value:      "tok3n9f8e7d6",
+			UsedBy: []CorrelationUse{
+				{Step: 2, Location: "header:Authorization"},
+				{Step: 2, Location: "header:Cookie"},
+				{Step: 2, Location: "body:session"},
+				{Step: 3, Location: "body:session"},
+			},
+			Confidence: "high",
+		},
+	}
+	if !reflect.DeepEqual(report.Proposals, want) {
+		t.Fatalf("proposals =\n%+v\nwant\n%+v", report.Proposals, want)
+	}
+
+	replaced, err := ApplyCorrelations(script, report.Proposals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replaced != 8 {
+		t.Errorf("replaced %d values, want 8", replaced)
+	}
+
+	login := script.Steps[1]
+	if len(login.Extract) != 2 {
+		t.Errorf("login extracts %+v, want id and token", login.Extract)
+	}
+
+	orders := script.Steps[2]
+	if got, want := orders.URL, base+"/users/{{id}}/orders?page=7&userId={{id}}&x=142"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	wantHeader := map[string]string{
+		"Authorization": "Bearer {{token}}",
+		"Cookie":        "lang=en; session={{token}}",
+		"X-Note":        "tok3n9f8e7d6 and more",
+	}
+	if !reflect.DeepEqual(orders.Header, wantHeader) {
+		t.Errorf("header = %v, want %v", orders.Header, wantHeader)
+	}
+	// Only whole values under the recorded key; the number stays bare
+	if got, want := orders.Body, `{"userId": {{id}}, "note": "user 42", "count": 42, "session": "{{token}}"}`; got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+
+	if got, want := script.Steps[3].Body, "userId={{id}}&total=420&session={{token}}"; got != want {
+		t.Errorf("form = %s, want %s", got, want)
+	}
+
+	if script.Steps[0].URL != base+"/stats" || len(script.Steps[0].Extract) != 0 {
+		t.Errorf("stats step changed: %+v", script.Steps[0])
+	}
+}
+
+func TestSubstituteJSONAfterPlaceholders(t *testing.T) {
+	// A bare {{id}} from an earlier substitution is not valid JSON
+	body := `{"id": {{id}}, "owner": {"id": 7, "token": "abc123"}, "ids": [7, 8]}`
+
+	got, n := substituteJSON(body, "token", "abc123", "{{token}}")
+	if want := `{"id": {{id}}, "owner": {"id": 7, "token": "{{token}}"}, "ids": [7, 8]}`; got != want || n != 1 {
+		t.Errorf("got %s (%d), want %s (1)", got, n, want)
+	}
+
+	got, n = substituteJSON(got, "ids", "7", "{{first}}")
+	if want := `{"id": {{id}}, "owner": {"id": 7, "token": "{{token}}"}, "ids": [{{first}}, 8]}`; got != want || n != 1 {
+		t.Errorf("got %s (%d), want %s (1)", got, n, want)
+	}
+}
+
+func TestApplyCorrelationsRejectsEarlierUses(t *testing.T) {
+	script := &model.Script{Steps: []model.Step{
+		{Type: model.HTTP, Method: "GET", URL: "http://localhost/a/42"},
+		{Type: model.HTTP, Method: "GET", URL: "http://localhost/b"},
+	}}
+	_, err := ApplyCorrelations(script, []Correlation{{
+		Step:       1,
+		Extraction: model.Extraction{Var: "id", Type: model.JSONPathRule, Expr: "$.id"},
+		Original:   "42",
+		UsedBy:     []CorrelationUse{{Step: 0, Location: "path"}},
+	}})
+	if err == nil {
+		t.Error("a use before the source step was accepted")
+	}
+	if script.Steps[0].URL != "http://localhost/a/42" {
+		t.Errorf("URL rewritten to %s", script.Steps[0].URL)
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"k6clone/internal/core/model"
+)
+
+// dataset is a CSV file loaded for one run. Rows are handed out in
+// order across all VUs.
+type dataset struct {
+	vars      []string
+	rows      [][]string
+	stopAtEnd bool
+	next      atomic.Uint64
+}
+
+// ValidateDataset checks a dataset's settings without reading the file
+func ValidateDataset(ds *model.Dataset) error {
+	if ds.File == "" {
+		return errors.New("dataset file is empty")
+	}
+	if ds.Delimiter != "" && utf8.RuneCountInString(ds.Delimiter) != 1 {
+		return errors.New("dataset delimiter must be a single character")
+	}
+	return nil
+}
+
+func (e *LoadEngine) loadDatasets(script *model.Script) ([]*dataset, error) {
+	var out []*dataset
+	for i := range script.Datasets {
+		d, err := e.loadDataset(&script.Datasets[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (e *LoadEngine) loadDataset(ds *model.Dataset) (*dataset, error) {
+	// Like open() in k6 scripts, datasets cannot leave the data directory
+	path := filepath.Join(e.dataDir, filepath.Clean("/"+ds.File))
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("dataset " + ds.File + ": " + err.Error())
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	if ds.Delimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(ds.Delimiter)
+	}
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.New("dataset " + ds.File + ": " + err.Error())
+	}
+
+	d := &dataset{vars: ds.Variables, stopAtEnd: ds.StopAtEnd}
+	if len(d.vars) == 0 && len(rows) > 0 {
+		d.vars, rows = rows[0], rows[1:]
+	} else if ds.IgnoreFirstLine && len(rows) > 0 {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("dataset " + ds.File + " has no rows")
+	}
+	d.rows = rows
+	return d, nil
+}
+
+// fill stores the next row in the VU's variables. It reports false
+// once a dataset that stops at its end has run out.
+func (d *dataset) fill(vars map[string]string) bool {
+	n := d.next.Add(1) - 1
+	if d.stopAtEnd && n >= uint64(len(d.rows)) {
+		return false
+	}
+
+	row := d.rows[n%uint64(len(d.rows))]
+	for i, name := range d.vars {
+		if i < len(row) {
+			vars[name] = row[i]
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k6clone/internal/core/model"
+)
+
+func init() {
+	Register(model.GraphQL, graphQLExecutor{})
+}
+
+type graphQLExecutor struct{}
+
+func (graphQLExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("step url is empty")
+	}
+	if step.GraphQL == nil || strings.TrimSpace(step.GraphQL.Query) == "" {
+		return errors.New("graphql step requires a query")
+	}
+	return nil
+}
+
+type graphQLRequest struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+type graphQLResponse struct {
+	Errors []json.RawMessage `json:"errors"`
+}
+
+// Run posts the operation and fails the request when the server
+// reports errors in the body, since GraphQL servers usually answer 200
+func (graphQLExecutor) Run(v *VU, index int, step *model.Step) {
+	gql := step.GraphQL
+	if gql == nil {
+		v.AddRequest(0, false)
+		return
+	}
+
+	operation := gql.OperationName
+	if operation == "" {
+		operation = "anonymous"
+	}
+
+	payload, err := json.Marshal(graphQLRequest{
+		Query:         gql.Query,
+		Variables:     json.RawMessage(v.Expand(string(gql.Variables))),
+		OperationName: gql.OperationName,
+	})
+	if err != nil {
+		v.AddRequest(0, false)
+		return
+	}
+
+	header := map[string]string{"Content-Type": "application/json"}
+	for k, val := range step.Header {
+		header[k] = v.Expand(val)
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	resp, latency, err := SendHTTP(v.Client, method, v.Expand(step.URL), header, string(payload), true)
+	ok := err == nil && resp.Status < 400
+	if ok {
+		var body graphQLResponse
+		if err := json.Unmarshal(resp.Body, &body); err != nil || len(body.Errors) > 0 {
+			ok = false
+		}
+	}
+
+	v.AddDuration(MetricName("graphql_req_duration", "operation", operation), latency)
+	if !ok {
+		v.AddCount(MetricName("graphql_errors", "operation", operation), 1)
+	}
+	v.AddRequest(latency, ok)
+}
+
+func (graphQLExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	gql := step.GraphQL
+	operation := gql.OperationName
+	if operation == "" {
+		operation = "anonymous"
+	}
+
+	payload, err := json.Marshal(graphQLRequest{
+		Query:         gql.Query,
+		Variables:     gql.Variables,
+		OperationName: gql.OperationName,
+	})
+	if err != nil {
+		return K6Snippet{}, err
+	}
+
+	header := map[string]string{"Content-Type": "application/json"}
+	for k, val := range step.Header {
+		header[k] = val
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: GraphQL %s\n", index+1, operation)
+	fmt.Fprintf(&b, "  const res%d = http.post(%s, JSON.stringify(%s), {\n", index, jsValue(step.URL), payload)
+	fmt.Fprintf(&b, "    headers: %s,\n", jsValue(header))
+	fmt.Fprintf(&b, "    tags: { name: %s },\n", jsValue(operation))
+	b.WriteString("  });\n")
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is 2xx\": (r) => r.status >= 200 && r.status < 300,\n")
+	b.WriteString("    \"no graphql errors\": (r) => !(r.json(\"errors\") || []).length,\n")
+	b.WriteString("  });\n")
+	return K6Snippet{Code: b.String()}, nil
+}
@@ -0,0 +1,323 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"k6clone/internal/core/model"
+)
+
+const defaultGRPCTimeout = 30 * time.Second
+
+func init() {
+	Register(model.GRPC, grpcExecutor{})
+}
+
+type grpcExecutor struct{}
+
+func (grpcExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("grpc step target is empty")
+	}
+	if step.GRPC == nil || step.GRPC.Service == "" || step.GRPC.Method == "" {
+		return errors.New("grpc step requires service and method")
+	}
+	if step.GRPC.TimeoutMs < 0 {
+		return errors.New("grpc timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// Run performs one call over the VU's connection to the target. Every
+// call is tagged by method for latency and by method and status code
+// for the code distribution.
+func (grpcExecutor) Run(v *VU, index int, step *model.Step) {
+	descriptors := v.Shared("grpc.descriptors", func() interface{} {
+		return newGRPCDescriptors(v.run.engine.protoDir)
+	}).(*grpcDescriptors)
+
+	client, _ := v.Resource("grpc", func() (io.Closer, error) {
+		return newGRPCClient(descriptors), nil
+	})
+
+	client.(*grpcClient).run(v, step)
+}
+
+// K6JS emits k6/net/grpc calls. Proto files are loaded by a client per
+// step because k6 only allows loading them in the init context.
+func (grpcExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	g := step.GRPC
+	client := fmt.Sprintf("grpcClient%d", index)
+
+	init := "const " + client + " = new grpc.Client();\n"
+	connect := "plaintext: " + strconv.FormatBool(!g.TLS)
+	if len(g.ProtoFiles) > 0 {
+		files := make([]string, len(g.ProtoFiles))
+		for i, f := range g.ProtoFiles {
+			files[i] = jsValue(f)
+		}
+		init += client + ".load([], " + strings.Join(files, ", ") + ");\n"
+	} else {
+		connect += ", reflect: true"
+	}
+
+	message := "{}"
+	if len(g.Message) > 0 {
+		message = string(g.Message)
+	}
+	params := ""
+	if len(step.Header) > 0 {
+		params = ", { metadata: " + jsValue(step.Header) + " }"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: gRPC %s/%s\n", index+1, g.Service, g.Method)
+	fmt.Fprintf(&b, "  %s.connect(%s, { %s });\n", client, jsValue(step.URL), connect)
+	fmt.Fprintf(&b, "  const res%d = %s.invoke(%s, %s%s);\n", index, client, jsValue(g.Service+"/"+g.Method), message, params)
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is OK\": (r) => r && r.status === grpc.StatusOK,\n")
+	b.WriteString("  });\n")
+	fmt.Fprintf(&b, "  %s.close();\n", client)
+
+	return K6Snippet{
+		Imports: []string{`import grpc from "k6/net/grpc";`},
+		Init:    init,
+		Code:    b.String(),
+	}, nil
+}
+
+// grpcDescriptors resolves method descriptors once per run, so .proto
+// files are compiled and reflection is queried once rather than on
+// every iteration
+type grpcDescriptors struct {
+	protoDir string
+
+	mu      sync.Mutex
+	methods map[string]protoreflect.MethodDescriptor
+}
+
+func newGRPCDescriptors(protoDir string) *grpcDescriptors {
+	return &grpcDescriptors{
+		protoDir: protoDir,
+		methods:  make(map[string]protoreflect.MethodDescriptor),
+	}
+}
+
+func (d *grpcDescriptors) method(
+	ctx context.Context,
+	conn *grpc.ClientConn,
+	g *model.GRPCStep,
+) (protoreflect.MethodDescriptor, error) {
+	key := g.Service + "/" + g.Method + "|" + strings.Join(g.ProtoFiles, ",")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if md, ok := d.methods[key]; ok {
+		return md, nil
+	}
+
+	var service protoreflect.ServiceDescriptor
+	var err error
+	if len(g.ProtoFiles) > 0 {
+		service, err = d.fromProtoFiles(ctx, g.Service, g.ProtoFiles)
+	} else {
+		service, err = fromReflection(ctx, conn, g.Service)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	md := service.Methods().ByName(protoreflect.Name(g.Method))
+	if md == nil {
+		return nil, errors.New("method " + g.Method + " not found in " + g.Service)
+	}
+	if md.IsStreamingClient() {
+		return nil, errors.New("client streaming methods are not supported")
+	}
+
+	d.methods[key] = md
+	return md, nil
+}
+
+func (d *grpcDescriptors) fromProtoFiles(
+	ctx context.Context,
+	service string,
+	files []string,
+) (protoreflect.ServiceDescriptor, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{d.protoDir},
+		}),
+	}
+
+	compiled, err := compiler.Compile(ctx, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := compiled.AsResolver().FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, errors.New("service " + service + " not found in proto files")
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, errors.New(service + " is not a service")
+	}
+	return sd, nil
+}
+
+func fromReflection(
+	ctx context.Context,
+	conn *grpc.ClientConn,
+	service string,
+) (protoreflect.ServiceDescriptor, error) {
+	client := grpcreflect.NewClientAuto(ctx, conn)
+	defer client.Reset()
+
+	fd, err := client.FileContainingSymbol(service)
+	if err != nil {
+		return nil, err
+	}
+
+	services := fd.UnwrapFile().Services()
+	for i := 0; i < services.Len(); i++ {
+		if string(services.Get(i).FullName()) == service {
+			return services.Get(i), nil
+		}
+	}
+	return nil, errors.New("service " + service + " not found via reflection")
+}
+
+// grpcClient keeps one connection per target for a single VU
+type grpcClient struct {
+	descriptors *grpcDescriptors
+	conns       map[string]*grpc.ClientConn
+}
+
+func newGRPCClient(descriptors *grpcDescriptors) *grpcClient {
+	return &grpcClient{
+		descriptors: descriptors,
+		conns:       make(map[string]*grpc.ClientConn),
+	}
+}
+
+func (g *grpcClient) conn(target string, useTLS bool) (*grpc.ClientConn, error) {
+	key := target
+	if useTLS {
+		key = "tls://" + target
+	}
+	if conn, ok := g.conns[key]; ok {
+		return conn, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewClientTLSFromCert(nil, "")
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	g.conns[key] = conn
+	return conn, nil
+}
+
+func (g *grpcClient) run(v *VU, step *model.Step) {
+	if step.GRPC == nil {
+		v.AddRequest(0, false)
+		return
+	}
+	fullMethod := "/" + step.GRPC.Service + "/" + step.GRPC.Method
+
+	timeout := defaultGRPCTimeout
+	if step.GRPC.TimeoutMs > 0 {
+		timeout = time.Duration(step.GRPC.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for k, val := range step.Header {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v.Expand(val))
+	}
+
+	start := time.Now()
+	err := g.invoke(ctx, v, step, fullMethod)
+	latency := time.Since(start)
+
+	code := status.Code(err)
+	v.AddCount(MetricName("grpc_status", "method", fullMethod, "code", code.String()), 1)
+	v.AddDuration(MetricName("grpc_req_duration", "method", fullMethod), latency)
+	v.AddRequest(latency, code == codes.OK)
+}
+
+func (g *grpcClient) invoke(ctx context.Context, v *VU, step *model.Step, fullMethod string) error {
+	conn, err := g.conn(v.Expand(step.URL), step.GRPC.TLS)
+	if err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	md, err := g.descriptors.method(ctx, conn, step.GRPC)
+	if err != nil {
+		return status.Error(codes.Unimplemented, err.Error())
+	}
+
+	req := dynamicpb.NewMessage(md.Input())
+	if len(step.GRPC.Message) > 0 {
+		if err := protojson.Unmarshal([]byte(v.Expand(string(step.GRPC.Message))), req); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	if !md.IsStreamingServer() {
+		return conn.Invoke(ctx, fullMethod, req, dynamicpb.NewMessage(md.Output()))
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	for {
+		err := stream.RecvMsg(dynamicpb.NewMessage(md.Output()))
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		v.AddCount(MetricName("grpc_stream_msgs_received", "method", fullMethod), 1)
+	}
+}
+
+func (g *grpcClient) Close() error {
+	for _, conn := range g.conns {
+		conn.Close()
+	}
+	return nil
+}
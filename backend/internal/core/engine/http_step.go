@@ -0,0 +1,172 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+// maxResponseBody caps how much of a response is kept for steps that
+// inspect the body
+const maxResponseBody = 10 << 20
+
+func init() {
+	Register(model.HTTP, httpExecutor{})
+}
+
+type httpExecutor struct{}
+
+func (httpExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("step url is empty")
+	}
+	if step.Method == "" {
+		return errors.New("step method is empty")
+	}
+	return nil
+}
+
+// Run executes an HTTP step with placeholders resolved, then evaluates
+// its checks and extractions against the response
+func (httpExecutor) Run(v *VU, index int, step *model.Step) {
+	if len(step.Checks) == 0 && len(step.Extract) == 0 {
+		latency, ok := DoHTTP(v.Client, v.ExpandStep(step))
+		v.AddRequest(latency, ok)
+		return
+	}
+
+	s := v.ExpandStep(step)
+	resp, latency, err := SendHTTP(v.Client, s.Method, s.URL, s.Header, s.Body, true)
+	if err != nil {
+		v.AddRequest(latency, false)
+		return
+	}
+
+	doc := newResponseDoc(resp.Body, nil)
+	ok := resp.Status < 400 && doc.checksPass(step.Checks)
+	doc.extract(step.Extract, v.Vars)
+
+	v.AddRequest(latency, ok)
+}
+
+func (httpExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: %s %s\n", index+1, step.Method, step.URL)
+	fmt.Fprintf(&b, "  const res%d = %s;\n", index, k6Request(step))
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is 2xx\": (r) => r.status >= 200 && r.status < 300,\n")
+	b.WriteString("  });\n")
+	return K6Snippet{Code: b.String()}, nil
+}
+
+// k6Request renders an http.* call for an HTTP-style step
+func k6Request(step *model.Step) string {
+	step = ApplyAuth(step, keepPlaceholders)
+	method := strings.ToLower(step.Method)
+	if method == "delete" {
+		method = "del"
+	}
+
+	args := []string{jsValue(step.URL)}
+	if step.Body != "" || len(step.Header) > 0 {
+		switch method {
+		case "get", "head":
+			// no body argument
+		default:
+			if step.Body != "" {
+				args = append(args, jsValue(step.Body))
+			} else {
+				args = append(args, "null")
+			}
+		}
+	}
+	if len(step.Header) > 0 {
+		args = append(args, "{ headers: "+jsValue(step.Header)+" }")
+	}
+
+	switch method {
+	case "get", "post", "put", "patch", "del", "head", "options":
+		return "http." + method + "(" + strings.Join(args, ", ") + ")"
+	}
+
+	// Other methods go through http.request, which always takes a body
+	if len(args) == 1 {
+		args = append(args, "null")
+	}
+	return "http.request(" + jsValue(step.Method) + ", " + strings.Join(args, ", ") + ")"
+}
+
+// jsValue renders a value as a JavaScript literal
+func jsValue(v interface{}) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "null"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// HTTPResponse is what SendHTTP keeps of a response
+type HTTPResponse struct {
+	Status int
+	Body   []byte
+}
+
+// SendHTTP performs one request. The response body is only kept when
+// keepBody is set; otherwise it is drained so the connection can be
+// reused.
+func SendHTTP(
+	client *http.Client,
+	method, url string,
+	header map[string]string,
+	body string,
+	keepBody bool,
+) (*HTTPResponse, time.Duration, error) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+
+	start := time.Now()
+
+	req, err := http.NewRequest(method, url, reqBody)
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	defer resp.Body.Close()
+
+	out := &HTTPResponse{Status: resp.StatusCode}
+	if keepBody {
+		out.Body, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	} else {
+		_, err = io.Copy(io.Discard, resp.Body)
+	}
+
+	return out, time.Since(start), err
+}
+
+// DoHTTP sends the request described by an HTTP step and reports its
+// latency and whether it succeeded
+func DoHTTP(client *http.Client, step *model.Step) (time.Duration, bool) {
+	resp, latency, err := SendHTTP(client, step.Method, step.URL, step.Header, step.Body, false)
+	if err != nil {
+		return latency, false
+	}
+	return latency, resp.Status < 400
+}
@@ -0,0 +1,315 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// jsRequest is a k6/http request with all JavaScript values resolved,
+// so it can be sent from another goroutine
+type jsRequest struct {
+	method string
+	url    string
+	body   []byte
+	header map[string]string
+
+	timeout time.Duration
+
+	// metric is the tagged http_req_duration name, empty when the
+	// request has neither a name tag nor a group
+	metric string
+}
+
+type jsResponse struct {
+	status   int
+	proto    string
+	url      string
+	header   http.Header
+	body     []byte
+	duration time.Duration
+	err      error
+}
+
+// httpModule implements the parts of k6/http scripts use most
+func (j *jsVU) httpModule() *goja.Object {
+	m := j.rt.NewObject()
+	m.Set("get", func(u, params goja.Value) goja.Value {
+		return j.send(http.MethodGet, u, nil, params)
+	})
+	m.Set("head", func(u, params goja.Value) goja.Value {
+		return j.send(http.MethodHead, u, nil, params)
+	})
+	m.Set("post", func(u, body, params goja.Value) goja.Value {
+		return j.send(http.MethodPost, u, body, params)
+	})
+	m.Set("put", func(u, body, params goja.Value) goja.Value {
+		return j.send(http.MethodPut, u, body, params)
+	})
+	m.Set("patch", func(u, body, params goja.Value) goja.Value {
+		return j.send(http.MethodPatch, u, body, params)
+	})
+	m.Set("del", func(u, body, params goja.Value) goja.Value {
+		return j.send(http.MethodDelete, u, body, params)
+	})
+	m.Set("options", func(u, body, params goja.Value) goja.Value {
+		return j.send(http.MethodOptions, u, body, params)
+	})
+	m.Set("request", func(method string, u, body, params goja.Value) goja.Value {
+		return j.send(strings.ToUpper(method), u, body, params)
+	})
+	m.Set("batch", j.batch)
+	return m
+}
+
+func (j *jsVU) send(method string, u, body, params goja.Value) goja.Value {
+	req := j.newRequest(method, u, body, params)
+	return j.responseObject(j.do(req))
+}
+
+// batch sends requests in parallel. Requests are given as URL strings,
+// [method, url, body, params] arrays or {method, url, body, params}
+// objects, in an array or an object keyed by name; the responses come
+// back in the same shape.
+func (j *jsVU) batch(reqs goja.Value) goja.Value {
+	if isNullish(reqs) {
+		panic(j.rt.NewTypeError("http.batch() needs an array or object of requests"))
+	}
+	_, isArray := reqs.Export().([]interface{})
+	obj := reqs.ToObject(j.rt)
+	keys := obj.Keys()
+
+	built := make([]*jsRequest, len(keys))
+	for i, key := range keys {
+		built[i] = j.batchRequest(obj.Get(key))
+	}
+
+	responses := make([]*jsResponse, len(built))
+	wg := sync.WaitGroup{}
+	for i := range built {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i] = j.do(built[i])
+		}(i)
+	}
+	wg.Wait()
+
+	if isArray {
+		values := make([]interface{}, len(responses))
+		for i, resp := range responses {
+			values[i] = j.responseObject(resp)
+		}
+		return j.rt.NewArray(values...)
+	}
+
+	out := j.rt.NewObject()
+	for i, key := range keys {
+		out.Set(key, j.responseObject(responses[i]))
+	}
+	return out
+}
+
+func (j *jsVU) batchRequest(item goja.Value) *jsRequest {
+	if _, ok := item.Export().(string); ok {
+		return j.newRequest(http.MethodGet, item, nil, nil)
+	}
+
+	obj := item.ToObject(j.rt)
+	if _, ok := item.Export().([]interface{}); ok {
+		return j.newRequest(strings.ToUpper(obj.Get("0").String()), obj.Get("1"), obj.Get("2"), obj.Get("3"))
+	}
+
+	method := http.MethodGet
+	if m := obj.Get("method"); !isNullish(m) {
+		method = strings.ToUpper(m.String())
+	}
+	return j.newRequest(method, obj.Get("url"), obj.Get("body"), obj.Get("params"))
+}
+
+func (j *jsVU) newRequest(method string, u, body, params goja.Value) *jsRequest {
+	if isNullish(u) {
+		panic(j.rt.NewTypeError("http." + strings.ToLower(method) + "() needs a URL"))
+	}
+	req := &jsRequest{
+		method: method,
+		url:    u.String(),
+		header: make(map[string]string),
+	}
+
+	var tags map[string]string
+	if !isNullish(params) {
+		p := params.ToObject(j.rt)
+		req.header = stringMap(p.Get("headers"))
+		tags = stringMap(p.Get("tags"))
+
+		if t := p.Get("timeout"); !isNullish(t) {
+			switch val := t.Export().(type) {
+			case string:
+				req.timeout, _ = time.ParseDuration(val)
+			case int64:
+				req.timeout = time.Duration(val) * time.Millisecond
+			case float64:
+				req.timeout = time.Duration(val * float64(time.Millisecond))
+			}
+		}
+	}
+
+	if !isNullish(body) {
+		switch val := body.Export().(type) {
+		case string:
+			req.body = []byte(val)
+		case goja.ArrayBuffer:
+			req.body = val.Bytes()
+		case map[string]interface{}:
+			// Like k6, object bodies are sent as a form
+			form := url.Values{}
+			for k, v := range val {
+				form.Set(k, fmt.Sprint(v))
+			}
+			req.body = []byte(form.Encode())
+			if _, ok := req.header["Content-Type"]; !ok {
+				req.header["Content-Type"] = "application/x-www-form-urlencoded"
+			}
+		default:
+			req.body = []byte(body.String())
+		}
+	}
+
+	if name := tags["name"]; name != "" || j.group != "" {
+		if name == "" {
+			name = req.url
+		}
+		req.metric = j.tagged("http_req_duration", "name", name)
+	}
+	return req
+}
+
+// do sends a request and records it. It does not touch the runtime, so
+// batch can call it from several goroutines.
+func (j *jsVU) do(req *jsRequest) *jsResponse {
+	ctx := context.Background()
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+
+	resp := &jsResponse{url: req.url}
+	start := time.Now()
+
+	var body io.Reader
+	if len(req.body) > 0 {
+		body = strings.NewReader(string(req.body))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, req.url, body)
+	if err == nil {
+		for k, v := range req.header {
+			httpReq.Header.Set(k, v)
+		}
+
+		var r *http.Response
+		if r, err = j.Client.Do(httpReq); err == nil {
+			resp.status = r.StatusCode
+			resp.proto = r.Proto
+			resp.header = r.Header
+			resp.url = r.Request.URL.String()
+			resp.body, err = io.ReadAll(io.LimitReader(r.Body, maxResponseBody))
+			r.Body.Close()
+		}
+	}
+	resp.err = err
+	resp.duration = time.Since(start)
+
+	j.AddRequest(resp.duration, err == nil && resp.status < 400)
+	if req.metric != "" {
+		j.AddDuration(req.metric, resp.duration)
+	}
+	return resp
+}
+
+func (j *jsVU) responseObject(resp *jsResponse) *goja.Object {
+	o := j.rt.NewObject()
+	o.Set("status", resp.status)
+	o.Set("proto", resp.proto)
+	o.Set("url", resp.url)
+	o.Set("body", string(resp.body))
+
+	errText := ""
+	if resp.err != nil {
+		errText = resp.err.Error()
+	}
+	o.Set("error", errText)
+
+	headers := j.rt.NewObject()
+	for k, v := range resp.header {
+		headers.Set(k, strings.Join(v, ", "))
+	}
+	o.Set("headers", headers)
+
+	timings := j.rt.NewObject()
+	timings.Set("duration", float64(resp.duration.Microseconds())/1000)
+	o.Set("timings", timings)
+
+	o.Set("json", func(selector string) goja.Value {
+		return j.jsonSelect(resp.body, selector)
+	})
+	return o
+}
+
+// jsonSelect parses a response body and optionally walks a selector
+// such as "data.items[0].id"
+func (j *jsVU) jsonSelect(body []byte, selector string) goja.Value {
+	parse, _ := goja.AssertFunction(j.rt.Get("JSON").ToObject(j.rt).Get("parse"))
+	val, err := parse(goja.Undefined(), j.rt.ToValue(string(body)))
+	if err != nil {
+		panic(j.rt.NewTypeError("response body is not JSON"))
+	}
+
+	selector = strings.NewReplacer("[", ".", "]", "").Replace(selector)
+	for _, part := range strings.Split(selector, ".") {
+		if part == "" {
+			continue
+		}
+		if isNullish(val) {
+			return goja.Undefined()
+		}
+		val = val.ToObject(j.rt).Get(part)
+		if val == nil {
+			return goja.Undefined()
+		}
+	}
+	return val
+}
+
+func isNullish(v goja.Value) bool {
+	return v == nil || goja.IsUndefined(v) || goja.IsNull(v)
+}
+
+// stringMap exports a JavaScript object of headers or tags
+func stringMap(v goja.Value) map[string]string {
+	out := make(map[string]string)
+	if isNullish(v) {
+		return out
+	}
+	m, _ := v.Export().(map[string]interface{})
+	for k, val := range m {
+		switch s := val.(type) {
+		case string:
+			out[k] = s
+		case int64:
+			out[k] = strconv.FormatInt(s, 10)
+		default:
+			out[k] = fmt.Sprint(val)
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+const (
+	// gracefulStop is how long iterations still running at the end of a
+	// k6 script run may take before they are interrupted
+	gracefulStop = 30 * time.Second
+
+	// rampPoll is how often VUs above the current stage target check
+	// whether they may start
+	rampPoll = 100 * time.Millisecond
+)
+
+// jsOptions is the subset of k6 options the engine understands
+type jsOptions struct {
+	VUs        int
+	Duration   time.Duration
+	Stages     []jsStage
+	Thresholds map[string][]string
+}
+
+type jsStage struct {
+	Duration time.Duration
+	Target   int
+}
+
+func parseJSOptions(raw map[string]interface{}) (jsOptions, error) {
+	var opts jsOptions
+	if raw == nil {
+		return opts, nil
+	}
+
+	// Round-trip through JSON so numbers and nested objects come out
+	// in predictable shapes
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return opts, err
+	}
+	var doc struct {
+		VUs      int             `json:"vus"`
+		Duration json.RawMessage `json:"duration"`
+		Stages   []struct {
+			Duration json.RawMessage `json:"duration"`
+			Target   int             `json:"target"`
+		} `json:"stages"`
+		Thresholds map[string]json.RawMessage `json:"thresholds"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return opts, errors.New("invalid k6 options: " + err.Error())
+	}
+
+	opts.VUs = doc.VUs
+	if opts.Duration, err = parseJSDuration(doc.Duration); err != nil {
+		return opts, err
+	}
+	for _, s := range doc.Stages {
+		d, err := parseJSDuration(s.Duration)
+		if err != nil {
+			return opts, err
+		}
+		opts.Stages = append(opts.Stages, jsStage{Duration: d, Target: s.Target})
+	}
+
+	if len(doc.Thresholds) > 0 {
+		opts.Thresholds = make(map[string][]string, len(doc.Thresholds))
+	}
+	for metric, raw := range doc.Thresholds {
+		exprs, err := parseThresholds(raw)
+		if err != nil {
+			return opts, errors.New("invalid thresholds for " + metric + ": " + err.Error())
+		}
+		opts.Thresholds[metric] = exprs
+	}
+	return opts, nil
+}
+
+// parseJSDuration accepts k6 duration strings such as "1m30s" and plain
+// numbers, which k6 reads as milliseconds
+func parseJSDuration(raw json.RawMessage) (time.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var ms float64
+	if err := json.Unmarshal(raw, &ms); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, errors.New("invalid duration: " + string(raw))
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, errors.New("invalid duration: " + s)
+	}
+	return d, nil
+}
+
+// parseThresholds accepts a single expression, a list of expressions
+// or a list of {threshold: ...} objects
+func parseThresholds(raw json.RawMessage) ([]string, error) {
+	var one string
+	if err := json.Unmarshal(raw, &one); err == nil {
+		return []string{one}, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	exprs := make([]string, 0, len(items))
+	for _, item := range items {
+		var expr string
+		if err := json.Unmarshal(item, &expr); err == nil {
+			exprs = append(exprs, expr)
+			continue
+		}
+
+		var obj struct {
+			Threshold string `json:"threshold"`
+		}
+		if err := json.Unmarshal(item, &obj); err != nil || obj.Threshold == "" {
+			return nil, errors.New("threshold must be a string or {threshold}")
+		}
+		exprs = append(exprs, obj.Threshold)
+	}
+	return exprs, nil
+}
+
+// jsSchedule decides how many VUs run and for how long
+type jsSchedule struct {
+	maxVUs   int
+	duration time.Duration
+
+	startVUs int
+	stages   []jsStage
+}
+
+// schedule resolves the VU schedule. As with k6's --vus and --duration
+// flags, VUs and duration set on the test config take precedence over
+// the script's options. Without any duration each VU runs the default
+// function once.
+func (o jsOptions) schedule(config model.TestConfig) jsSchedule {
+	if config.VUs > 0 || config.Duration > 0 {
+		s := jsSchedule{maxVUs: config.VUs, duration: time.Duration(config.Duration) * time.Second}
+		if s.maxVUs == 0 {
+			s.maxVUs = o.VUs
+		}
+		if s.duration == 0 {
+			s.duration = o.Duration
+		}
+		if s.maxVUs == 0 {
+			s.maxVUs = 1
+		}
+		return s
+	}
+
+	if len(o.Stages) > 0 {
+		s := jsSchedule{startVUs: o.VUs, stages: o.Stages}
+		if s.startVUs == 0 {
+			s.startVUs = 1
+		}
+		s.maxVUs = s.startVUs
+		for _, stage := range o.Stages {
+			s.duration += stage.Duration
+			if stage.Target > s.maxVUs {
+				s.maxVUs = stage.Target
+			}
+		}
+		return s
+	}
+
+	s := jsSchedule{maxVUs: o.VUs, duration: o.Duration}
+	if s.maxVUs == 0 {
+		s.maxVUs = 1
+	}
+	return s
+}
+
+// target is the number of VUs that should be active after elapsed.
+// Stages ramp linearly from the previous stage's target.
+func (s jsSchedule) target(elapsed time.Duration) int {
+	if len(s.stages) == 0 {
+		return s.maxVUs
+	}
+
+	from := s.startVUs
+	for _, stage := range s.stages {
+		if elapsed < stage.Duration {
+			frac := float64(elapsed) / float64(stage.Duration)
+			return from + int(float64(stage.Target-from)*frac)
+		}
+		elapsed -= stage.Duration
+		from = stage.Target
+	}
+	return from
+}
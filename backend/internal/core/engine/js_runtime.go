@@ -0,0 +1,379 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"k6clone/internal/core/model"
+)
+
+// k6 scripts are ES modules, which goja does not load. The handful of
+// import/export forms k6 scripts use are rewritten to require() and an
+// exports object before compiling.
+var (
+	importDefault = regexp.MustCompile(`(?m)^\s*import\s+(?:\*\s+as\s+)?([\w$]+)\s+from\s+['"]([^'"]+)['"]\s*;?`)
+	importNamed   = regexp.MustCompile(`(?m)^\s*import\s+\{([^}]*)\}\s*from\s+['"]([^'"]+)['"]\s*;?`)
+	importBare    = regexp.MustCompile(`(?m)^\s*import\s+['"]([^'"]+)['"]\s*;?`)
+	exportDefault = regexp.MustCompile(`(?m)^(\s*)export\s+default\s+`)
+	exportVar     = regexp.MustCompile(`(?m)^(\s*)export\s+(const|let|var)\s+([\w$]+)\s*=`)
+	exportFunc    = regexp.MustCompile(`(?m)^(\s*)export\s+function\s+([\w$]+)`)
+)
+
+// jsModules are the modules a script may import
+var jsModules = map[string]bool{
+	"k6":      true,
+	"k6/http": true,
+}
+
+// CompileJS checks that a k6 script parses and only imports supported
+// modules
+func CompileJS(source string) error {
+	_, err := compileJS(source)
+	return err
+}
+
+func compileJS(source string) (*goja.Program, error) {
+	src, err := transformModule(source)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := goja.Compile("script.js", src, false)
+	if err != nil {
+		return nil, errors.New("invalid k6 script: " + err.Error())
+	}
+	return program, nil
+}
+
+func transformModule(source string) (string, error) {
+	var unsupported []string
+	module := func(name string) string {
+		if !jsModules[name] {
+			unsupported = append(unsupported, name)
+		}
+		return fmt.Sprintf("require(%q)", name)
+	}
+
+	src := importNamed.ReplaceAllStringFunc(source, func(m string) string {
+		parts := importNamed.FindStringSubmatch(m)
+		// { a as b } becomes the destructuring { a: b }
+		names := strings.ReplaceAll(parts[1], " as ", ": ")
+		return "const {" + names + "} = " + module(parts[2]) + ";"
+	})
+	src = importDefault.ReplaceAllStringFunc(src, func(m string) string {
+		parts := importDefault.FindStringSubmatch(m)
+		return "const " + parts[1] + " = " + module(parts[2]) + ";"
+	})
+	src = importBare.ReplaceAllStringFunc(src, func(m string) string {
+		return module(importBare.FindStringSubmatch(m)[1]) + ";"
+	})
+
+	if len(unsupported) > 0 {
+		return "", errors.New("unsupported k6 module: " + strings.Join(unsupported, ", "))
+	}
+
+	var funcs []string
+	for _, m := range exportFunc.FindAllStringSubmatch(src, -1) {
+		funcs = append(funcs, m[2])
+	}
+
+	src = exportDefault.ReplaceAllString(src, "${1}exports.default = ")
+	src = exportVar.ReplaceAllString(src, "${1}${2} ${3} = exports.${3} =")
+	src = exportFunc.ReplaceAllString(src, "${1}function ${2}")
+
+	// Function declarations are hoisted, so exporting them at the end
+	// is equivalent
+	for _, name := range funcs {
+		src += "\nexports." + name + " = " + name + ";"
+	}
+	return src, nil
+}
+
+// jsVU is one VU running a k6 script in its own runtime. Runtimes are
+// not safe for concurrent use, so each VU gets its own, as in k6.
+type jsVU struct {
+	*VU
+	rt *goja.Runtime
+
+	exports *goja.Object
+	main    goja.Callable
+
+	dataDir string
+	initCtx bool
+	group   string
+
+	// hardStop bounds sleeps once the run is being interrupted
+	hardStop time.Time
+}
+
+func newJSVU(v *VU, program *goja.Program, dataDir string) (*jsVU, error) {
+	j := &jsVU{
+		VU:      v,
+		rt:      goja.New(),
+		dataDir: dataDir,
+		initCtx: true,
+	}
+
+	j.exports = j.rt.NewObject()
+	env := j.rt.NewObject()
+	for k, val := range v.Vars {
+		env.Set(k, val)
+	}
+
+	console := j.rt.NewObject()
+	for _, level := range []string{"log", "info", "warn", "error", "debug"} {
+		console.Set(level, j.consoleLog)
+	}
+
+	j.rt.Set("exports", j.exports)
+	j.rt.Set("require", j.require)
+	j.rt.Set("open", j.open)
+	j.rt.Set("console", console)
+	j.rt.Set("__ENV", env)
+	j.rt.Set("__VU", v.ID)
+	j.rt.Set("__ITER", 0)
+
+	if _, err := j.rt.RunProgram(program); err != nil {
+		return nil, err
+	}
+	j.initCtx = false
+
+	main, ok := goja.AssertFunction(j.exports.Get("default"))
+	if !ok {
+		return nil, errors.New("k6 script has no default function")
+	}
+	j.main = main
+	return j, nil
+}
+
+// iterate runs the default function once. Exceptions end the iteration
+// early but not the VU, like in k6.
+func (j *jsVU) iterate(iter int) {
+	j.rt.Set("__ITER", iter)
+	j.group = ""
+
+	if _, err := j.main(goja.Undefined()); err != nil {
+		var interrupted *goja.InterruptedError
+		if !errors.As(err, &interrupted) {
+			j.AddCount("script_errors", 1)
+			log.Printf("VU %d: %v", j.ID, err)
+		}
+	}
+}
+
+// options returns the script's exported options as plain Go values
+func (j *jsVU) options() map[string]interface{} {
+	opts := j.exports.Get("options")
+	if opts == nil || goja.IsUndefined(opts) || goja.IsNull(opts) {
+		return nil
+	}
+	m, _ := opts.Export().(map[string]interface{})
+	return m
+}
+
+func (j *jsVU) require(name string) goja.Value {
+	switch name {
+	case "k6":
+		m := j.rt.NewObject()
+		m.Set("check", j.check)
+		m.Set("group", j.groupFn)
+		m.Set("sleep", j.sleep)
+		m.Set("fail", j.fail)
+		return m
+	case "k6/http":
+		return j.httpModule()
+	}
+	panic(j.rt.NewTypeError("unsupported k6 module: " + name))
+}
+
+// check runs each named predicate against val and records the outcome.
+// It returns true only if all of them pass.
+func (j *jsVU) check(val goja.Value, sets *goja.Object) bool {
+	if sets == nil {
+		return true
+	}
+
+	all := true
+	for _, name := range sets.Keys() {
+		pred := sets.Get(name)
+		ok := pred.ToBoolean()
+		if fn, isFn := goja.AssertFunction(pred); isFn {
+			res, err := fn(goja.Undefined(), val)
+			ok = err == nil && res.ToBoolean()
+		}
+
+		result := "pass"
+		if !ok {
+			result = "fail"
+			all = false
+		}
+		j.AddCount(j.tagged("checks", "check", name, "result", result), 1)
+	}
+	return all
+}
+
+// groupFn runs fn as a named group. Requests, checks and the group's
+// own duration are tagged with the group path, e.g. "::login::submit".
+func (j *jsVU) groupFn(name string, fn goja.Callable) goja.Value {
+	parent := j.group
+	j.group = parent + "::" + name
+	defer func() { j.group = parent }()
+
+	start := time.Now()
+	res, err := fn(goja.Undefined())
+	j.AddDuration(MetricName("group_duration", "group", j.group), time.Since(start))
+
+	if err != nil {
+		var ex *goja.Exception
+		if errors.As(err, &ex) {
+			panic(ex.Value())
+		}
+		panic(j.rt.NewGoError(err))
+	}
+	return res
+}
+
+func (j *jsVU) sleep(seconds float64) {
+	d := time.Duration(seconds * float64(time.Second))
+	if !j.hardStop.IsZero() {
+		if left := time.Until(j.hardStop); d > left {
+			d = left
+		}
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (j *jsVU) fail(msg string) {
+	panic(j.rt.NewGoError(errors.New(msg)))
+}
+
+func (j *jsVU) consoleLog(call goja.FunctionCall) goja.Value {
+	parts := make([]string, len(call.Arguments))
+	for i, arg := range call.Arguments {
+		parts[i] = arg.String()
+	}
+	log.Printf("VU %d: %s", j.ID, strings.Join(parts, " "))
+	return goja.Undefined()
+}
+
+// open reads a data file from the engine's data directory. Like in k6
+// it is only available in the init context. Files are read once per
+// run and shared by all VUs.
+func (j *jsVU) open(name string, mode string) goja.Value {
+	if !j.initCtx {
+		panic(j.rt.NewTypeError("open() is only available in the init context"))
+	}
+
+	// Scripts come from users, so paths cannot leave the data directory
+	path := filepath.Join(j.dataDir, filepath.Clean("/"+name))
+	data := j.Shared("js.file:"+path, func() interface{} {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return b
+	})
+	if err, ok := data.(error); ok {
+		panic(j.rt.NewGoError(err))
+	}
+
+	if mode == "b" {
+		return j.rt.ToValue(j.rt.NewArrayBuffer(data.([]byte)))
+	}
+	return j.rt.ToValue(string(data.([]byte)))
+}
+
+// tagged adds the current group to a metric's tags
+func (j *jsVU) tagged(name string, tags ...string) string {
+	if j.group != "" {
+		tags = append([]string{"group", j.group}, tags...)
+	}
+	return MetricName(name, tags...)
+}
+
+// runJS runs a k6 script. The first VU's init context also provides
+// the options.
+func (e *LoadEngine) runJS(r *run, script *model.Script, config model.TestConfig) (model.TestResult, error) {
+	program, err := compileJS(script.Source)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	first, err := newJSVU(r.newVU(1, script), program, e.dataDir)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	opts, err := parseJSOptions(first.options())
+	if err != nil {
+		first.close()
+		return model.TestResult{}, err
+	}
+	sched := opts.schedule(config)
+
+	startedAt := time.Now()
+	endAt := startedAt.Add(sched.duration)
+	hardStop := endAt.Add(gracefulStop)
+
+	wg := sync.WaitGroup{}
+	for id := 1; id <= sched.maxVUs; id++ {
+		wg.Add(1)
+
+		go func(id int) {
+			defer wg.Done()
+
+			j := first
+			if id > 1 {
+				var err error
+				v := r.newVU(id, script)
+				if j, err = newJSVU(v, program, e.dataDir); err != nil {
+					v.close()
+					r.c.addCount("script_errors", 1)
+					log.Printf("VU %d: %v", id, err)
+					return
+				}
+			}
+			defer j.close()
+
+			j.hardStop = hardStop
+			stop := time.AfterFunc(time.Until(hardStop), func() {
+				j.rt.Interrupt("run stopped")
+			})
+			defer stop.Stop()
+
+			if sched.duration == 0 {
+				r.c.addIteration()
+				j.iterate(0)
+				return
+			}
+
+			for iter := 0; time.Now().Before(endAt); {
+				if id > sched.target(time.Since(startedAt)) {
+					time.Sleep(rampPoll)
+					continue
+				}
+
+				r.c.addIteration()
+				j.iterate(iter)
+				iter++
+			}
+		}(id)
+	}
+
+	wg.Wait()
+
+	result := r.c.result(config, startedAt)
+	result.Thresholds = r.c.thresholds(opts.Thresholds, time.Since(startedAt))
+	return result, nil
+}
@@ -1,126 +1,208 @@
 package engine
 
 import (
+	"io"
 	"net/http"
-	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"k6clone/internal/core/model"
 )
 
-type LoadEngine struct {}
+type LoadEngine struct {
+	protoDir string
+	dataDir  string
+}
 
-func NewLoadEngine() *LoadEngine {
-	return &LoadEngine{}
+// NewLoadEngine creates an engine. protoDir is where uploaded .proto
+// files for GRPC steps are looked up and dataDir is where k6 scripts
+// open() their data files.
+func NewLoadEngine(protoDir, dataDir string) *LoadEngine {
+	return &LoadEngine{protoDir: protoDir, dataDir: dataDir}
 }
 
+// Run executes a script. Scripts with k6 JavaScript source run in the
+// embedded runtime; everything else runs step by step.
 func (e *LoadEngine) Run(
 	script *model.Script,
 	config model.TestConfig,
-) model.TestResult {
+) (model.TestResult, error) {
+
+	c := newCollector()
+	r := &run{
+		engine: e,
+		c:      c,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		shared: make(map[string]interface{}),
+	}
 
-	var mu sync.Mutex
+	if script.Source != "" {
+		return e.runJS(r, script, config)
+	}
 
-	var total, success, failure int
-	var latencies []int64
-	var iterations int
+	datasets, err := e.loadDatasets(script)
+	if err != nil {
+		return model.TestResult{}, err
+	}
 
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	if script.Replay != nil {
+		return e.replay(r, script, config, datasets)
+	}
+
+	var rampUp time.Duration
+	iterations := 0
+	if sc := script.Scenario; sc != nil {
+		rampUp = time.Duration(sc.RampUpSeconds) * time.Second
+		iterations = sc.Iterations
+	}
+	if config.Duration <= 0 && iterations == 0 {
+		iterations = 1 // as for k6 scripts without a duration
 	}
 
 	startedAt := time.Now()
 	endAt := startedAt.Add(time.Duration(config.Duration) * time.Second)
+	running := func() bool {
+		return config.Duration <= 0 || time.Now().Before(endAt)
+	}
+
+	mix := newStepMix(script.Steps)
 
 	wg := sync.WaitGroup{}
 
-	for vu := 0; vu < config.VUs; vu++ {
+	for id := 1; id <= config.VUs; id++ {
 		wg.Add(1)
 
-		go func() {
-			defer wg.Done()
-
-			for time.Now().Before(endAt) {
-				iterations++
-
-				for _, step := range script.Steps {
-					start := time.Now()
+		// VUs start evenly spread over the ramp-up
+		delay := rampUp * time.Duration(id-1) / time.Duration(config.VUs)
 
-					req, _ := http.NewRequest(step.Method, step.URL, nil)
-					resp, err := client.Do(req)
-					latency := time.Since(start).Milliseconds()
-
-					mu.Lock()
-					total++
-					latencies = append(latencies, latency)
+		go func(v *VU) {
+			defer wg.Done()
+			defer v.close()
 
-					if err == nil && resp != nil && resp.StatusCode < 400 {
-						success++
-					} else {
-						failure++
-					}
-					mu.Unlock()
+			if delay > 0 {
+				if config.Duration > 0 && delay > time.Until(endAt) {
+					return
+				}
+				time.Sleep(delay)
+			}
 
-					if resp != nil {
-						resp.Body.Close()
-					}
+			for n := 0; (iterations == 0 || n < iterations) && running(); n++ {
+				if !fillRows(v, datasets) {
+					return
 				}
+				c.addIteration()
+				if mix != nil {
+					runPicked(v, script.Steps, mix.pick())
+					continue
+				}
+				runSteps(v, script.Steps)
 			}
-		}()
+		}(r.newVU(id, script))
 	}
 
 	wg.Wait()
 
-	// ---- METRICS CALCULATION ----
+	return c.result(config, startedAt), nil
+}
 
-	sort.Slice(latencies, func(i, j int) bool {
-		return latencies[i] < latencies[j]
-	})
+func (r *run) newVU(id int, script *model.Script) *VU {
+	return &VU{
+		ID:        id,
+		Client:    r.client,
+		Vars:      copyVars(script.Variables),
+		run:       r,
+		resources: make(map[string]io.Closer),
+	}
+}
 
-	avgLatency := int64(0)
-	if total > 0 {
-		sum := int64(0)
-		for _, l := range latencies {
-			sum += l
+// fillRows takes the next row of every dataset for an iteration
+func fillRows(v *VU, datasets []*dataset) bool {
+	for _, d := range datasets {
+		if !d.fill(v.Vars) {
+			return false
 		}
-		avgLatency = sum / int64(total)
-	}
-
-	p90 := percentile(latencies, 90)
-	p95 := percentile(latencies, 95)
-	p99 := percentile(latencies, 99)
-
-	durationSec := time.Since(startedAt).Seconds()
-	rps := float64(total) / durationSec
-
-	return model.TestResult{
-		TestID:        time.Now().Format("20060102150405"),
-		ScriptID:      config.ScriptID,
-		TotalRequests: total,
-		Success:       success,
-		Failure:       failure,
-		AvgLatencyMs:  avgLatency,
-		P90LatencyMs:  p90,
-		P95LatencyMs:  p95,
-		P99LatencyMs:  p99,
-		RPS:           rps,
-		Iterations:    iterations,
-		StartedAt:     startedAt,
+	}
+	return true
+}
+
+// runSteps runs one iteration of a script. Consecutive steps of the
+// same group are timed together as group_duration, tagged like k6's
+// groups: steps in "users::admin" record both {group:::users} and
+// {group:::users::admin}.
+func runSteps(v *VU, steps []model.Step) {
+	g := groupTimer{v: v}
+	for i := range steps {
+		step := &steps[i]
+		g.enter(GroupPath(step.Group))
+		runStep(v, i, step)
+	}
+	g.enter(nil)
+}
+
+// runPicked runs the single step a traffic mix picked for an iteration
+func runPicked(v *VU, steps []model.Step, index int) {
+	g := groupTimer{v: v}
+	g.enter(GroupPath(steps[index].Group))
+	runStep(v, index, &steps[index])
+	g.enter(nil)
+}
+
+// groupTimer tracks the groups an iteration is in
+type groupTimer struct {
+	v       *VU
+	open    []string // group path currently entered
+	started []time.Time
+}
+
+// enter leaves the open groups that are not part of path, recording
+// their durations, and enters the rest of path
+func (g *groupTimer) enter(path []string) {
+	common := 0
+	for common < len(g.open) && common < len(path) && g.open[common] == path[common] {
+		common++
+	}
+	for len(g.open) > common {
+		last := len(g.open) - 1
+		name := "::" + strings.Join(g.open, "::")
+		g.v.AddDuration(MetricName("group_duration", "group", name), time.Since(g.started[last]))
+		g.open, g.started = g.open[:last], g.started[:last]
+	}
+	for _, name := range path[common:] {
+		g.open = append(g.open, name)
+		g.started = append(g.started, time.Now())
 	}
 }
 
-// ---------- helpers ----------
+// GroupPath splits a step's group into its nested group names
+func GroupPath(group string) []string {
+	if group == "" {
+		return nil
+	}
+	return strings.Split(group, "::")
+}
 
-func percentile(values []int64, p int) int64 {
-	if len(values) == 0 {
-		return 0
+// runStep hands a step to its executor after the step's think time.
+// Steps of an unknown type count as failed requests.
+func runStep(v *VU, index int, step *model.Step) {
+	if step.ThinkTimeMs > 0 {
+		time.Sleep(time.Duration(step.ThinkTimeMs) * time.Millisecond)
 	}
 
-	index := (p * len(values)) / 100
-	if index >= len(values) {
-		index = len(values) - 1
+	e, ok := Executor(step.Type)
+	if !ok {
+		v.AddRequest(0, false)
+		return
 	}
-	return values[index]
+	e.Run(v, index, ApplyAuth(step, v.Expand))
 }
 
+func copyVars(vars map[string]string) map[string]string {
+	out := make(map[string]string, len(vars))
+	for k, val := range vars {
+		out[k] = val
+	}
+	return out
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+// collector gathers samples from all VUs of a run
+type collector struct {
+	mu sync.Mutex
+
+	total, success, failure int
+	iterations              int
+	latencies               []int64
+
+	trends   map[string][]float64
+	counters map[string]int64
+}
+
+func newCollector() *collector {
+	return &collector{
+		trends:   make(map[string][]float64),
+		counters: make(map[string]int64),
+	}
+}
+
+// addRequest records one request towards the overall totals
+func (c *collector) addRequest(latency time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total++
+	c.latencies = append(c.latencies, latency.Milliseconds())
+	if ok {
+		c.success++
+	} else {
+		c.failure++
+	}
+}
+
+func (c *collector) addIteration() {
+	c.mu.Lock()
+	c.iterations++
+	c.mu.Unlock()
+}
+
+// addTrend records a sample for a named metric
+func (c *collector) addTrend(name string, value float64) {
+	c.mu.Lock()
+	c.trends[name] = append(c.trends[name], value)
+	c.mu.Unlock()
+}
+
+// addCount increments a named counter
+func (c *collector) addCount(name string, n int64) {
+	c.mu.Lock()
+	c.counters[name] += n
+	c.mu.Unlock()
+}
+
+// addDuration records a duration sample in milliseconds
+func (c *collector) addDuration(name string, d time.Duration) {
+	c.addTrend(name, float64(d.Microseconds())/1000)
+}
+
+func (c *collector) result(config model.TestConfig, startedAt time.Time) model.TestResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sort.Slice(c.latencies, func(i, j int) bool {
+		return c.latencies[i] < c.latencies[j]
+	})
+
+	avgLatency := int64(0)
+	if c.total > 0 {
+		sum := int64(0)
+		for _, l := range c.latencies {
+			sum += l
+		}
+		avgLatency = sum / int64(c.total)
+	}
+
+	durationSec := time.Since(startedAt).Seconds()
+	rps := float64(c.total) / durationSec
+
+	var metrics map[string]model.MetricSummary
+	if len(c.trends) > 0 {
+		metrics = make(map[string]model.MetricSummary, len(c.trends))
+		for name, values := range c.trends {
+			metrics[name] = summarize(values)
+		}
+	}
+
+	var counters map[string]int64
+	if len(c.counters) > 0 {
+		counters = make(map[string]int64, len(c.counters))
+		for name, n := range c.counters {
+			counters[name] = n
+		}
+	}
+
+	testID := config.TestID
+	if testID == "" {
+		testID = time.Now().Format("20060102150405")
+	}
+
+	return model.TestResult{
+		TestID:        testID,
+		ScriptID:      config.ScriptID,
+		TotalRequests: c.total,
+		Success:       c.success,
+		Failure:       c.failure,
+		AvgLatencyMs:  avgLatency,
+		P90LatencyMs:  percentile(c.latencies, 90),
+		P95LatencyMs:  percentile(c.latencies, 95),
+		P99LatencyMs:  percentile(c.latencies, 99),
+		RPS:           rps,
+		Iterations:    c.iterations,
+		StartedAt:     startedAt,
+		Metrics:       metrics,
+		Counters:      counters,
+	}
+}
+
+// ---------- helpers ----------
+
+func percentile(values []int64, p int) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	index := (p * len(values)) / 100
+	if index >= len(values) {
+		index = len(values) - 1
+	}
+	return values[index]
+}
+
+func summarize(values []float64) model.MetricSummary {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+
+	pick := func(p int) float64 {
+		index := (p * len(sorted)) / 100
+		if index >= len(sorted) {
+			index = len(sorted) - 1
+		}
+		return sorted[index]
+	}
+
+	return model.MetricSummary{
+		Count: len(sorted),
+		Avg:   sum / float64(len(sorted)),
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P90:   pick(90),
+		P95:   pick(95),
+		P99:   pick(99),
+	}
+}
+
+// MetricName builds a tagged metric name such as
+// "batch_duration{step:1}". Tags are given as key/value pairs.
+func MetricName(name string, tags ...string) string {
+	if len(tags) < 2 {
+		return name
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i := 0; i+1 < len(tags); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(tags[i])
+		b.WriteByte(':')
+		b.WriteString(tags[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,272 @@
+package engine
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+const defaultRedisTimeout = 5 * time.Second
+
+func init() {
+	Register(model.Redis, redisExecutor{})
+}
+
+type redisExecutor struct{}
+
+func (redisExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("redis step address is empty")
+	}
+	if step.Redis == nil || len(step.Redis.Commands) == 0 {
+		return errors.New("redis step has no commands")
+	}
+	for _, cmd := range step.Redis.Commands {
+		if len(cmd) == 0 || cmd[0] == "" {
+			return errors.New("redis command is empty")
+		}
+	}
+	if step.Redis.TimeoutMs < 0 {
+		return errors.New("redis timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// redisConn is a minimal RESP client connection
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// redisError is an error reply from the server, as opposed to a
+// network or protocol failure
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// redisClient keeps one connection per address for a single VU
+type redisClient struct {
+	conns map[string]*redisConn
+}
+
+func newRedisClient() *redisClient {
+	return &redisClient{conns: make(map[string]*redisConn)}
+}
+
+func (rc *redisClient) conn(addr string, timeout time.Duration) (*redisConn, error) {
+	if conn, ok := rc.conns[addr]; ok {
+		return conn, nil
+	}
+
+	host, password, db := addr, "", ""
+	if strings.HasPrefix(addr, "redis://") {
+		u, err := url.Parse(addr)
+		if err != nil {
+			return nil, err
+		}
+		host = u.Host
+		if pw, ok := u.User.Password(); ok {
+			password = pw
+		}
+		db = strings.TrimPrefix(u.Path, "/")
+	}
+
+	nc, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := &redisConn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	nc.SetDeadline(time.Now().Add(timeout))
+	if password != "" {
+		if _, err := conn.do("AUTH", password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if db != "" && db != "0" {
+		if _, err := conn.do("SELECT", db); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+
+	rc.conns[addr] = conn
+	return conn, nil
+}
+
+func (rc *redisClient) drop(addr string) {
+	if conn, ok := rc.conns[addr]; ok {
+		conn.conn.Close()
+		delete(rc.conns, addr)
+	}
+}
+
+func (rc *redisClient) Close() error {
+	for _, conn := range rc.conns {
+		conn.conn.Close()
+	}
+	return nil
+}
+
+// Run runs the step's commands one by one or as a pipeline. Each
+// command counts as a request and is tagged by command name.
+func (redisExecutor) Run(v *VU, index int, step *model.Step) {
+	if step.Redis == nil || len(step.Redis.Commands) == 0 {
+		v.AddRequest(0, false)
+		return
+	}
+
+	r, _ := v.Resource("redis", func() (io.Closer, error) {
+		return newRedisClient(), nil
+	})
+	client := r.(*redisClient)
+
+	timeout := defaultRedisTimeout
+	if step.Redis.TimeoutMs > 0 {
+		timeout = time.Duration(step.Redis.TimeoutMs) * time.Millisecond
+	}
+
+	commands := make([][]string, len(step.Redis.Commands))
+	for i, cmd := range step.Redis.Commands {
+		commands[i] = make([]string, len(cmd))
+		for j, arg := range cmd {
+			commands[i][j] = v.Expand(arg)
+		}
+	}
+
+	record := func(cmd []string, latency time.Duration, err error) {
+		name := strings.ToUpper(cmd[0])
+		v.AddDuration(MetricName("redis_cmd_duration", "cmd", name), latency)
+		if err != nil {
+			v.AddCount(MetricName("redis_errors", "cmd", name), 1)
+		}
+		v.AddRequest(latency, err == nil)
+	}
+
+	addr := v.Expand(step.URL)
+	start := time.Now()
+	conn, err := client.conn(addr, timeout)
+	if err != nil {
+		for _, cmd := range commands {
+			record(cmd, time.Since(start), err)
+		}
+		return
+	}
+	conn.conn.SetDeadline(time.Now().Add(timeout))
+
+	if step.Redis.Pipeline {
+		start := time.Now()
+		for _, cmd := range commands {
+			conn.write(cmd)
+		}
+		err := conn.w.Flush()
+
+		// Error replies belong to one command; anything else leaves
+		// the remaining replies unreadable
+		for _, cmd := range commands {
+			if err == nil || isRedisError(err) {
+				_, err = conn.read()
+			}
+			record(cmd, time.Since(start), err)
+		}
+		if err != nil && !isRedisError(err) {
+			client.drop(addr)
+		}
+		v.AddDuration("redis_pipeline_duration", time.Since(start))
+		return
+	}
+
+	for _, cmd := range commands {
+		start := time.Now()
+		_, err := conn.do(cmd...)
+		record(cmd, time.Since(start), err)
+
+		// After a network error the reply stream is out of sync
+		if err != nil && !isRedisError(err) {
+			client.drop(addr)
+			return
+		}
+	}
+}
+
+func isRedisError(err error) bool {
+	var re redisError
+	return errors.As(err, &re)
+}
+
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	c.write(args)
+	if err := c.w.Flush(); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// write encodes a command as a RESP array of bulk strings
+func (c *redisConn) write(args []string) {
+	c.w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		c.w.WriteString("$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n")
+	}
+}
+
+// read decodes one reply. Error replies are returned as redisError.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := c.read()
+			if err != nil && !isRedisError(err) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	return nil, errors.New("unexpected redis reply: " + line)
+}
@@ -0,0 +1,266 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"k6clone/internal/core/model"
+)
+
+// redisStub is an in-process server speaking enough RESP for GET, SET,
+// INCR, AUTH and SELECT
+type redisStub struct {
+	addr     string
+	password string
+
+	mu   sync.Mutex
+	data map[string]string
+	cmds []string // command names in the order received
+}
+
+func newRedisStub(t *testing.T, password string) *redisStub {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &redisStub{addr: ln.Addr().String(), password: password, data: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *redisStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := s.password == ""
+
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(args[0])
+
+		s.mu.Lock()
+		s.cmds = append(s.cmds, name)
+		var reply string
+		switch {
+		case name == "AUTH":
+			if len(args) == 2 && args[1] == s.password {
+				authed = true
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case name == "SELECT":
+			reply = "+OK\r\n"
+		case name == "SET" && len(args) == 3:
+			s.data[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case name == "GET" && len(args) == 2:
+			if v, ok := s.data[args[1]]; ok {
+				reply = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+			} else {
+				reply = "$-1\r\n"
+			}
+		case name == "INCR" && len(args) == 2:
+			n, err := strconv.ParseInt(s.data[args[1]], 10, 64)
+			if err != nil && s.data[args[1]] != "" {
+				reply = "-ERR value is not an integer or out of range\r\n"
+				break
+			}
+			n++
+			s.data[args[1]] = strconv.FormatInt(n, 10)
+			reply = ":" + strconv.FormatInt(n, 10) + "\r\n"
+		default:
+			reply = "-ERR unknown command '" + args[0] + "'\r\n"
+		}
+		s.mu.Unlock()
+
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (s *redisStub) get(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[key]
+}
+
+func (s *redisStub) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.cmds...)
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func TestRedisStepCommands(t *testing.T) {
+	stub := newRedisStub(t, "")
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type: model.Redis,
+		URL:  stub.addr,
+		Redis: &model.RedisStep{Commands: [][]string{
+			{"SET", "greeting", "hello"},
+			{"GET", "greeting"},
+			{"INCR", "hits"},
+			{"INCR", "hits"},
+			{"GET", "missing"},
+		}},
+	}}})
+
+	if result.TotalRequests != 5 || result.Success != 5 {
+		t.Fatalf("requests = %d, success = %d; want 5, 5", result.TotalRequests, result.Success)
+	}
+	if got := stub.get("hits"); got != "2" {
+		t.Errorf("hits = %q, want 2", got)
+	}
+	if got := result.Metrics["redis_cmd_duration{cmd:INCR}"].Count; got != 2 {
+		t.Errorf("INCR duration count = %d, want 2", got)
+	}
+	if got := result.Metrics["redis_cmd_duration{cmd:GET}"].Count; got != 2 {
+		t.Errorf("GET duration count = %d, want 2", got)
+	}
+	if len(result.Counters) != 0 {
+		t.Errorf("unexpected counters %v", result.Counters)
+	}
+}
+
+func TestRedisStepErrorReply(t *testing.T) {
+	stub := newRedisStub(t, "")
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type: model.Redis,
+		URL:  stub.addr,
+		Redis: &model.RedisStep{Commands: [][]string{
+			{"SET", "name", "not a number"},
+			{"INCR", "name"},
+			{"GET", "name"}, // the connection is still usable
+		}},
+	}}})
+
+	if result.Success != 2 || result.Failure != 1 {
+		t.Errorf("success = %d, failure = %d; want 2, 1", result.Success, result.Failure)
+	}
+	if got := result.Counters["redis_errors{cmd:INCR}"]; got != 1 {
+		t.Errorf("INCR errors = %d, want 1", got)
+	}
+}
+
+func TestRedisStepPipeline(t *testing.T) {
+	stub := newRedisStub(t, "")
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type: model.Redis,
+		URL:  stub.addr,
+		Redis: &model.RedisStep{
+			Pipeline: true,
+			Commands: [][]string{
+				{"SET", "word", "abc"},
+				{"INCR", "word"}, // error reply for this command only
+				{"INCR", "counter"},
+				{"NOPE"},
+				{"GET", "counter"},
+			},
+		},
+	}}})
+
+	if result.TotalRequests != 5 || result.Success != 3 || result.Failure != 2 {
+		t.Errorf("requests = %d, success = %d, failure = %d; want 5, 3, 2",
+			result.TotalRequests, result.Success, result.Failure)
+	}
+	if got := result.Counters["redis_errors{cmd:INCR}"]; got != 1 {
+		t.Errorf("INCR errors = %d, want 1", got)
+	}
+	if got := result.Counters["redis_errors{cmd:NOPE}"]; got != 1 {
+		t.Errorf("NOPE errors = %d, want 1", got)
+	}
+	if got := result.Metrics["redis_pipeline_duration"].Count; got != 1 {
+		t.Errorf("pipeline duration count = %d, want 1", got)
+	}
+	if got := stub.get("counter"); got != "1" {
+		t.Errorf("counter = %q, want 1", got)
+	}
+}
+
+func TestRedisStepAuthAndSelect(t *testing.T) {
+	stub := newRedisStub(t, "s3cret")
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type:  model.Redis,
+		URL:   "redis://:s3cret@" + stub.addr + "/2",
+		Redis: &model.RedisStep{Commands: [][]string{{"SET", "k", "v"}}},
+	}}})
+
+	if result.Success != 1 {
+		t.Fatalf("success = %d, want 1", result.Success)
+	}
+	want := []string{"AUTH", "SELECT", "SET"}
+	if got := stub.received(); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("commands = %v, want %v", got, want)
+	}
+}
+
+func TestRedisStepWrongPassword(t *testing.T) {
+	stub := newRedisStub(t, "s3cret")
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type:  model.Redis,
+		URL:   "redis://:wrong@" + stub.addr,
+		Redis: &model.RedisStep{Commands: [][]string{{"GET", "a"}, {"GET", "b"}}},
+	}}})
+
+	if result.Failure != 2 {
+		t.Errorf("failure = %d, want 2", result.Failure)
+	}
+	if got := result.Counters["redis_errors{cmd:GET}"]; got != 2 {
+		t.Errorf("GET errors = %d, want 2", got)
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"k6clone/internal/core/model"
+)
+
+// StepExecutor implements one step type. Executors register themselves
+// with Register, usually from an init function next to the
+// implementation, and the engine loop dispatches to them by type.
+type StepExecutor interface {
+	// Validate checks a step before it is stored or run
+	Validate(step *model.Step) error
+
+	// Run executes the step once for a VU and records its metrics.
+	// index is the step's position in the script, starting at 0.
+	Run(v *VU, index int, step *model.Step)
+}
+
+// K6Generator is implemented by executors whose steps have a k6
+// JavaScript equivalent
+type K6Generator interface {
+	K6JS(index int, step *model.Step) (K6Snippet, error)
+}
+
+// K6Snippet is the k6 code for one step
+type K6Snippet struct {
+	Imports []string // full import lines, deduplicated across steps
+	Init    string   // init-context code, e.g. loading proto files
+	Code    string   // code inside the default function
+
+	// Options are numeric k6 options such as "batch"; when several
+	// steps set the same option the largest value wins
+	Options map[string]int
+}
+
+var (
+	registryMu sync.RWMutex
+	executors  = make(map[model.StepType]StepExecutor)
+)
+
+// Register makes an executor available for a step type. It panics if
+// the type is registered twice, since that is always a programming
+// error.
+func Register(t model.StepType, e StepExecutor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := executors[t]; dup {
+		panic("engine: step type registered twice: " + string(t))
+	}
+	executors[t] = e
+}
+
+// Executor returns the executor for a step type. Steps without a type
+// are treated as HTTP, as they were before types existed.
+func Executor(t model.StepType) (StepExecutor, bool) {
+	if t == "" {
+		t = model.HTTP
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	e, ok := executors[t]
+	return e, ok
+}
+
+// StepTypes lists the registered step types
+func StepTypes() []model.StepType {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]model.StepType, 0, len(executors))
+	for t := range executors {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// ValidateStep validates a step with its type's executor
+func ValidateStep(step *model.Step) error {
+	e, ok := Executor(step.Type)
+	if !ok {
+		return errors.New("unsupported step type: " + string(step.Type))
+	}
+	if err := validateAuth(step.Auth); err != nil {
+		return err
+	}
+	return e.Validate(step)
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+// stepMix picks steps in proportion to their weights
+type stepMix struct {
+	steps []int // indexes of weighted steps
+	upTo  []int // cumulative weights
+	total int
+}
+
+// newStepMix returns nil when no step has a weight and the script runs
+// in order
+func newStepMix(steps []model.Step) *stepMix {
+	m := &stepMix{}
+	for i, s := range steps {
+		if s.Weight > 0 {
+			m.total += s.Weight
+			m.steps = append(m.steps, i)
+			m.upTo = append(m.upTo, m.total)
+		}
+	}
+	if m.total == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *stepMix) pick() int {
+	n := rand.IntN(m.total)
+	i := sort.SearchInts(m.upTo, n+1)
+	return m.steps[i]
+}
+
+// replay runs every step once at its offset, scaled by the replay
+// speed. Each request takes a free VU, so the test's VUs bound the
+// requests in flight; the time a request waits for one is recorded as
+// replay_lag.
+func (e *LoadEngine) replay(r *run, script *model.Script, config model.TestConfig, datasets []*dataset) (model.TestResult, error) {
+	speed := script.Replay.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	vus := max(config.VUs, 1)
+	pool := make(chan *VU, vus)
+	for id := 1; id <= vus; id++ {
+		pool <- r.newVU(id, script)
+	}
+
+	startedAt := time.Now()
+	endAt := startedAt.Add(time.Duration(config.Duration) * time.Second)
+
+	wg := sync.WaitGroup{}
+
+	for i := range script.Steps {
+		at := startedAt.Add(time.Duration(float64(script.Steps[i].OffsetMs) / speed * float64(time.Millisecond)))
+		if config.Duration > 0 && at.After(endAt) {
+			break
+		}
+		time.Sleep(time.Until(at))
+
+		v := <-pool
+		if lag := time.Since(at); lag > time.Millisecond {
+			v.AddDuration("replay_lag", lag)
+		}
+		if !fillRows(v, datasets) {
+			pool <- v
+			break
+		}
+		r.c.addIteration()
+
+		wg.Add(1)
+		go func(v *VU, index int) {
+			defer wg.Done()
+			runPicked(v, script.Steps, index)
+			pool <- v
+		}(v, i)
+	}
+
+	wg.Wait()
+	close(pool)
+	for v := range pool {
+		v.close()
+	}
+
+	return r.c.result(config, startedAt), nil
+}
@@ -0,0 +1,265 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+
+	"k6clone/internal/core/model"
+)
+
+// responseDoc evaluates check and extraction rules against a response
+// body, parsing it at most once
+type responseDoc struct {
+	body       []byte
+	namespaces map[string]string
+
+	xmlRoot *xmlquery.Node
+	xmlErr  error
+	xmlDone bool
+
+	jsonRoot interface{}
+	jsonErr  error
+	jsonDone bool
+}
+
+func newResponseDoc(body []byte, namespaces map[string]string) *responseDoc {
+	return &responseDoc{body: body, namespaces: namespaces}
+}
+
+func (d *responseDoc) xml() (*xmlquery.Node, error) {
+	if !d.xmlDone {
+		d.xmlRoot, d.xmlErr = xmlquery.Parse(bytes.NewReader(d.body))
+		d.xmlDone = true
+	}
+	return d.xmlRoot, d.xmlErr
+}
+
+// eval returns the first value matched by a rule
+func (d *responseDoc) eval(ruleType model.RuleType, expr string) (string, bool) {
+	switch ruleType {
+	case model.XPathRule:
+		return d.xpath(expr)
+	case model.RegexRule:
+		return d.regex(expr)
+	case model.JSONPathRule:
+		return d.jsonPath(expr)
+	}
+	return "", false
+}
+
+// ValidateRule checks that a check or extraction expression compiles
+func ValidateRule(ruleType model.RuleType, expr string) error {
+	if expr == "" {
+		return errors.New("rule expression is empty")
+	}
+	switch ruleType {
+	case model.XPathRule:
+		return nil
+	case model.RegexRule:
+		_, err := compileRegex(expr)
+		return err
+	case model.JSONPathRule:
+		_, err := parseJSONPath(expr)
+		return err
+	}
+	return errors.New("unsupported rule type: " + string(ruleType))
+}
+
+func (d *responseDoc) xpath(expr string) (string, bool) {
+	root, err := d.xml()
+	if err != nil {
+		return "", false
+	}
+
+	compiled, err := xpath.CompileWithNS(expr, d.namespaces)
+	if err != nil {
+		return "", false
+	}
+
+	// Expressions like count(...) or boolean(...) evaluate to values
+	// rather than node sets. A false boolean is still a value, so a check
+	// can compare it with Equals.
+	switch res := compiled.Evaluate(xmlquery.CreateXPathNavigator(root)).(type) {
+	case *xpath.NodeIterator:
+		if !res.MoveNext() {
+			return "", false
+		}
+		return strings.TrimSpace(res.Current().Value()), true
+	case string:
+		return res, res != ""
+	case float64:
+		return strconv.FormatFloat(res, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(res), true
+	}
+	return "", false
+}
+
+// regexCache holds compiled expressions, since every VU evaluates the
+// same few on every iteration
+var regexCache sync.Map
+
+func compileRegex(expr string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, errors.New("invalid regex: " + err.Error())
+	}
+	regexCache.Store(expr, re)
+	return re, nil
+}
+
+func (d *responseDoc) regex(expr string) (string, bool) {
+	re, err := compileRegex(expr)
+	if err != nil {
+		return "", false
+	}
+	m := re.FindSubmatch(d.body)
+	if m == nil {
+		return "", false
+	}
+	if len(m) > 1 {
+		return string(m[1]), true
+	}
+	return string(m[0]), true
+}
+
+func (d *responseDoc) json() (interface{}, error) {
+	if !d.jsonDone {
+		dec := json.NewDecoder(bytes.NewReader(d.body))
+		dec.UseNumber()
+		d.jsonErr = dec.Decode(&d.jsonRoot)
+		d.jsonDone = true
+	}
+	return d.jsonRoot, d.jsonErr
+}
+
+// parseJSONPath splits a path such as $.data.items[0]['id'] into keys
+// and indexes. Wildcards, recursive descent and filters are not
+// supported.
+func parseJSONPath(expr string) ([]interface{}, error) {
+	rest := strings.TrimSpace(expr)
+	rest = strings.TrimPrefix(rest, "$")
+
+	var parts []interface{}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, errors.New("jsonpath recursive descent is not supported: " + expr)
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" || key == "*" {
+				return nil, errors.New("invalid jsonpath: " + expr)
+			}
+			parts = append(parts, key)
+			rest = rest[end:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New("invalid jsonpath: " + expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				parts = append(parts, inner[1:len(inner)-1])
+				continue
+			}
+			n, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, errors.New("unsupported jsonpath selector [" + inner + "]: " + expr)
+			}
+			parts = append(parts, n)
+		default:
+			if len(parts) > 0 {
+				return nil, errors.New("invalid jsonpath: " + expr)
+			}
+			// A bare first key, as in "data.id"
+			rest = "." + rest
+		}
+	}
+	return parts, nil
+}
+
+func (d *responseDoc) jsonPath(expr string) (string, bool) {
+	parts, err := parseJSONPath(expr)
+	if err != nil {
+		return "", false
+	}
+	val, err := d.json()
+	if err != nil {
+		return "", false
+	}
+
+	for _, part := range parts {
+		switch p := part.(type) {
+		case string:
+			obj, ok := val.(map[string]interface{})
+			if !ok {
+				return "", false
+			}
+			if val, ok = obj[p]; !ok {
+				return "", false
+			}
+		case int:
+			arr, ok := val.([]interface{})
+			if p < 0 {
+				p += len(arr)
+			}
+			if !ok || p < 0 || p >= len(arr) {
+				return "", false
+			}
+			val = arr[p]
+		}
+	}
+
+	switch v := val.(type) {
+	case nil:
+		return "", false
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	b, err := json.Marshal(val)
+	return string(b), err == nil
+}
+
+// checksPass reports whether every check holds
+func (d *responseDoc) checksPass(checks []model.Check) bool {
+	for _, check := range checks {
+		val, ok := d.eval(check.Type, check.Expr)
+		if !ok || (check.Equals != "" && val != check.Equals) {
+			return false
+		}
+	}
+	return true
+}
+
+// extract stores matched values in the VU's variables
+func (d *responseDoc) extract(rules []model.Extraction, vars map[string]string) {
+	for _, rule := range rules {
+		if val, ok := d.eval(rule.Type, rule.Expr); ok {
+			vars[rule.Var] = val
+		} else if rule.Default != "" {
+			vars[rule.Var] = rule.Default
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"testing"
+
+	"k6clone/internal/core/model"
+)
+
+const (
+	soapOK = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body><m:AddResponse xmlns:m="urn:calc"><m:Result>5</m:Result></m:AddResponse></soap:Body>
+</soap:Envelope>`
+
+	soapFault = `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body><soap:Fault><faultcode>soap:Client</faultcode><faultstring>bad input</faultstring></soap:Fault></soap:Body>
+</soap:Envelope>`
+)
+
+func TestXPathChecks(t *testing.T) {
+	namespaces := map[string]string{
+		"soap": "http://schemas.xmlsoap.org/soap/envelope/",
+		"m":    "urn:calc",
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		check model.Check
+		want  bool
+	}{
+		{"no fault", soapOK, model.Check{Expr: "boolean(//soap:Fault)", Equals: "false"}, true},
+		{"fault", soapFault, model.Check{Expr: "boolean(//soap:Fault)", Equals: "false"}, false},
+		{"fault expected", soapFault, model.Check{Expr: "boolean(//soap:Fault)", Equals: "true"}, true},
+		{"not a fault", soapOK, model.Check{Expr: "not(//soap:Fault)", Equals: "true"}, true},
+		{"false boolean without Equals", soapOK, model.Check{Expr: "boolean(//soap:Fault)"}, true},
+		{"node value", soapOK, model.Check{Expr: "//m:Result", Equals: "5"}, true},
+		{"missing node", soapFault, model.Check{Expr: "//m:Result"}, false},
+		{"count", soapFault, model.Check{Expr: "count(//soap:Fault)", Equals: "1"}, true},
+		{"empty string", soapOK, model.Check{Expr: "string(//faultstring)"}, false},
+		{"not XML", `{"ok":true}`, model.Check{Expr: "boolean(//soap:Fault)", Equals: "false"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check.Type = model.XPathRule
+			doc := newResponseDoc([]byte(tt.body), namespaces)
+			if got := doc.checksPass([]model.Check{tt.check}); got != tt.want {
+				val, ok := doc.eval(tt.check.Type, tt.check.Expr)
+				t.Errorf("checksPass = %v, want %v (value %q, matched %v)", got, tt.want, val, ok)
+			}
+		})
+	}
+}
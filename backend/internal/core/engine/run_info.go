@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"k6clone/internal/core/model"
+)
+
+// Load executors, named after the k6 executors that schedule VUs and
+// iterations the same way. Not to be confused with step executors.
+const (
+	ConstantVUs     = "constant-vus"
+	RampingVUs      = "ramping-vus"
+	PerVUIterations = "per-vu-iterations"
+	ReplayExecutor  = "replay"
+
+	// ScriptOptions means a k6 script's own options decide
+	ScriptOptions = "script-options"
+)
+
+// LoadExecutor names how a script is scheduled under a config
+func LoadExecutor(script *model.Script, config model.TestConfig) string {
+	switch {
+	case script.Source != "":
+		return ScriptOptions
+	case script.Replay != nil:
+		return ReplayExecutor
+	case script.Scenario != nil && script.Scenario.RampUpSeconds > 0:
+		return RampingVUs
+	case config.Duration <= 0:
+		return PerVUIterations
+	}
+	return ConstantVUs
+}
+
+var buildVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "native"
+	}
+
+	version := "native " + info.Main.Version
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision" && len(s.Value) >= 12:
+			version += " " + s.Value[:12]
+		case s.Key == "vcs.modified" && s.Value == "true":
+			version += "+dirty"
+		}
+	}
+	return version
+})
+
+// Version identifies the engine build: the module version and, when
+// built from a checkout, the commit
+func (e *LoadEngine) Version() string {
+	return buildVersion()
+}
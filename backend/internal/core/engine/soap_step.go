@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k6clone/internal/core/model"
+)
+
+const soapFaultXPath = `//*[local-name()='Envelope']/*[local-name()='Body']/*[local-name()='Fault']`
+
+func init() {
+	Register(model.SOAP, soapExecutor{})
+}
+
+type soapExecutor struct{}
+
+func (soapExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("step url is empty")
+	}
+	if step.SOAP == nil || strings.TrimSpace(step.SOAP.Envelope) == "" {
+		return errors.New("soap step requires an envelope")
+	}
+	if v := step.SOAP.Version; v != "" && v != "1.1" && v != "1.2" {
+		return errors.New("soap version must be 1.1 or 1.2")
+	}
+	return nil
+}
+
+// soapHeaders returns the headers the SOAP version expects
+func soapHeaders(soap *model.SOAPStep) map[string]string {
+	header := make(map[string]string, 2)
+	if soap.Version == "1.2" {
+		ct := "application/soap+xml; charset=utf-8"
+		if soap.Action != "" {
+			ct += "; action=" + strconv.Quote(soap.Action)
+		}
+		header["Content-Type"] = ct
+	} else {
+		header["Content-Type"] = "text/xml; charset=utf-8"
+		header["SOAPAction"] = strconv.Quote(soap.Action)
+	}
+	return header
+}
+
+// Run posts the envelope with the headers the SOAP version expects.
+// A Fault element in the response body, a failed check or an error
+// status all count as failures.
+func (soapExecutor) Run(v *VU, index int, step *model.Step) {
+	soap := step.SOAP
+	if soap == nil {
+		v.AddRequest(0, false)
+		return
+	}
+
+	action := soap.Action
+	if action == "" {
+		action = "unknown"
+	}
+	tag := func(name string) string {
+		return MetricName(name, "action", action)
+	}
+
+	header := soapHeaders(soap)
+	for k, val := range step.Header {
+		header[k] = v.Expand(val)
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	resp, latency, err := SendHTTP(v.Client, method, v.Expand(step.URL), header, v.Expand(soap.Envelope), true)
+	v.AddDuration(tag("soap_req_duration"), latency)
+	if err != nil {
+		v.AddRequest(latency, false)
+		return
+	}
+
+	doc := newResponseDoc(resp.Body, soap.Namespaces)
+	ok := resp.Status < 400
+
+	if _, fault := doc.xpath(soapFaultXPath); fault {
+		ok = false
+		v.AddCount(tag("soap_faults"), 1)
+	}
+
+	if !doc.checksPass(step.Checks) {
+		ok = false
+		v.AddCount(tag("soap_check_failures"), 1)
+	}
+
+	doc.extract(step.Extract, v.Vars)
+	v.AddRequest(latency, ok)
+}
+
+func (soapExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	header := soapHeaders(step.SOAP)
+	for k, val := range step.Header {
+		header[k] = val
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: SOAP %s\n", index+1, step.SOAP.Action)
+	fmt.Fprintf(&b, "  const res%d = http.post(%s, %s, {\n", index, jsValue(step.URL), jsValue(step.SOAP.Envelope))
+	fmt.Fprintf(&b, "    headers: %s,\n", jsValue(header))
+	b.WriteString("  });\n")
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is 2xx\": (r) => r.status >= 200 && r.status < 300,\n")
+	b.WriteString("    \"no soap fault\": (r) => !/<([\\w-]+:)?Fault[\\s>]/.test(r.body),\n")
+	b.WriteString("  });\n")
+	return K6Snippet{Code: b.String()}, nil
+}
@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+const defaultSocketTimeout = 5 * time.Second
+
+func init() {
+	Register(model.TCP, socketExecutor{})
+	Register(model.UDP, socketExecutor{})
+}
+
+// socketExecutor runs TCP and UDP steps. Raw sockets need a k6
+// extension, so there is no k6 equivalent.
+type socketExecutor struct{}
+
+func (socketExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("socket step address is empty")
+	}
+	if _, _, err := net.SplitHostPort(step.URL); err != nil {
+		return errors.New("socket step address must be host:port")
+	}
+	if step.Socket == nil {
+		return errors.New("socket step requires a payload")
+	}
+	if step.Socket.ReadBytes < 0 || step.Socket.TimeoutMs < 0 {
+		return errors.New("socket readBytes and timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// Run connects (TCP) or binds (UDP), sends the payload and reads the
+// reply if the step asks for one. Metrics are prefixed with the
+// protocol, e.g. tcp_connecting and udp_rtt.
+func (socketExecutor) Run(v *VU, index int, step *model.Step) {
+	stepTag := strconv.Itoa(index + 1)
+	network := strings.ToLower(string(step.Type))
+	tag := func(name string) string {
+		return MetricName(network+"_"+name, "step", stepTag)
+	}
+
+	var sock model.SocketStep
+	if step.Socket != nil {
+		sock = *step.Socket
+	}
+
+	timeout := defaultSocketTimeout
+	if sock.TimeoutMs > 0 {
+		timeout = time.Duration(sock.TimeoutMs) * time.Millisecond
+	}
+
+	fail := func(latency time.Duration) {
+		v.AddCount(tag("errors"), 1)
+		v.AddRequest(latency, false)
+	}
+
+	start := time.Now()
+	conn, err := net.DialTimeout(network, v.Expand(step.URL), timeout)
+	connecting := time.Since(start)
+	if err != nil {
+		fail(connecting)
+		return
+	}
+	defer conn.Close()
+
+	// UDP has no handshake, so only TCP reports a connect time
+	if step.Type == model.TCP {
+		v.AddDuration(tag("connecting"), connecting)
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	sentAt := time.Now()
+	n, err := conn.Write([]byte(v.Expand(sock.Payload)))
+	v.AddCount(tag("bytes_sent"), int64(n))
+	if err != nil {
+		fail(time.Since(start))
+		return
+	}
+
+	if sock.ReadUntil == "" && sock.ReadBytes <= 0 {
+		v.AddRequest(time.Since(start), true)
+		return
+	}
+
+	received, err := readReply(conn, sock)
+	rtt := time.Since(sentAt)
+	v.AddCount(tag("bytes_received"), int64(len(received)))
+	if err != nil {
+		fail(time.Since(start))
+		return
+	}
+
+	v.AddDuration(tag("rtt"), rtt)
+	v.AddRequest(time.Since(start), true)
+}
+
+// readReply reads until the delimiter shows up or ReadBytes bytes have
+// arrived. For UDP every read returns one datagram.
+func readReply(conn net.Conn, sock model.SocketStep) ([]byte, error) {
+	delim := []byte(sock.ReadUntil)
+	buf := make([]byte, 64*1024)
+	var received []byte
+
+	for {
+		n, err := conn.Read(buf)
+		received = append(received, buf[:n]...)
+
+		if len(delim) > 0 {
+			if i := bytes.Index(received, delim); i >= 0 {
+				return received[:i+len(delim)], nil
+			}
+		} else if len(received) >= sock.ReadBytes {
+			return received[:sock.ReadBytes], nil
+		}
+
+		if err != nil {
+			return received, err
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+const defaultSSETimeout = 30 * time.Second
+
+func init() {
+	Register(model.SSE, sseExecutor{})
+}
+
+type sseExecutor struct{}
+
+func (sseExecutor) Validate(step *model.Step) error {
+	if step.URL == "" {
+		return errors.New("step url is empty")
+	}
+	if step.SSE != nil && (step.SSE.MaxEvents < 0 || step.SSE.TimeoutMs < 0) {
+		return errors.New("sse maxEvents and timeoutMs must not be negative")
+	}
+	return nil
+}
+
+// newSSEClient returns a client for event streams. Streams hold their
+// connection for a long time, so they get their own HTTP/1.1 transport
+// instead of starving the pool (or HTTP/2 stream limit) used by regular
+// requests. There is no client timeout; each stream is bounded by its
+// own context.
+func newSSEClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: 30 * time.Second,
+			ForceAttemptHTTP2:     false,
+			DisableKeepAlives:     true,
+		},
+	}
+}
+
+type sseEvent struct {
+	event string
+	data  string
+}
+
+// Run opens one stream and records time-to-first-event, gaps between
+// events and the event rate for the session
+func (sseExecutor) Run(v *VU, index int, step *model.Step) {
+	client := v.Shared("sse.client", func() interface{} {
+		return newSSEClient()
+	}).(*http.Client)
+
+	stepTag := strconv.Itoa(index + 1)
+	tag := func(name string) string {
+		return MetricName(name, "step", stepTag)
+	}
+
+	var sse model.SSEStep
+	if step.SSE != nil {
+		sse = *step.SSE
+	}
+
+	timeout := defaultSSETimeout
+	if sse.TimeoutMs > 0 {
+		timeout = time.Duration(sse.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	start := time.Now()
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(v.Expand(step.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.Expand(step.URL), body)
+	if err != nil {
+		v.AddRequest(time.Since(start), false)
+		return
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	for k, val := range step.Header {
+		req.Header.Set(k, v.Expand(val))
+	}
+
+	resp, err := client.Do(req)
+	connected := time.Since(start)
+	if err != nil {
+		v.AddRequest(connected, false)
+		v.AddCount(tag("sse_errors"), 1)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		v.AddRequest(connected, false)
+		v.AddCount(tag("sse_errors"), 1)
+		return
+	}
+
+	matchConfigured := sse.UntilEvent != "" || sse.UntilData != ""
+	matched := false
+	events := 0
+	var last time.Time
+
+	readEvents(resp, func(ev sseEvent) bool {
+		now := time.Now()
+		if events == 0 {
+			v.AddDuration(tag("sse_time_to_first_event"), now.Sub(start))
+		} else {
+			v.AddDuration(tag("sse_event_gap"), now.Sub(last))
+		}
+		last = now
+		events++
+
+		if matchConfigured &&
+			(sse.UntilEvent == "" || ev.event == sse.UntilEvent) &&
+			strings.Contains(ev.data, sse.UntilData) {
+			matched = true
+			return false
+		}
+		return sse.MaxEvents == 0 || events < sse.MaxEvents
+	})
+
+	session := time.Since(start)
+	v.AddCount(tag("sse_events"), int64(events))
+	v.AddDuration(tag("sse_session_duration"), session)
+	if session > 0 {
+		v.AddTrend(tag("sse_events_per_second"), float64(events)/session.Seconds())
+	}
+
+	ok := true
+	switch {
+	case matchConfigured:
+		ok = matched
+	case sse.MaxEvents > 0:
+		ok = events >= sse.MaxEvents
+	}
+	if !ok {
+		v.AddCount(tag("sse_errors"), 1)
+	}
+	v.AddRequest(connected, ok)
+}
+
+// K6JS emits a plain request; k6 has no streaming reader, so the
+// generated script reads the stream until the timeout
+func (sseExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	body := "null"
+	if step.Body != "" {
+		body = jsValue(step.Body)
+	}
+	timeout := int(defaultSSETimeout / time.Millisecond)
+	if step.SSE != nil && step.SSE.TimeoutMs > 0 {
+		timeout = step.SSE.TimeoutMs
+	}
+
+	header := map[string]string{"Accept": "text/event-stream"}
+	for k, val := range step.Header {
+		header[k] = val
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: SSE %s\n", index+1, step.URL)
+	b.WriteString("  // k6 has no streaming reader, so this reads the stream until the timeout\n")
+	fmt.Fprintf(&b, "  const res%d = http.request(%s, %s, %s, {\n", index, jsValue(method), jsValue(step.URL), body)
+	fmt.Fprintf(&b, "    headers: %s,\n", jsValue(header))
+	fmt.Fprintf(&b, "    timeout: \"%dms\",\n", timeout)
+	b.WriteString("  });\n")
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is 200\": (r) => r.status === 200,\n")
+	b.WriteString("  });\n")
+	return K6Snippet{Code: b.String()}, nil
+}
+
+// readEvents parses the stream and calls fn for every dispatched event
+// until fn returns false or the stream ends
+func readEvents(resp *http.Response, fn func(sseEvent) bool) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var ev sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			if len(data) == 0 {
+				ev = sseEvent{}
+				continue
+			}
+			ev.data = strings.Join(data, "\n")
+			if ev.event == "" {
+				ev.event = "message"
+			}
+			if !fn(ev) {
+				return
+			}
+			ev, data = sseEvent{}, nil
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keep-alive
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			ev.event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+}
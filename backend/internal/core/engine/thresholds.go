@@ -0,0 +1,214 @@
+package engine
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+var thresholdExpr = regexp.MustCompile(`^\s*(avg|min|max|med|count|rate|value|p\(\s*([\d.]+)\s*\))\s*(<=|>=|===|==|!=|<|>)\s*(-?[\d.]+)\s*$`)
+
+// thresholds evaluates k6 threshold expressions against the collected
+// samples. Expressions that cannot be parsed fail.
+func (c *collector) thresholds(th map[string][]string, elapsed time.Duration) []model.ThresholdResult {
+	if len(th) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metrics := make([]string, 0, len(th))
+	for metric := range th {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	var results []model.ThresholdResult
+	for _, metric := range metrics {
+		name := normalizeMetric(metric)
+		for _, expr := range th[metric] {
+			res := model.ThresholdResult{Metric: metric, Threshold: expr}
+
+			m := thresholdExpr.FindStringSubmatch(expr)
+			if m != nil {
+				limit, _ := strconv.ParseFloat(m[4], 64)
+				res.Value = c.aggregate(name, m[1], m[2], elapsed)
+				res.Passed = compare(res.Value, m[3], limit)
+			}
+			results = append(results, res)
+		}
+	}
+	return results
+}
+
+// normalizeMetric drops the spaces k6 allows in tag selectors, so
+// "http_req_duration{ name: login }" matches MetricName's output
+func normalizeMetric(metric string) string {
+	return strings.Join(strings.Fields(metric), "")
+}
+
+// aggregate computes one k6 aggregation for a metric. The built-in
+// request metrics map onto the collector's totals; everything else is
+// looked up by name among the trends and counters.
+func (c *collector) aggregate(name, agg, pct string, elapsed time.Duration) float64 {
+	switch name {
+	case "http_req_duration":
+		values := make([]float64, len(c.latencies))
+		for i, l := range c.latencies {
+			values[i] = float64(l)
+		}
+		return trendAggregate(values, agg, pct)
+	case "http_req_failed":
+		return ratio(c.failure, c.total)
+	case "http_reqs":
+		return counterAggregate(float64(c.total), agg, elapsed)
+	case "iterations":
+		return counterAggregate(float64(c.iterations), agg, elapsed)
+	case "checks":
+		pass, total := 0, 0
+		for counter, n := range c.counters {
+			if !strings.HasPrefix(counter, "checks{") {
+				continue
+			}
+			total += int(n)
+			if strings.HasSuffix(counter, "result:pass}") {
+				pass += int(n)
+			}
+		}
+		return ratio(pass, total)
+	}
+
+	if values, ok := c.trends[name]; ok {
+		return trendAggregate(values, agg, pct)
+	}
+	if n, ok := c.counters[name]; ok {
+		return counterAggregate(float64(n), agg, elapsed)
+	}
+
+	// Like k6, a tag selector matches every series carrying at least
+	// those tags, e.g. {group:::api} matches {group:::api,name:login}
+	base, tags := splitMetricName(name)
+	if len(tags) == 0 {
+		return 0
+	}
+	var values []float64
+	for series, samples := range c.trends {
+		if matchesTags(series, base, tags) {
+			values = append(values, samples...)
+		}
+	}
+	if len(values) > 0 {
+		return trendAggregate(values, agg, pct)
+	}
+	count := int64(0)
+	for series, n := range c.counters {
+		if matchesTags(series, base, tags) {
+			count += n
+		}
+	}
+	return counterAggregate(float64(count), agg, elapsed)
+}
+
+// splitMetricName is the inverse of MetricName
+func splitMetricName(name string) (string, map[string]string) {
+	open := strings.IndexByte(name, '{')
+	if open < 0 || !strings.HasSuffix(name, "}") {
+		return name, nil
+	}
+
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(name[open+1:len(name)-1], ",") {
+		k, v, _ := strings.Cut(pair, ":")
+		tags[k] = v
+	}
+	return name[:open], tags
+}
+
+func matchesTags(series, base string, want map[string]string) bool {
+	name, tags := splitMetricName(series)
+	if name != base {
+		return false
+	}
+	for k, v := range want {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func trendAggregate(values []float64, agg, pct string) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	at := func(p float64) float64 {
+		index := int(p * float64(len(sorted)) / 100)
+		if index >= len(sorted) {
+			index = len(sorted) - 1
+		}
+		return sorted[index]
+	}
+
+	switch agg {
+	case "min":
+		return sorted[0]
+	case "max":
+		return sorted[len(sorted)-1]
+	case "med":
+		return at(50)
+	case "count":
+		return float64(len(sorted))
+	}
+	if pct != "" {
+		p, _ := strconv.ParseFloat(pct, 64)
+		return at(p)
+	}
+
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	return sum / float64(len(sorted))
+}
+
+func counterAggregate(count float64, agg string, elapsed time.Duration) float64 {
+	if agg == "rate" && elapsed > 0 {
+		return count / elapsed.Seconds()
+	}
+	return count
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func compare(value float64, op string, limit float64) bool {
+	const epsilon = 1e-9
+	switch op {
+	case "<":
+		return value < limit
+	case "<=":
+		return value <= limit
+	case ">":
+		return value > limit
+	case ">=":
+		return value >= limit
+	case "==", "===":
+		return math.Abs(value-limit) < epsilon
+	case "!=":
+		return math.Abs(value-limit) >= epsilon
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package engine
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+// VU is the state of one virtual user for the length of a run. It is
+// handed to step executors and is only used from the VU's goroutine.
+type VU struct {
+	ID int
+
+	// Client is the HTTP client shared by all VUs of the run
+	Client *http.Client
+
+	// Vars holds script variables and extracted values
+	Vars map[string]string
+
+	run       *run
+	resources map[string]io.Closer
+}
+
+// run is the state shared by all VUs of one run
+type run struct {
+	engine *LoadEngine
+	c      *collector
+	client *http.Client
+
+	mu     sync.Mutex
+	shared map[string]interface{}
+}
+
+// AddRequest records one request towards the overall totals
+func (v *VU) AddRequest(latency time.Duration, ok bool) {
+	v.run.c.addRequest(latency, ok)
+}
+
+// AddDuration records a duration sample for a named metric
+func (v *VU) AddDuration(name string, d time.Duration) {
+	v.run.c.addDuration(name, d)
+}
+
+// AddTrend records a sample for a named metric
+func (v *VU) AddTrend(name string, value float64) {
+	v.run.c.addTrend(name, value)
+}
+
+// AddCount increments a named counter
+func (v *VU) AddCount(name string, n int64) {
+	v.run.c.addCount(name, n)
+}
+
+// Resource returns a per-VU resource such as a connection, opening it
+// on first use. Resources are closed when the VU finishes.
+func (v *VU) Resource(key string, open func() (io.Closer, error)) (io.Closer, error) {
+	if r, ok := v.resources[key]; ok {
+		return r, nil
+	}
+
+	r, err := open()
+	if err != nil {
+		return nil, err
+	}
+	v.resources[key] = r
+	return r, nil
+}
+
+// Shared returns a value shared by all VUs of the run, such as a cache,
+// creating it on first use
+func (v *VU) Shared(key string, create func() interface{}) interface{} {
+	v.run.mu.Lock()
+	defer v.run.mu.Unlock()
+
+	if val, ok := v.run.shared[key]; ok {
+		return val
+	}
+	val := create()
+	v.run.shared[key] = val
+	return val
+}
+
+func (v *VU) close() {
+	for _, r := range v.resources {
+		r.Close()
+	}
+}
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*\}\}`)
+
+// Expand replaces {{name}} placeholders with VU variables. Unknown
+// names are left untouched so mistakes stay visible in the request.
+func (v *VU) Expand(s string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholder.FindStringSubmatch(m)[1]
+		if val, ok := v.Vars[name]; ok {
+			return val
+		}
+		return m
+	})
+}
+
+// ExpandStep returns a copy of an HTTP-style step with placeholders in
+// the URL, headers and body resolved
+func (v *VU) ExpandStep(step *model.Step) *model.Step {
+	out := *step
+	out.URL = v.Expand(step.URL)
+	out.Body = v.Expand(step.Body)
+
+	if len(step.Header) > 0 {
+		out.Header = make(map[string]string, len(step.Header))
+		for k, val := range step.Header {
+			out.Header[k] = v.Expand(val)
+		}
+	}
+	return &out
+}
@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"k6clone/internal/core/model"
+)
+
+const defaultWSTimeout = 5 * time.Second
+
+var wsDialer = &websocket.Dialer{
+	HandshakeTimeout: 30 * time.Second,
+}
+
+func init() {
+	Register(model.WS, wsExecutor{})
+}
+
+type wsExecutor struct{}
+
+func (wsExecutor) Validate(step *model.Step) error {
+	if !strings.HasPrefix(step.URL, "ws://") && !strings.HasPrefix(step.URL, "wss://") {
+		return errors.New("ws step url must start with ws:// or wss://")
+	}
+	if step.WS == nil {
+		return nil
+	}
+	if step.WS.HoldMs < 0 {
+		return errors.New("ws holdMs must not be negative")
+	}
+	for _, msg := range step.WS.Messages {
+		if msg.DelayMs < 0 || msg.TimeoutMs < 0 {
+			return errors.New("ws message delays and timeouts must not be negative")
+		}
+	}
+	return nil
+}
+
+// Run runs one WebSocket session. The session counts as a single
+// request that succeeds if the connection opens and every expected
+// reply arrives in time.
+func (wsExecutor) Run(v *VU, index int, step *model.Step) {
+	stepTag := strconv.Itoa(index + 1)
+	tag := func(name string) string {
+		return MetricName(name, "step", stepTag)
+	}
+
+	header := http.Header{}
+	for k, val := range step.Header {
+		header.Set(k, v.Expand(val))
+	}
+
+	start := time.Now()
+	conn, resp, err := wsDialer.Dial(v.Expand(step.URL), header)
+	connecting := time.Since(start)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		v.AddRequest(connecting, false)
+		v.AddCount(tag("ws_errors"), 1)
+		return
+	}
+	defer conn.Close()
+
+	v.AddDuration(tag("ws_connecting"), connecting)
+
+	// Read in the background so replies are counted even when no
+	// message is waiting for them
+	received := make(chan string, 64)
+	go func() {
+		defer close(received)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			v.AddCount(tag("ws_msgs_received"), 1)
+			select {
+			case received <- string(data):
+			default:
+				// Nobody is waiting; drop it
+			}
+		}
+	}()
+
+	ok := true
+	var ws model.WSStep
+	if step.WS != nil {
+		ws = *step.WS
+	}
+
+	for _, msg := range ws.Messages {
+		if msg.DelayMs > 0 {
+			time.Sleep(time.Duration(msg.DelayMs) * time.Millisecond)
+		}
+
+		sentAt := time.Now()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(v.Expand(msg.Data))); err != nil {
+			ok = false
+			v.AddCount(tag("ws_errors"), 1)
+			break
+		}
+		v.AddCount(tag("ws_msgs_sent"), 1)
+
+		if msg.Expect == "" {
+			continue
+		}
+
+		if !awaitWS(received, msg) {
+			ok = false
+			v.AddCount(tag("ws_errors"), 1)
+			continue
+		}
+		v.AddDuration(tag("ws_msg_rtt"), time.Since(sentAt))
+	}
+
+	if ws.HoldMs > 0 {
+		time.Sleep(time.Duration(ws.HoldMs) * time.Millisecond)
+	}
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+
+	v.AddDuration(tag("ws_session_duration"), time.Since(start))
+	v.AddRequest(connecting, ok)
+}
+
+// awaitWS waits for a received message containing msg.Expect
+func awaitWS(received <-chan string, msg model.WSMessage) bool {
+	timeout := defaultWSTimeout
+	if msg.TimeoutMs > 0 {
+		timeout = time.Duration(msg.TimeoutMs) * time.Millisecond
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case data, open := <-received:
+			if !open {
+				return false
+			}
+			if strings.Contains(data, msg.Expect) {
+				return true
+			}
+		case <-timer.C:
+			return false
+		}
+	}
+}
+
+func (wsExecutor) K6JS(index int, step *model.Step) (K6Snippet, error) {
+	params := "null"
+	if len(step.Header) > 0 {
+		params = "{ headers: " + jsValue(step.Header) + " }"
+	}
+
+	var ws model.WSStep
+	if step.WS != nil {
+		ws = *step.WS
+	}
+
+	// k6 schedules sends with socket.setTimeout, relative to open
+	var b strings.Builder
+	fmt.Fprintf(&b, "  // Step %d: WebSocket %s\n", index+1, step.URL)
+	fmt.Fprintf(&b, "  const res%d = ws.connect(%s, %s, function (socket) {\n", index, jsValue(step.URL), params)
+	b.WriteString("    socket.on(\"open\", () => {\n")
+	at, wait := 0, 0
+	for _, msg := range ws.Messages {
+		at += msg.DelayMs
+		fmt.Fprintf(&b, "      socket.setTimeout(() => socket.send(%s), %d);\n", jsValue(msg.Data), at)
+
+		wait = 0
+		if msg.Expect != "" {
+			wait = msg.TimeoutMs
+			if wait == 0 {
+				wait = int(defaultWSTimeout / time.Millisecond)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "      socket.setTimeout(() => socket.close(), %d);\n", at+wait+ws.HoldMs)
+	b.WriteString("    });\n")
+	b.WriteString("  });\n")
+	fmt.Fprintf(&b, "  check(res%d, {\n", index)
+	b.WriteString("    \"status is 101\": (r) => r && r.status === 101,\n")
+	b.WriteString("  });\n")
+
+	return K6Snippet{
+		Imports: []string{`import ws from "k6/ws";`},
+		Code:    b.String(),
+	}, nil
+}
@@ -0,0 +1,118 @@
+package engine
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"k6clone/internal/core/model"
+)
+
+// newWSEchoServer echoes every text message back prefixed with "echo:"
+func newWSEchoServer(t *testing.T) string {
+	t.Helper()
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(kind, append([]byte("echo:"), data...)); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func runOnce(t *testing.T, script *model.Script) model.TestResult {
+	t.Helper()
+
+	script.Scenario = &model.Scenario{VUs: 1, Iterations: 1}
+	result, err := NewLoadEngine(t.TempDir(), t.TempDir()).Run(script, model.TestConfig{VUs: 1})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return result
+}
+
+func TestWSStepEcho(t *testing.T) {
+	url := newWSEchoServer(t)
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type: model.WS,
+		URL:  url,
+		WS: &model.WSStep{Messages: []model.WSMessage{
+			{Data: "hello", Expect: "echo:hello"},
+			{Data: "world", Expect: "echo:world"},
+			{Data: "no reply needed"},
+		}},
+	}}})
+
+	if result.TotalRequests != 1 || result.Success != 1 || result.Failure != 0 {
+		t.Fatalf("requests = %d, success = %d, failure = %d; want 1, 1, 0",
+			result.TotalRequests, result.Success, result.Failure)
+	}
+	if got := result.Counters["ws_msgs_sent{step:1}"]; got != 3 {
+		t.Errorf("ws_msgs_sent = %d, want 3", got)
+	}
+	if got := result.Counters["ws_msgs_received{step:1}"]; got < 2 {
+		t.Errorf("ws_msgs_received = %d, want at least 2", got)
+	}
+	if got := result.Counters["ws_errors{step:1}"]; got != 0 {
+		t.Errorf("ws_errors = %d, want 0", got)
+	}
+	for _, name := range []string{"ws_connecting{step:1}", "ws_msg_rtt{step:1}", "ws_session_duration{step:1}"} {
+		if m, ok := result.Metrics[name]; !ok || m.Count == 0 {
+			t.Errorf("metric %s missing: %+v", name, result.Metrics)
+		}
+	}
+	if got := result.Metrics["ws_msg_rtt{step:1}"].Count; got != 2 {
+		t.Errorf("ws_msg_rtt count = %d, want 2", got)
+	}
+}
+
+func TestWSStepMissingReply(t *testing.T) {
+	url := newWSEchoServer(t)
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{
+		Type: model.WS,
+		URL:  url,
+		WS: &model.WSStep{Messages: []model.WSMessage{
+			{Data: "ping", Expect: "pong", TimeoutMs: 200},
+		}},
+	}}})
+
+	if result.Success != 0 || result.Failure != 1 {
+		t.Errorf("success = %d, failure = %d; want 0, 1", result.Success, result.Failure)
+	}
+	if got := result.Counters["ws_errors{step:1}"]; got != 1 {
+		t.Errorf("ws_errors = %d, want 1", got)
+	}
+}
+
+func TestWSStepConnectError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	srv.Close()
+
+	result := runOnce(t, &model.Script{Steps: []model.Step{{Type: model.WS, URL: url}}})
+
+	if result.Failure != 1 {
+		t.Errorf("failure = %d, want 1", result.Failure)
+	}
+	if got := result.Counters["ws_errors{step:1}"]; got != 1 {
+		t.Errorf("ws_errors = %d, want 1", got)
+	}
+}
@@ -0,0 +1,329 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+const (
+	defaultTopEndpoints = 20
+	defaultMaxReplay    = 10000
+
+	accessLogTime = "02/Jan/2006:15:04:05 -0700"
+)
+
+// accessLogLine matches the common and combined log formats used by
+// nginx and Apache:
+// host ident user [time] "request" status bytes "referer" "user-agent"
+var accessLogLine = regexp.MustCompile(`^(\S+) \S+ \S+ \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\S+)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+// idSegment matches path segments that identify a resource rather than
+// an endpoint: numbers, UUIDs and long hex strings
+var idSegment = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// replayableMethods can be sent again from a log line alone; other
+// requests need a body the log does not have
+var replayableMethods = map[string]bool{"GET": true, "HEAD": true, "OPTIONS": true}
+
+// AccessLogGenerator builds scripts from web server access logs
+type AccessLogGenerator struct{}
+
+func NewAccessLogGenerator() *AccessLogGenerator {
+	return &AccessLogGenerator{}
+}
+
+// AccessLogOptions control how a log becomes a script
+type AccessLogOptions struct {
+	// BaseURL is the target the logged paths are sent to; without it
+	// the script uses a {{baseUrl}} variable
+	BaseURL string `json:"baseUrl"`
+
+	// Top is how many endpoints the weighted script keeps; defaults
+	// to 20
+	Top int `json:"top"`
+
+	// Replay builds a script that sends every request at its logged
+	// time instead of a weighted mix of endpoints
+	Replay bool `json:"replay"`
+
+	// Speed scales replay timing: 2 replays twice as fast
+	Speed float64 `json:"speed"`
+
+	// MaxReplay caps the requests in a replay script; defaults to 10000
+	MaxReplay int `json:"maxReplay"`
+
+	// IncludeStatic keeps requests for images, scripts, stylesheets
+	// and fonts
+	IncludeStatic bool `json:"includeStatic"`
+}
+
+// AccessLogReport describes how much of a log the script reproduces
+type AccessLogReport struct {
+	Lines     int `json:"lines"`
+	Parsed    int `json:"parsed"`
+	Malformed int `json:"malformed"`
+
+	// Replayable requests could be sent again; Skipped counts the
+	// others by reason
+	Replayable int            `json:"replayable"`
+	Skipped    map[string]int `json:"skipped"`
+
+	// Endpoints is the number of distinct endpoints, with IDs in paths
+	// folded together; Included is how many made it into the script
+	Endpoints int `json:"endpoints"`
+	Included  int `json:"included"`
+
+	// Coverage is the share of parsed requests the script reproduces,
+	// in percent
+	Coverage float64 `json:"coverage"`
+
+	// The load the log shows, for sizing the test
+	DurationSeconds int     `json:"durationSeconds"`
+	AvgRPS          float64 `json:"avgRps"`
+	PeakRPS         int     `json:"peakRps"`
+}
+
+type logRequest struct {
+	time   time.Time
+	method string
+	target string // path and query
+}
+
+type logEndpoint struct {
+	method  string
+	pattern string
+	count   int
+	targets map[string]int
+}
+
+// FromAccessLog converts an access log. By default the result is a
+// traffic mix: one step per top endpoint, weighted by how often it was
+// requested. With Replay the steps follow the log line by line at their
+// original relative times.
+func (g *AccessLogGenerator) FromAccessLog(data []byte, opts AccessLogOptions) (*model.Script, *AccessLogReport, error) {
+	report := &AccessLogReport{Skipped: make(map[string]int)}
+
+	var requests []logRequest
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		report.Lines++
+
+		req, ok := parseLogLine(line)
+		if !ok {
+			report.Malformed++
+			continue
+		}
+		report.Parsed++
+
+		if !replayableMethods[req.method] {
+			report.Skipped[req.method+" without a body"]++
+			continue
+		}
+		if u, err := url.Parse(req.target); err != nil || !strings.HasPrefix(u.Path, "/") {
+			report.Skipped["invalid request target"]++
+			continue
+		} else if !opts.IncludeStatic && isStatic(u, "") {
+			report.Skipped["static asset"]++
+			continue
+		}
+		report.Replayable++
+		requests = append(requests, req)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, errors.New("invalid access log: " + err.Error())
+	}
+	if report.Parsed == 0 {
+		return nil, nil, errors.New("no lines in common or combined log format")
+	}
+
+	loadShape(requests, report)
+
+	script := &model.Script{ID: uuid.NewString()}
+	base := strings.TrimSuffix(opts.BaseURL, "/")
+	if base == "" {
+		script.Variables = map[string]string{"baseUrl": defaultBaseURL}
+		base = "{{baseUrl}}"
+	}
+
+	if opts.Replay {
+		replayScript(script, base, requests, opts, report)
+	} else {
+		mixScript(script, base, requests, opts, report)
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, report, errors.New("no replayable requests in the log")
+	}
+	report.Coverage = float64(int(float64(report.Included)/float64(report.Parsed)*1000+0.5)) / 10
+	return script, report, nil
+}
+
+func parseLogLine(line string) (logRequest, bool) {
+	m := accessLogLine.FindStringSubmatch(line)
+	if m == nil {
+		return logRequest{}, false
+	}
+	t, err := time.Parse(accessLogTime, m[2])
+	if err != nil {
+		return logRequest{}, false
+	}
+
+	parts := strings.Fields(strings.ReplaceAll(m[3], `\"`, `"`))
+	if len(parts) < 2 {
+		return logRequest{}, false // e.g. "-" for a dropped connection
+	}
+	return logRequest{time: t, method: strings.ToUpper(parts[0]), target: parts[1]}, true
+}
+
+// loadShape fills in the log's duration and request rates
+func loadShape(requests []logRequest, report *AccessLogReport) {
+	if len(requests) == 0 {
+		return
+	}
+	first, last := requests[0].time, requests[0].time
+	perSecond := make(map[int64]int)
+	for _, r := range requests {
+		if r.time.Before(first) {
+			first = r.time
+		}
+		if r.time.After(last) {
+			last = r.time
+		}
+		perSecond[r.time.Unix()]++
+	}
+	for _, n := range perSecond {
+		report.PeakRPS = max(report.PeakRPS, n)
+	}
+
+	report.DurationSeconds = int(last.Sub(first).Seconds()) + 1
+	report.AvgRPS = float64(int(float64(len(requests))/float64(report.DurationSeconds)*100+0.5)) / 100
+}
+
+// mixScript keeps the most requested endpoints as weighted steps. Each
+// step requests the endpoint's most common concrete URL.
+func mixScript(script *model.Script, base string, requests []logRequest, opts AccessLogOptions, report *AccessLogReport) {
+	top := opts.Top
+	if top <= 0 {
+		top = defaultTopEndpoints
+	}
+
+	byKey := make(map[string]*logEndpoint)
+	for _, r := range requests {
+		pattern := endpointPattern(r.target)
+		key := r.method + " " + pattern
+		ep := byKey[key]
+		if ep == nil {
+			ep = &logEndpoint{method: r.method, pattern: pattern, targets: make(map[string]int)}
+			byKey[key] = ep
+		}
+		ep.count++
+		ep.targets[r.target]++
+	}
+	report.Endpoints = len(byKey)
+
+	endpoints := make([]*logEndpoint, 0, len(byKey))
+	for _, ep := range byKey {
+		endpoints = append(endpoints, ep)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].count != endpoints[j].count {
+			return endpoints[i].count > endpoints[j].count
+		}
+		return endpoints[i].method+endpoints[i].pattern < endpoints[j].method+endpoints[j].pattern
+	})
+	if len(endpoints) > top {
+		for _, ep := range endpoints[top:] {
+			report.Skipped["outside the top endpoints"] += ep.count
+		}
+		endpoints = endpoints[:top]
+	}
+
+	for _, ep := range endpoints {
+		script.Steps = append(script.Steps, model.Step{
+			Type:   model.HTTP,
+			Method: ep.method,
+			URL:    base + mostCommon(ep.targets),
+			Weight: ep.count,
+		})
+		report.Included += ep.count
+	}
+}
+
+// replayScript sends the requests in log order. Logs only have second
+// precision, so requests within a second are spread evenly across it.
+func replayScript(script *model.Script, base string, requests []logRequest, opts AccessLogOptions, report *AccessLogReport) {
+	limit := opts.MaxReplay
+	if limit <= 0 {
+		limit = defaultMaxReplay
+	}
+
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].time.Before(requests[j].time)
+	})
+	if len(requests) > limit {
+		report.Skipped["over the replay limit"] += len(requests) - limit
+		requests = requests[:limit]
+	}
+
+	distinct := make(map[string]bool)
+	for i := 0; i < len(requests); {
+		second := requests[i].time
+		j := i
+		for j < len(requests) && requests[j].time.Equal(second) {
+			j++
+		}
+		n := j - i
+		for k, r := range requests[i:j] {
+			offset := second.Sub(requests[0].time).Milliseconds() + int64(k*1000/n)
+			script.Steps = append(script.Steps, model.Step{
+				Type:     model.HTTP,
+				Method:   r.method,
+				URL:      base + r.target,
+				OffsetMs: offset,
+			})
+			distinct[r.method+" "+endpointPattern(r.target)] = true
+		}
+		i = j
+	}
+
+	report.Endpoints = len(distinct)
+	report.Included = len(script.Steps)
+	script.Replay = &model.Replay{Speed: opts.Speed}
+}
+
+// endpointPattern folds IDs in a path so /users/42 and /users/7 count
+// as one endpoint; the query is left out
+func endpointPattern(target string) string {
+	path, _, _ := strings.Cut(target, "?")
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if idSegment.MatchString(s) {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestN := "", 0
+	for s, n := range counts {
+		if n > bestN || n == bestN && s < best {
+			best, bestN = s, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,485 @@
+package generator
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/html"
+	"k6clone/internal/core/model"
+)
+
+const (
+	defaultCrawlDepth = 2
+	defaultCrawlPages = 20
+	maxCrawlPages     = 500
+
+	// crawlUserAgent is the name robots.txt rules are matched against
+	crawlUserAgent = "k6clone"
+
+	maxCrawlBody = 5 << 20
+
+	// Reading time is estimated from the words on a page at a typical
+	// reading speed, within sensible bounds
+	wordsPerMinute = 230
+	minThinkTime   = time.Second
+)
+
+// Crawler is implemented by generators that can build a script by
+// browsing a site instead of requesting a single URL
+type Crawler interface {
+	Crawl(rawURL string, opts CrawlOptions) (*model.Script, error)
+}
+
+// CrawlOptions control how far a crawl goes and what it records
+type CrawlOptions struct {
+	// MaxDepth is how many links away from the start page to follow;
+	// defaults to 2
+	MaxDepth int `json:"maxDepth"`
+
+	// MaxPages caps the pages visited; defaults to 20, at most 500
+	MaxPages int `json:"maxPages"`
+
+	// SkipSitemap ignores sitemap.xml and the sitemaps robots.txt lists
+	SkipSitemap bool `json:"skipSitemap"`
+
+	// IncludeAssets adds a batch step after each page that loads its
+	// stylesheets, scripts and images, like a browser with a cold
+	// cache. Assets already loaded by an earlier page are left out.
+	IncludeAssets bool `json:"includeAssets"`
+
+	// MaxThinkTimeMs caps the reading time before each page; 0 means
+	// the default of 10s, a negative value drops think times entirely
+	MaxThinkTimeMs int `json:"maxThinkTimeMs"`
+}
+
+type crawlPage struct {
+	url   *url.URL
+	depth int
+}
+
+// Crawl browses the site from rawURL, following same-origin links and
+// the site's sitemap breadth-first while obeying robots.txt. Every HTML
+// page becomes a GET step, with the time it takes to read the previous
+// page as think time.
+func (g *HttpGenerator) Crawl(rawURL string, opts CrawlOptions) (*model.Script, error) {
+	start, err := url.ParseRequestURI(rawURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, errors.New("invalid URL")
+	}
+	start.Fragment = ""
+
+	maxDepth := opts.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultCrawlDepth
+	}
+	maxPages := opts.MaxPages
+	if maxPages <= 0 {
+		maxPages = defaultCrawlPages
+	}
+	if maxPages > maxCrawlPages {
+		maxPages = maxCrawlPages
+	}
+	maxThink := defaultMaxThinkTime
+	if opts.MaxThinkTimeMs > 0 {
+		maxThink = time.Duration(opts.MaxThinkTimeMs) * time.Millisecond
+	}
+
+	robots, sitemaps := g.robots(start)
+	if !robots.allowed(start.EscapedPath()) {
+		return nil, errors.New("robots.txt disallows crawling " + start.String())
+	}
+
+	queue := []crawlPage{{url: start}}
+	seen := map[string]bool{start.String(): true}
+	enqueue := func(u *url.URL, depth int) {
+		if depth > maxDepth || !sameOrigin(start, u) || seen[u.String()] || !robots.allowed(u.EscapedPath()) {
+			return
+		}
+		seen[u.String()] = true
+		queue = append(queue, crawlPage{url: u, depth: depth})
+	}
+
+	if !opts.SkipSitemap {
+		if len(sitemaps) == 0 {
+			sitemaps = []string{start.Scheme + "://" + start.Host + "/sitemap.xml"}
+		}
+		for _, u := range g.sitemapURLs(sitemaps) {
+			enqueue(u, 1)
+		}
+	}
+
+	script := &model.Script{ID: uuid.NewString()}
+	loadedAssets := make(map[string]bool)
+	var reading time.Duration
+
+	for len(queue) > 0 && pages(script) < maxPages {
+		page := queue[0]
+		queue = queue[1:]
+
+		doc, final, err := g.fetchHTML(page.url)
+		if err != nil {
+			if page.depth == 0 {
+				return nil, err
+			}
+			continue
+		}
+		if f := final.String(); f != page.url.String() {
+			switch {
+			case page.depth == 0:
+				// The start page may redirect, e.g. to https; links are
+				// followed on the site that actually answered
+				start = final
+			case seen[f] || !sameOrigin(start, final):
+				continue // a page already visited, or off the site
+			}
+			seen[f] = true
+		}
+
+		step := model.Step{Type: model.HTTP, Method: "GET", URL: page.url.String()}
+		if len(script.Steps) > 0 && opts.MaxThinkTimeMs >= 0 {
+			step.ThinkTimeMs = int(min(reading, maxThink).Milliseconds())
+		}
+		script.Steps = append(script.Steps, step)
+
+		links, assets, words := scanHTML(doc, final)
+		reading = max(minThinkTime, time.Duration(words)*time.Minute/wordsPerMinute)
+
+		if opts.IncludeAssets {
+			var batch []model.Step
+			for _, a := range assets {
+				if !sameOrigin(start, a) || loadedAssets[a.String()] || !robots.allowed(a.EscapedPath()) {
+					continue
+				}
+				loadedAssets[a.String()] = true
+				batch = append(batch, model.Step{Type: model.HTTP, Method: "GET", URL: a.String()})
+			}
+			if len(batch) > 0 {
+				script.Steps = append(script.Steps, model.Step{
+					Type:  model.Batch,
+					Batch: &model.BatchStep{Requests: batch, Parallelism: 6},
+				})
+			}
+		}
+
+		for _, l := range links {
+			enqueue(l, page.depth+1)
+		}
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, errors.New("no HTML pages found at " + start.String())
+	}
+	return script, nil
+}
+
+// pages counts the page steps, leaving out asset batches
+func pages(script *model.Script) int {
+	n := 0
+	for _, s := range script.Steps {
+		if s.Type == model.HTTP {
+			n++
+		}
+	}
+	return n
+}
+
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && strings.EqualFold(a.Host, b.Host)
+}
+
+func (g *HttpGenerator) get(u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", crawlUserAgent)
+	return g.client.Do(req)
+}
+
+// fetchHTML loads a page, returning its body and the URL after
+// redirects. Anything but a successful HTML response is an error.
+func (g *HttpGenerator) fetchHTML(u *url.URL) ([]byte, *url.URL, error) {
+	resp, err := g.get(u.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, nil, errors.New(u.String() + ": " + resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" && !strings.Contains(ct, "html") {
+		return nil, nil, errors.New(u.String() + " is not an HTML page")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlBody))
+	if err != nil {
+		return nil, nil, err
+	}
+	final := *resp.Request.URL
+	final.Fragment = ""
+	return body, &final, nil
+}
+
+// scanHTML finds the links and assets of a page and counts the words a
+// visitor reads
+func scanHTML(doc []byte, base *url.URL) (links, assets []*url.URL, words int) {
+	z := html.NewTokenizer(strings.NewReader(string(doc)))
+	skipText := 0 // inside <script> or <style>
+	nofollow := false
+
+	resolve := func(ref string) *url.URL {
+		ref = strings.TrimSpace(ref)
+		if ref == "" || strings.HasPrefix(ref, "#") {
+			return nil
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil
+		}
+		u.Fragment = ""
+		return u
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if nofollow {
+				links = nil
+			}
+			return links, assets, words
+
+		case html.TextToken:
+			if skipText == 0 {
+				words += len(strings.FieldsFunc(string(z.Text()), unicode.IsSpace))
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if (string(name) == "script" || string(name) == "style") && skipText > 0 {
+				skipText--
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := make(map[string]string)
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+
+			switch string(name) {
+			case "base":
+				if u := resolve(attrs["href"]); u != nil {
+					base = u
+				}
+			case "meta":
+				if strings.EqualFold(attrs["name"], "robots") && strings.Contains(strings.ToLower(attrs["content"]), "nofollow") {
+					nofollow = true
+				}
+			case "a", "area":
+				if strings.Contains(strings.ToLower(attrs["rel"]), "nofollow") {
+					continue
+				}
+				if _, download := attrs["download"]; download {
+					continue
+				}
+				if u := resolve(attrs["href"]); u != nil {
+					links = append(links, u)
+				}
+			case "link":
+				rel := strings.ToLower(attrs["rel"])
+				if strings.Contains(rel, "stylesheet") || strings.Contains(rel, "icon") {
+					if u := resolve(attrs["href"]); u != nil {
+						assets = append(assets, u)
+					}
+				}
+			case "script":
+				if tt == html.StartTagToken {
+					skipText++
+				}
+				if u := resolve(attrs["src"]); u != nil {
+					assets = append(assets, u)
+				}
+			case "style":
+				if tt == html.StartTagToken {
+					skipText++
+				}
+			case "img", "source", "video", "audio":
+				if u := resolve(attrs["src"]); u != nil {
+					assets = append(assets, u)
+				}
+			}
+		}
+	}
+}
+
+// robotsRules are the Allow and Disallow lines of the robots.txt group
+// that applies to the crawler
+type robotsRules struct {
+	allow    []string
+	disallow []string
+}
+
+// allowed applies the most specific matching rule, Allow winning ties
+func (r robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allow := -1, true
+	for _, p := range r.disallow {
+		if n := robotsMatch(p, path); n > best {
+			best, allow = n, false
+		}
+	}
+	for _, p := range r.allow {
+		if n := robotsMatch(p, path); n >= best && n >= 0 {
+			best, allow = n, true
+		}
+	}
+	return allow
+}
+
+// robotsMatch returns the length of pattern when it matches path, or
+// -1. Patterns may use * wildcards and a trailing $ anchor.
+func robotsMatch(pattern, path string) int {
+	anchored := strings.HasSuffix(pattern, "$")
+	p := strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(p, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return -1
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return -1
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored && rest != "" && !strings.HasSuffix(p, "*") {
+		return -1
+	}
+	return len(pattern)
+}
+
+// robots reads the site's robots.txt. A missing or unreadable file
+// allows everything.
+func (g *HttpGenerator) robots(site *url.URL) (robotsRules, []string) {
+	resp, err := g.get(site.Scheme + "://" + site.Host + "/robots.txt")
+	if err != nil {
+		return robotsRules{}, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return robotsRules{}, nil
+	}
+
+	var sitemaps []string
+	groups := make(map[string]*robotsRules)
+	var current []string // agents of the group being read
+	inRules := false
+
+	sc := bufio.NewScanner(io.LimitReader(resp.Body, maxCrawlBody))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				current, inRules = nil, false
+			}
+			agent := strings.ToLower(value)
+			current = append(current, agent)
+			if groups[agent] == nil {
+				groups[agent] = &robotsRules{}
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // an empty Disallow allows everything
+			}
+			for _, agent := range current {
+				if key == "allow" {
+					groups[agent].allow = append(groups[agent].allow, value)
+				} else {
+					groups[agent].disallow = append(groups[agent].disallow, value)
+				}
+			}
+		case "sitemap":
+			sitemaps = append(sitemaps, value)
+		}
+	}
+
+	if r, ok := groups[crawlUserAgent]; ok {
+		return *r, sitemaps
+	}
+	if r, ok := groups["*"]; ok {
+		return *r, sitemaps
+	}
+	return robotsRules{}, sitemaps
+}
+
+type sitemapDoc struct {
+	URLs     []string `xml:"url>loc"`
+	Sitemaps []string `xml:"sitemap>loc"`
+}
+
+// sitemapURLs reads page URLs from sitemaps, following one level of
+// sitemap index. Missing sitemaps are ignored.
+func (g *HttpGenerator) sitemapURLs(sitemaps []string) []*url.URL {
+	var out []*url.URL
+	for _, sm := range sitemaps {
+		doc, ok := g.sitemap(sm)
+		if !ok {
+			continue
+		}
+		locs := doc.URLs
+		for _, child := range doc.Sitemaps {
+			if d, ok := g.sitemap(child); ok {
+				locs = append(locs, d.URLs...)
+			}
+		}
+		for _, loc := range locs {
+			if u, err := url.Parse(strings.TrimSpace(loc)); err == nil {
+				u.Fragment = ""
+				out = append(out, u)
+			}
+		}
+	}
+	return out
+}
+
+func (g *HttpGenerator) sitemap(u string) (*sitemapDoc, bool) {
+	resp, err := g.get(u)
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, maxCrawlBody)).Decode(&doc); err != nil {
+		return nil, false
+	}
+	return &doc, true
+}
@@ -0,0 +1,400 @@
+package generator
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+// curlIgnored are curl options that take no value and do not change
+// the request itself
+var curlIgnored = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-k": true, "--insecure": true, "-L": true, "--location": true,
+	"-v": true, "--verbose": true, "-i": true, "--include": true,
+	"-f": true, "--fail": true, "-N": true, "--no-buffer": true,
+	"--http1.1": true, "--http2": true, "--http2-prior-knowledge": true,
+	"--globoff": true, "-g": true, "--path-as-is": true,
+
+	// Go's HTTP client already asks for and decodes gzip
+	"--compressed": true,
+}
+
+// curlIgnoredWithValue are curl options that take a value and do not
+// change the request itself
+var curlIgnoredWithValue = map[string]bool{
+	"-o": true, "--output": true, "-m": true, "--max-time": true,
+	"--connect-timeout": true, "--retry": true, "-w": true, "--write-out": true,
+	"--cacert": true, "--resolve": true, "--max-redirs": true,
+}
+
+// curlShortWithValue are the single-letter options that take a value,
+// which may be attached as in -XPOST
+const curlShortWithValue = "XHdubAeomw"
+
+// CurlGenerator builds a script from curl command lines such as those
+// copied from browser devtools, one HTTP step per command
+type CurlGenerator struct{}
+
+func NewCurlGenerator() *CurlGenerator {
+	return &CurlGenerator{}
+}
+
+// FromCurl parses one or more curl commands. Commands are separated by
+// newlines, ";" or "&&"; a trailing backslash continues a line.
+func (g *CurlGenerator) FromCurl(text string) (*model.Script, error) {
+	commands, err := splitShell(text)
+	if err != nil {
+		return nil, err
+	}
+
+	script := &model.Script{ID: uuid.NewString()}
+	for _, args := range commands {
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] != "curl" {
+			return nil, errors.New("not a curl command: " + args[0])
+		}
+
+		step, err := curlStep(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		script.Steps = append(script.Steps, step)
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, errors.New("no curl commands found")
+	}
+	return script, nil
+}
+
+func curlStep(args []string) (model.Step, error) {
+	step := model.Step{Type: model.HTTP}
+	header := make(map[string]string)
+	var data []string
+	var cookies []string
+	var rawURL string
+	asQuery := false
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			rawURL = arg
+			continue
+		}
+
+		name, value, hasValue := arg, "", false
+		if strings.HasPrefix(arg, "--") {
+			name, value, hasValue = strings.Cut(arg, "=")
+		} else if len(arg) > 2 {
+			if strings.IndexByte(curlShortWithValue, arg[1]) >= 0 {
+				// -XPOST, -H'Accept: */*'
+				name, value, hasValue = arg[:2], arg[2:], true
+			} else {
+				// Combined flags such as -sSL
+				for _, c := range arg[1:] {
+					if !curlIgnored["-"+string(c)] {
+						return step, errors.New("unsupported curl option: " + arg)
+					}
+				}
+				continue
+			}
+		}
+
+		if curlIgnored[name] {
+			continue
+		}
+
+		takeValue := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(args) {
+				return "", errors.New("curl option " + name + " needs a value")
+			}
+			i++
+			return args[i], nil
+		}
+
+		switch name {
+		case "-G", "--get":
+			asQuery = true
+		case "-I", "--head":
+			step.Method = "HEAD"
+		default:
+			v, err := takeValue()
+			if err != nil {
+				return step, err
+			}
+
+			switch name {
+			case "-X", "--request":
+				step.Method = strings.ToUpper(v)
+			case "-H", "--header":
+				k, val, ok := strings.Cut(v, ":")
+				if !ok {
+					return step, errors.New("invalid curl header: " + v)
+				}
+				header[strings.TrimSpace(k)] = strings.TrimSpace(val)
+			case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii":
+				if strings.HasPrefix(v, "@") && name != "--data-raw" {
+					return step, errors.New("curl data from a file is not supported: " + v)
+				}
+				data = append(data, v)
+			case "--data-urlencode":
+				data = append(data, curlURLEncode(v))
+			case "--json":
+				data = append(data, v)
+				setHeaderDefault(header, "Content-Type", "application/json")
+				setHeaderDefault(header, "Accept", "application/json")
+			case "-u", "--user":
+				user, pass, _ := strings.Cut(v, ":")
+				step.Auth = &model.Auth{Type: model.BasicAuth, Username: user, Password: pass}
+			case "-b", "--cookie":
+				if !strings.Contains(v, "=") {
+					return step, errors.New("curl cookie jar files are not supported: " + v)
+				}
+				cookies = append(cookies, v)
+			case "-A", "--user-agent":
+				header["User-Agent"] = v
+			case "-e", "--referer":
+				header["Referer"] = v
+			case "--url":
+				rawURL = v
+			default:
+				if !curlIgnoredWithValue[name] {
+					return step, errors.New("unsupported curl option: " + name)
+				}
+			}
+		}
+	}
+
+	if rawURL == "" {
+		return step, errors.New("curl command has no URL")
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL // curl's default scheme
+	}
+	if _, err := url.Parse(rawURL); err != nil {
+		return step, errors.New("invalid URL: " + rawURL)
+	}
+	step.URL = rawURL
+
+	if len(cookies) > 0 {
+		header["Cookie"] = strings.Join(cookies, "; ")
+	}
+
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if asQuery {
+			sep := "?"
+			if strings.Contains(step.URL, "?") {
+				sep = "&"
+			}
+			step.URL += sep + joined
+		} else {
+			step.Body = joined
+			setHeaderDefault(header, "Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+
+	if step.Method == "" {
+		step.Method = "GET"
+		if step.Body != "" {
+			step.Method = "POST"
+		}
+	}
+	if len(header) > 0 {
+		step.Header = header
+	}
+	return step, nil
+}
+
+// setHeaderDefault sets a header unless it is already set in any case
+func setHeaderDefault(header map[string]string, name, value string) {
+	for k := range header {
+		if strings.EqualFold(k, name) {
+			return
+		}
+	}
+	header[name] = value
+}
+
+// curlURLEncode encodes a --data-urlencode value: "name=value" encodes
+// only the value, anything else is encoded whole
+func curlURLEncode(v string) string {
+	if name, value, ok := strings.Cut(v, "="); ok {
+		if name == "" {
+			return url.QueryEscape(value)
+		}
+		return name + "=" + url.QueryEscape(value)
+	}
+	return url.QueryEscape(v)
+}
+
+// splitShell splits text into commands of words, following the POSIX
+// shell rules that matter for pasted commands: single and double
+// quotes, $'...' strings, backslash escapes and line continuations
+func splitShell(text string) ([][]string, error) {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	r := []rune(text)
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+
+		switch {
+		case c == '\\':
+			if i+1 < len(r) && r[i+1] == '\r' {
+				i++
+			}
+			if i+1 < len(r) && r[i+1] == '\n' {
+				i++ // line continuation
+				continue
+			}
+			if i+1 < len(r) {
+				i++
+				word.WriteRune(r[i])
+				inWord = true
+			}
+		case c == '\'':
+			end := indexRune(r, i+1, '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(string(r[i+1 : end]))
+			inWord = true
+			i = end
+		case c == '$' && i+1 < len(r) && r[i+1] == '\'':
+			end, s, err := ansiCString(r, i+2)
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(s)
+			inWord = true
+			i = end
+		case c == '"':
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				if r[i] == '\\' && i+1 < len(r) && strings.ContainsRune("\"\\$`\n", r[i+1]) {
+					i++
+					if r[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(r[i])
+			}
+			if i >= len(r) {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+		case c == '\n' || c == ';':
+			endCommand()
+		case c == '&' && i+1 < len(r) && r[i+1] == '&':
+			i++
+			endCommand()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands, nil
+}
+
+func indexRune(r []rune, from int, c rune) int {
+	for i := from; i < len(r); i++ {
+		if r[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// ansiCString reads a bash $'...' string starting after the opening
+// quote and returns the index of the closing quote
+func ansiCString(r []rune, from int) (int, string, error) {
+	var b strings.Builder
+	for i := from; i < len(r); i++ {
+		switch r[i] {
+		case '\'':
+			return i, b.String(), nil
+		case '\\':
+			if i+1 >= len(r) {
+				break
+			}
+			i++
+			switch r[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u', 'x':
+				// \xHH and \uHHHH
+				n := 2
+				if r[i] == 'u' {
+					n = 4
+				}
+				if i+n < len(r) {
+					var code rune
+					valid := true
+					for _, h := range r[i+1 : i+1+n] {
+						d := strings.IndexRune("0123456789abcdef", toLower(h))
+						if d < 0 {
+							valid = false
+							break
+						}
+						code = code*16 + rune(d)
+					}
+					if valid {
+						b.WriteRune(code)
+						i += n
+						continue
+					}
+				}
+				b.WriteRune('\\')
+				b.WriteRune(r[i])
+			default:
+				// \\, \' and \" stand for themselves
+				b.WriteRune(r[i])
+			}
+			continue
+		}
+		b.WriteRune(r[i])
+	}
+	return 0, "", errors.New("unterminated $'...' string")
+}
+
+func toLower(c rune) rune {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
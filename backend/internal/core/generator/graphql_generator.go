@@ -0,0 +1,276 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+// IntrospectionQuery is the subset of the standard introspection query
+// needed to build starter operations
+const IntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    types {
+      kind
+      name
+      fields {
+        name
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind
+  name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// GraphQLGenerator builds a starter script with one GRAPHQL step per
+// root field of a schema
+type GraphQLGenerator struct {
+	client *http.Client
+}
+
+type GraphQLOptions struct {
+	// IncludeMutations also generates steps for mutation fields
+	IncludeMutations bool `json:"includeMutations"`
+}
+
+func NewGraphQLGenerator() *GraphQLGenerator {
+	return &GraphQLGenerator{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Generate introspects the endpoint and builds a query-only script
+func (g *GraphQLGenerator) Generate(endpoint string) (*model.Script, error) {
+	return g.Introspect(endpoint, GraphQLOptions{})
+}
+
+// Introspect runs the introspection query against the endpoint and
+// builds a script from the returned schema
+func (g *GraphQLGenerator) Introspect(endpoint string, opts GraphQLOptions) (*model.Script, error) {
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return nil, errors.New("invalid URL")
+	}
+
+	payload, _ := json.Marshal(map[string]string{"query": IntrospectionQuery})
+	resp, err := g.client.Post(endpoint, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errors.New("introspection query failed: " + resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	return g.FromIntrospection(endpoint, data, opts)
+}
+
+type gqlTypeRef struct {
+	Kind   string      `json:"kind"`
+	Name   string      `json:"name"`
+	OfType *gqlTypeRef `json:"ofType"`
+}
+
+type gqlField struct {
+	Name string `json:"name"`
+	Args []struct {
+		Name string     `json:"name"`
+		Type gqlTypeRef `json:"type"`
+	} `json:"args"`
+	Type gqlTypeRef `json:"type"`
+}
+
+type gqlType struct {
+	Kind   string     `json:"kind"`
+	Name   string     `json:"name"`
+	Fields []gqlField `json:"fields"`
+}
+
+type gqlSchema struct {
+	QueryType    *struct{ Name string } `json:"queryType"`
+	MutationType *struct{ Name string } `json:"mutationType"`
+	Types        []gqlType              `json:"types"`
+}
+
+// FromIntrospection builds a script from an introspection result, either
+// the raw response ({"data": {"__schema": ...}}) or just {"__schema": ...}
+func (g *GraphQLGenerator) FromIntrospection(
+	endpoint string,
+	introspection []byte,
+	opts GraphQLOptions,
+) (*model.Script, error) {
+	var doc struct {
+		Data *struct {
+			Schema *gqlSchema `json:"__schema"`
+		} `json:"data"`
+		Schema *gqlSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(introspection, &doc); err != nil {
+		return nil, errors.New("invalid introspection result")
+	}
+
+	schema := doc.Schema
+	if doc.Data != nil && doc.Data.Schema != nil {
+		schema = doc.Data.Schema
+	}
+	if schema == nil || schema.QueryType == nil {
+		return nil, errors.New("introspection result has no query type")
+	}
+
+	types := make(map[string]gqlType, len(schema.Types))
+	for _, t := range schema.Types {
+		types[t.Name] = t
+	}
+
+	script := &model.Script{ID: uuid.NewString()}
+	script.Steps = append(script.Steps, rootSteps(endpoint, "query", types[schema.QueryType.Name], types)...)
+	if opts.IncludeMutations && schema.MutationType != nil {
+		script.Steps = append(script.Steps, rootSteps(endpoint, "mutation", types[schema.MutationType.Name], types)...)
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, errors.New("schema has no root fields")
+	}
+	return script, nil
+}
+
+func rootSteps(endpoint, kind string, root gqlType, types map[string]gqlType) []model.Step {
+	fields := append([]gqlField(nil), root.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+
+	var steps []model.Step
+	for _, f := range fields {
+		if strings.HasPrefix(f.Name, "__") {
+			continue
+		}
+
+		opName := strings.ToUpper(f.Name[:1]) + f.Name[1:]
+		var params, args []string
+		variables := make(map[string]interface{})
+		for _, a := range f.Args {
+			params = append(params, "$"+a.Name+": "+renderType(a.Type))
+			args = append(args, a.Name+": $"+a.Name)
+			variables[a.Name] = sampleValue(a.Type)
+		}
+
+		var q strings.Builder
+		q.WriteString(kind + " " + opName)
+		if len(params) > 0 {
+			q.WriteString("(" + strings.Join(params, ", ") + ")")
+		}
+		q.WriteString(" { " + f.Name)
+		if len(args) > 0 {
+			q.WriteString("(" + strings.Join(args, ", ") + ")")
+		}
+		q.WriteString(selection(f.Type, types))
+		q.WriteString(" }")
+
+		step := model.Step{
+			Type:   model.GraphQL,
+			Method: http.MethodPost,
+			URL:    endpoint,
+			GraphQL: &model.GraphQLStep{
+				Query:         q.String(),
+				OperationName: opName,
+			},
+		}
+		if len(variables) > 0 {
+			step.GraphQL.Variables, _ = json.Marshal(variables)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// selection picks the scalar fields of an object return type, one
+// level deep, so the starter query is valid without getting huge
+func selection(ref gqlTypeRef, types map[string]gqlType) string {
+	named := namedType(ref)
+	t, ok := types[named.Name]
+	if !ok || (t.Kind != "OBJECT" && t.Kind != "INTERFACE") {
+		return ""
+	}
+
+	var picked []string
+	for _, f := range t.Fields {
+		if len(f.Args) > 0 {
+			continue
+		}
+		switch namedType(f.Type).Kind {
+		case "SCALAR", "ENUM":
+			picked = append(picked, f.Name)
+		}
+	}
+	if len(picked) == 0 {
+		picked = []string{"__typename"}
+	}
+	return " { " + strings.Join(picked, " ") + " }"
+}
+
+func namedType(ref gqlTypeRef) gqlTypeRef {
+	for ref.OfType != nil && (ref.Kind == "NON_NULL" || ref.Kind == "LIST") {
+		ref = *ref.OfType
+	}
+	return ref
+}
+
+func renderType(ref gqlTypeRef) string {
+	switch ref.Kind {
+	case "NON_NULL":
+		if ref.OfType != nil {
+			return renderType(*ref.OfType) + "!"
+		}
+	case "LIST":
+		if ref.OfType != nil {
+			return "[" + renderType(*ref.OfType) + "]"
+		}
+	}
+	return ref.Name
+}
+
+// sampleValue returns a placeholder for a variable of the given type
+func sampleValue(ref gqlTypeRef) interface{} {
+	if ref.Kind == "NON_NULL" && ref.OfType != nil {
+		return sampleValue(*ref.OfType)
+	}
+	if ref.Kind == "LIST" {
+		return []interface{}{}
+	}
+
+	switch ref.Name {
+	case "Int", "Float":
+		return 0
+	case "Boolean":
+		return false
+	case "ID":
+		return "1"
+	case "String":
+		return ""
+	}
+	if ref.Kind == "INPUT_OBJECT" {
+		return map[string]interface{}{}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+// defaultMaxThinkTime caps recorded pauses, so a tester who walked away
+// from the browser does not stall every iteration
+const defaultMaxThinkTime = 10 * time.Second
+
+// staticExtensions are paths treated as static assets
+var staticExtensions = map[string]bool{
+	".js": true, ".mjs": true, ".css": true, ".map": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true,
+	".ico": true, ".webp": true, ".avif": true, ".bmp": true,
+	".woff": true, ".woff2": true, ".ttf": true, ".otf": true, ".eot": true,
+	".mp4": true, ".webm": true, ".mp3": true, ".wav": true,
+}
+
+// staticContentTypes are response types treated as static assets
+var staticContentTypes = []string{
+	"image/", "font/", "audio/", "video/",
+	"text/css", "text/javascript", "application/javascript",
+	"application/x-javascript", "application/font-",
+}
+
+// volatileHeaders change from one session to the next, or are set by
+// the HTTP client itself, so replaying them does more harm than good
+var volatileHeaders = map[string]bool{
+	"cookie":                    true,
+	"content-length":            true,
+	"host":                      true,
+	"connection":                true,
+	"accept-encoding":           true,
+	"if-none-match":             true,
+	"if-modified-since":         true,
+	"traceparent":               true,
+	"tracestate":                true,
+	"x-request-id":              true,
+	"x-amzn-trace-id":           true,
+	"x-b3-traceid":              true,
+	"x-b3-spanid":               true,
+	"sentry-trace":              true,
+	"baggage":                   true,
+	"upgrade-insecure-requests": true,
+}
+
+// HARGenerator builds a script from a browser recording in HAR 1.2
+// format, one HTTP step per recorded request
+type HARGenerator struct{}
+
+// HAROptions control which recorded requests become steps
+type HAROptions struct {
+	// IncludeStatic keeps images, scripts, stylesheets and fonts
+	IncludeStatic bool `json:"includeStatic"`
+
+	// IncludeThirdParty keeps requests to domains other than the first
+	// request's site and AllowedDomains
+	IncludeThirdParty bool `json:"includeThirdParty"`
+
+	// AllowedDomains are extra first-party domains; subdomains match
+	AllowedDomains []string `json:"allowedDomains"`
+
+	// KeepVolatileHeaders replays cookies, cache validators, tracing
+	// IDs and the like as recorded
+	KeepVolatileHeaders bool `json:"keepVolatileHeaders"`
+
+	// StripHeaders are additional headers to drop
+	StripHeaders []string `json:"stripHeaders"`
+
+	// MaxThinkTimeMs caps the pause taken from the recording; 0 means
+	// the default of 10s, a negative value drops think times entirely
+	MaxThinkTimeMs int `json:"maxThinkTimeMs"`
+}
+
+func NewHARGenerator() *HARGenerator {
+	return &HARGenerator{}
+}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"` // ms
+	Request         struct {
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Headers  []harHeader `json:"headers"`
+		PostData *struct {
+			MimeType string `json:"mimeType"`
+			Text     string `json:"text"`
+			Params   []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// FromHAR converts a HAR document. Entries are replayed in the order
+// they started, and the gap between the end of one request and the
+// start of the next becomes the next step's think time.
+func (g *HARGenerator) FromHAR(data []byte, opts HAROptions) (*model.Script, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, errors.New("invalid HAR: " + err.Error())
+	}
+	if len(har.Log.Entries) == 0 {
+		return nil, errors.New("HAR has no entries")
+	}
+
+	maxThink := defaultMaxThinkTime
+	if opts.MaxThinkTimeMs > 0 {
+		maxThink = time.Duration(opts.MaxThinkTimeMs) * time.Millisecond
+	}
+
+	strip := make(map[string]bool, len(opts.StripHeaders))
+	for _, h := range opts.StripHeaders {
+		strip[strings.ToLower(h)] = true
+	}
+
+	var sites []string
+	if first, err := url.Parse(har.Log.Entries[0].Request.URL); err == nil {
+		sites = append(sites, site(first.Hostname()))
+	}
+	for _, d := range opts.AllowedDomains {
+		sites = append(sites, strings.ToLower(strings.TrimPrefix(d, ".")))
+	}
+
+	entries := har.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	script := &model.Script{ID: uuid.NewString()}
+
+	// lastEnd follows every entry, filtered or not, so time spent
+	// loading skipped assets does not turn into think time
+	var lastEnd, prevEnd time.Time
+
+	for _, e := range entries {
+		prevEnd = lastEnd
+		end := e.StartedDateTime.Add(time.Duration(e.Time * float64(time.Millisecond)))
+		if end.After(lastEnd) {
+			lastEnd = end
+		}
+
+		u, err := url.Parse(e.Request.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue // data:, blob:, chrome-extension: and the like
+		}
+		if !opts.IncludeStatic && isStatic(u, e.Response.Content.MimeType) {
+			continue
+		}
+		if !opts.IncludeThirdParty && !inSites(u.Hostname(), sites) {
+			continue
+		}
+
+		step := model.Step{
+			Type:   model.HTTP,
+			Method: strings.ToUpper(e.Request.Method),
+			URL:    e.Request.URL,
+		}
+
+		for _, h := range e.Request.Headers {
+			// HTTP/2 pseudo-headers such as :authority are not real headers
+			name := strings.ToLower(h.Name)
+			if strings.HasPrefix(name, ":") || strip[name] {
+				continue
+			}
+			if !opts.KeepVolatileHeaders && volatileHeaders[name] {
+				continue
+			}
+			if step.Header == nil {
+				step.Header = make(map[string]string)
+			}
+			step.Header[h.Name] = h.Value
+		}
+
+		if pd := e.Request.PostData; pd != nil {
+			step.Body = pd.Text
+			if step.Body == "" && len(pd.Params) > 0 {
+				form := url.Values{}
+				for _, p := range pd.Params {
+					form.Add(p.Name, p.Value)
+				}
+				step.Body = form.Encode()
+			}
+		}
+
+		if len(script.Steps) > 0 && opts.MaxThinkTimeMs >= 0 {
+			if gap := e.StartedDateTime.Sub(prevEnd); gap > 0 {
+				if gap > maxThink {
+					gap = maxThink
+				}
+				step.ThinkTimeMs = int(gap.Milliseconds())
+			}
+		}
+
+		script.Steps = append(script.Steps, step)
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, errors.New("no requests left after filtering")
+	}
+	return script, nil
+}
+
+func isStatic(u *url.URL, mimeType string) bool {
+	if staticExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return true
+	}
+	mimeType = strings.ToLower(mimeType)
+	for _, prefix := range staticContentTypes {
+		if strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// site approximates a host's registrable domain by its last two
+// labels, so www.example.com and api.example.com are the same site
+func site(host string) string {
+	host = strings.ToLower(host)
+	if strings.Count(host, ".") < 2 || net.ParseIP(host) != nil {
+		return host
+	}
+	labels := strings.Split(host, ".")
+	return strings.Join(labels[len(labels)-2:], ".")
+}
+
+func inSites(host string, sites []string) bool {
+	host = strings.ToLower(host)
+	for _, s := range sites {
+		if host == s || strings.HasSuffix(host, "."+s) {
+			return true
+		}
+	}
+	return false
+}
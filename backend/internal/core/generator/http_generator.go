@@ -2,16 +2,22 @@ package generator
 
 import (
 	"errors"
+	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/google/uuid"
 	"k6clone/internal/core/model"
 )
 
-type HttpGenerator struct {}
+type HttpGenerator struct {
+	client *http.Client // used when crawling
+}
 
 func NewHttpGenerator() *HttpGenerator {
-	return &HttpGenerator{}
+	return &HttpGenerator{
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
 }
 
 func (g *HttpGenerator) Generate(rawURL string) (*model.Script, error) {
@@ -0,0 +1,604 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/engine"
+	"k6clone/internal/core/model"
+)
+
+var (
+	// jmeterVar matches ${name} references
+	jmeterVar = regexp.MustCompile(`\$\{([A-Za-z_][\w.-]*)\}`)
+
+	// jmeterProperty matches ${__P(name)} and ${__P(name,default)}
+	jmeterProperty = regexp.MustCompile(`^\$\{__P(?:roperty)?\(\s*[^,)]+\s*(?:,\s*([^)]*))?\)\}$`)
+
+	// jmeterFunction matches any other ${__function(...)} call
+	jmeterFunction = regexp.MustCompile(`\$\{__\w+\(`)
+)
+
+// jmeterIgnored are listeners, which only matter inside JMeter since
+// the engine collects its own results
+var jmeterIgnored = map[string]bool{
+	"ResultCollector": true, "BackendListener": true, "Summariser": true,
+}
+
+// JMXGenerator builds a script from a JMeter test plan
+type JMXGenerator struct{}
+
+func NewJMXGenerator() *JMXGenerator {
+	return &JMXGenerator{}
+}
+
+// JMXReport lists what an import could not convert
+type JMXReport struct {
+	Unsupported []JMXUnsupported `json:"unsupported"`
+}
+
+// JMXUnsupported is one element, or one setting of an element, that
+// was dropped or only partly converted
+type JMXUnsupported struct {
+	Element string `json:"element"` // the JMX tag, e.g. "IfController"
+	Name    string `json:"name"`    // the element's testname
+	Reason  string `json:"reason"`
+}
+
+// jmxNode is a generic JMX element. In JMX every test element is
+// followed by a hashTree sibling that holds its children.
+type jmxNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []jmxNode  `xml:",any"`
+}
+
+func (n *jmxNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *jmxNode) tag() string {
+	return n.XMLName.Local
+}
+
+// prop finds a direct property such as <stringProp name="...">
+func (n *jmxNode) prop(name string) *jmxNode {
+	for i := range n.Children {
+		if n.Children[i].attr("name") == name {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+func (n *jmxNode) str(name string) string {
+	if p := n.prop(name); p != nil {
+		return strings.TrimSpace(p.Text)
+	}
+	return ""
+}
+
+func (n *jmxNode) boolean(name string) bool {
+	return n.str(name) == "true"
+}
+
+// elements returns the elementProps of a collectionProp
+func (n *jmxNode) elements(collection string) []jmxNode {
+	if p := n.prop(collection); p != nil {
+		return p.Children
+	}
+	return nil
+}
+
+// jmxScope is what an element inherits from its ancestors. JMeter
+// applies config elements, timers and post-processors to every sampler
+// in their scope.
+type jmxScope struct {
+	group    []string
+	headers  map[string]string
+	defaults *jmxNode // HTTP Request Defaults
+	timerMs  int
+	extract  []model.Extraction
+}
+
+func (s jmxScope) child() jmxScope {
+	out := s
+	out.group = append([]string(nil), s.group...)
+	out.headers = make(map[string]string, len(s.headers))
+	for k, v := range s.headers {
+		out.headers[k] = v
+	}
+	out.extract = append([]model.Extraction(nil), s.extract...)
+	return out
+}
+
+type jmxConverter struct {
+	script  *model.Script
+	report  *JMXReport
+	threads []*model.Scenario
+}
+
+// FromJMX converts a JMeter test plan. Thread groups become the
+// script's scenario, HTTP samplers become steps, header managers and
+// HTTP Request Defaults apply to the samplers in their scope, CSV Data
+// Set Configs become datasets and regex and JSON extractors become
+// extraction rules. Everything else is listed in the report.
+func (g *JMXGenerator) FromJMX(data []byte) (*model.Script, *JMXReport, error) {
+	var root jmxNode
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&root); err != nil {
+		return nil, nil, errors.New("invalid JMX: " + err.Error())
+	}
+	if root.tag() != "jmeterTestPlan" {
+		return nil, nil, errors.New("invalid JMX: root element is " + root.tag())
+	}
+
+	c := &jmxConverter{
+		script: &model.Script{
+			ID:        uuid.NewString(),
+			Variables: make(map[string]string),
+		},
+		report: &JMXReport{Unsupported: []JMXUnsupported{}},
+	}
+
+	for _, tree := range root.Children {
+		if tree.tag() == "hashTree" {
+			c.walk(&tree, jmxScope{headers: map[string]string{}})
+		}
+	}
+
+	if len(c.script.Steps) == 0 {
+		return nil, nil, errors.New("test plan has no HTTP samplers")
+	}
+	c.scenario()
+	if len(c.script.Variables) == 0 {
+		c.script.Variables = nil
+	}
+	return c.script, c.report, nil
+}
+
+func (c *jmxConverter) unsupported(el *jmxNode, reason string) {
+	c.report.Unsupported = append(c.report.Unsupported, JMXUnsupported{
+		Element: el.tag(),
+		Name:    el.attr("testname"),
+		Reason:  reason,
+	})
+}
+
+// walk converts the elements of a hashTree. Config elements, timers
+// and extractors are collected first, since JMeter applies them to the
+// whole scope regardless of their position.
+func (c *jmxConverter) walk(tree *jmxNode, parent jmxScope) {
+	scope := parent.child()
+
+	type pair struct {
+		el       *jmxNode
+		children *jmxNode
+	}
+	var items []pair
+	for i := 0; i < len(tree.Children); i++ {
+		el := &tree.Children[i]
+		if el.tag() == "hashTree" {
+			continue
+		}
+		p := pair{el: el}
+		if i+1 < len(tree.Children) && tree.Children[i+1].tag() == "hashTree" {
+			p.children = &tree.Children[i+1]
+			i++
+		}
+		items = append(items, p)
+	}
+
+	var rest []pair
+	for _, it := range items {
+		if it.el.attr("enabled") == "false" {
+			c.unsupported(it.el, "disabled; skipped")
+			continue
+		}
+		if !c.config(it.el, &scope) {
+			rest = append(rest, it)
+		}
+	}
+
+	for _, it := range rest {
+		c.element(it.el, it.children, scope)
+	}
+}
+
+// config applies scope-wide elements and reports whether el was one
+func (c *jmxConverter) config(el *jmxNode, scope *jmxScope) bool {
+	switch el.tag() {
+	case "HeaderManager":
+		for _, h := range el.elements("HeaderManager.headers") {
+			if name := h.str("Header.name"); name != "" {
+				scope.headers[name] = c.value(el, h.str("Header.value"))
+			}
+		}
+	case "ConfigTestElement":
+		if el.attr("guiclass") == "HttpDefaultsGui" {
+			scope.defaults = el
+		} else {
+			c.unsupported(el, "config element not supported")
+		}
+	case "CSVDataSet":
+		c.dataset(el)
+	case "Arguments":
+		c.arguments(el, el)
+	case "ConstantTimer":
+		delay, _ := strconv.Atoi(c.value(el, el.str("ConstantTimer.delay")))
+		scope.timerMs += delay
+	case "UniformRandomTimer", "GaussianRandomTimer", "PoissonRandomTimer":
+		// The constant part is kept; the random part is not
+		delay, _ := strconv.Atoi(c.value(el, el.str("ConstantTimer.delay")))
+		scope.timerMs += delay
+		c.unsupported(el, "random delay not supported; constant offset kept")
+	case "RegexExtractor":
+		c.regexExtractor(el, scope)
+	case "JSONPostProcessor":
+		c.jsonExtractor(el, scope)
+	case "CookieManager":
+		c.unsupported(el, "cookies are not kept between requests")
+	case "CacheManager", "AuthManager", "DNSCacheManager", "KeystoreConfig":
+		c.unsupported(el, "config element not supported")
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *jmxConverter) element(el, children *jmxNode, scope jmxScope) {
+	switch el.tag() {
+	case "TestPlan":
+		if udv := el.prop("TestPlan.user_defined_variables"); udv != nil {
+			c.arguments(el, udv)
+		}
+		if children != nil {
+			c.walk(children, scope)
+		}
+	case "ThreadGroup":
+		c.threadGroup(el)
+		scope.group = append(scope.group, el.attr("testname"))
+		if children != nil {
+			c.walk(children, scope)
+		}
+	case "TransactionController":
+		scope.group = append(scope.group, el.attr("testname"))
+		if children != nil {
+			c.walk(children, scope)
+		}
+	case "GenericController":
+		// Simple Controller only groups elements in the tree
+		if children != nil {
+			c.walk(children, scope)
+		}
+	case "HTTPSamplerProxy", "HTTPSampler":
+		c.sampler(el, children, scope)
+	default:
+		switch {
+		case jmeterIgnored[el.tag()]:
+			c.unsupported(el, "listener; results are collected by the engine")
+		case strings.HasSuffix(el.tag(), "Controller"):
+			c.unsupported(el, "logic controller not supported; children run unconditionally")
+			if children != nil {
+				c.walk(children, scope)
+			}
+		case strings.HasSuffix(el.tag(), "ThreadGroup"):
+			c.unsupported(el, "thread group type not supported; its samplers were skipped")
+		default:
+			c.unsupported(el, "element not supported")
+		}
+	}
+}
+
+// value converts ${var} references to {{var}} placeholders and reports
+// JMeter functions that cannot be converted
+func (c *jmxConverter) value(el *jmxNode, s string) string {
+	if jmeterFunction.MatchString(s) {
+		c.unsupported(el, "JMeter function in "+strconv.Quote(s)+" not supported")
+	}
+	return jmeterVar.ReplaceAllString(s, "{{$1}}")
+}
+
+// number reads a numeric setting, resolving ${__P(name,default)} to its
+// default
+func (c *jmxConverter) number(el *jmxNode, prop string) int {
+	s := el.str(prop)
+	if m := jmeterProperty.FindStringSubmatch(s); m != nil {
+		s = strings.TrimSpace(m[1])
+	}
+	if s == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		c.unsupported(el, fmt.Sprintf("%s %q is not a number", prop, el.str(prop)))
+	}
+	return n
+}
+
+func (c *jmxConverter) arguments(el, args *jmxNode) {
+	for _, arg := range args.elements("Arguments.arguments") {
+		if name := arg.str("Argument.name"); name != "" {
+			c.script.Variables[name] = c.value(el, arg.str("Argument.value"))
+		}
+	}
+}
+
+func (c *jmxConverter) threadGroup(el *jmxNode) {
+	sc := &model.Scenario{
+		VUs:           c.number(el, "ThreadGroup.num_threads"),
+		RampUpSeconds: c.number(el, "ThreadGroup.ramp_time"),
+	}
+	if el.boolean("ThreadGroup.scheduler") {
+		sc.Duration = c.number(el, "ThreadGroup.duration")
+	}
+	if loop := el.prop("ThreadGroup.main_controller"); loop != nil && !loop.boolean("LoopController.continue_forever") {
+		if loops := c.number(loop, "LoopController.loops"); loops > 0 {
+			sc.Iterations = loops
+		}
+	}
+	c.threads = append(c.threads, sc)
+}
+
+// scenario merges the thread groups. The engine runs one scenario, so
+// several thread groups become their groups of steps run by all VUs.
+func (c *jmxConverter) scenario() {
+	if len(c.threads) == 0 {
+		return
+	}
+	sc := *c.threads[0]
+	for _, t := range c.threads[1:] {
+		sc.VUs += t.VUs
+		sc.RampUpSeconds = max(sc.RampUpSeconds, t.RampUpSeconds)
+		sc.Duration = max(sc.Duration, t.Duration)
+		sc.Iterations = max(sc.Iterations, t.Iterations)
+	}
+	if len(c.threads) > 1 {
+		c.report.Unsupported = append(c.report.Unsupported, JMXUnsupported{
+			Element: "ThreadGroup",
+			Reason:  "several thread groups merged into one scenario; each runs as a group of steps",
+		})
+	}
+	c.script.Scenario = &sc
+}
+
+func (c *jmxConverter) dataset(el *jmxNode) {
+	file := el.str("filename")
+	if file == "" {
+		c.unsupported(el, "no filename; skipped")
+		return
+	}
+	if jmeterVar.MatchString(file) || jmeterFunction.MatchString(file) {
+		c.unsupported(el, "filename with variables not supported; skipped")
+		return
+	}
+
+	ds := model.Dataset{
+		// JMX files often hold absolute paths from someone's machine;
+		// the file is expected in the data directory under its name
+		File:            path.Base(strings.ReplaceAll(file, `\`, "/")),
+		Delimiter:       el.str("delimiter"),
+		IgnoreFirstLine: el.boolean("ignoreFirstLine"),
+		StopAtEnd:       el.str("recycle") == "false" && el.boolean("stopThread"),
+	}
+	if ds.Delimiter == `\t` {
+		ds.Delimiter = "\t"
+	}
+	if names := el.str("variableNames"); names != "" {
+		sep := ds.Delimiter
+		if sep == "" {
+			sep = ","
+		}
+		for _, n := range strings.Split(names, sep) {
+			ds.Variables = append(ds.Variables, strings.TrimSpace(n))
+		}
+	}
+	if el.str("recycle") == "false" && !el.boolean("stopThread") {
+		c.unsupported(el, "end-of-file without recycling or stopping not supported; rows are recycled")
+	}
+	if mode := el.str("shareMode"); mode != "" && mode != "shareMode.all" {
+		c.unsupported(el, "share mode "+mode+" not supported; rows are shared by all VUs")
+	}
+	c.script.Datasets = append(c.script.Datasets, ds)
+}
+
+func (c *jmxConverter) regexExtractor(el *jmxNode, scope *jmxScope) {
+	if field := el.str("RegexExtractor.useHeaders"); field != "" && field != "false" {
+		c.unsupported(el, "only response bodies can be matched; skipped")
+		return
+	}
+	if tpl := el.str("RegexExtractor.template"); tpl != "" && tpl != "$1$" {
+		c.unsupported(el, "template "+tpl+" not supported; the first group is used")
+	}
+	if n := el.str("RegexExtractor.match_number"); n != "" && n != "1" {
+		c.unsupported(el, "match number "+n+" not supported; the first match is used")
+	}
+
+	expr := el.str("RegexExtractor.regex")
+	if err := engine.ValidateRule(model.RegexRule, expr); err != nil {
+		c.unsupported(el, err.Error()+"; skipped")
+		return
+	}
+
+	scope.extract = append(scope.extract, model.Extraction{
+		Var:     el.str("RegexExtractor.refname"),
+		Type:    model.RegexRule,
+		Expr:    expr,
+		Default: el.str("RegexExtractor.default"),
+	})
+}
+
+func (c *jmxConverter) jsonExtractor(el *jmxNode, scope *jmxScope) {
+	names := strings.Split(el.str("JSONPostProcessor.referenceNames"), ";")
+	exprs := strings.Split(el.str("JSONPostProcessor.jsonPathExprs"), ";")
+	defaults := strings.Split(el.str("JSONPostProcessor.defaultValues"), ";")
+
+	if n := el.str("JSONPostProcessor.match_numbers"); n != "" && strings.Trim(n, "1;") != "" {
+		c.unsupported(el, "match numbers "+n+" not supported; the first match is used")
+	}
+
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || i >= len(exprs) {
+			continue
+		}
+		expr := strings.TrimSpace(exprs[i])
+		if err := engine.ValidateRule(model.JSONPathRule, expr); err != nil {
+			c.unsupported(el, name+": "+err.Error()+"; skipped")
+			continue
+		}
+		ex := model.Extraction{
+			Var:  name,
+			Type: model.JSONPathRule,
+			Expr: expr,
+		}
+		if i < len(defaults) {
+			ex.Default = defaults[i]
+		}
+		scope.extract = append(scope.extract, ex)
+	}
+}
+
+func (c *jmxConverter) sampler(el, children *jmxNode, scope jmxScope) {
+	// A sampler's own children (header managers, extractors, timers)
+	// apply to it alone
+	if children != nil {
+		scope = scope.child()
+		for i := range children.Children {
+			child := &children.Children[i]
+			if child.tag() == "hashTree" {
+				continue
+			}
+			if child.attr("enabled") == "false" {
+				c.unsupported(child, "disabled; skipped")
+				continue
+			}
+			if !c.config(child, &scope) {
+				c.unsupported(child, "element not supported")
+			}
+		}
+	}
+
+	setting := func(name string) string {
+		if v := el.str("HTTPSampler." + name); v != "" {
+			return v
+		}
+		if scope.defaults != nil {
+			return scope.defaults.str("HTTPSampler." + name)
+		}
+		return ""
+	}
+
+	step := model.Step{
+		Type:        model.HTTP,
+		Method:      strings.ToUpper(el.str("HTTPSampler.method")),
+		Group:       strings.Join(scope.group, "::"),
+		ThinkTimeMs: scope.timerMs,
+	}
+	if step.Method == "" {
+		step.Method = "GET"
+	}
+
+	p := setting("path")
+	if strings.HasPrefix(p, "http://") || strings.HasPrefix(p, "https://") {
+		step.URL = c.value(el, p)
+	} else {
+		protocol := setting("protocol")
+		if protocol == "" {
+			protocol = "http"
+		}
+		host := setting("domain")
+		if host == "" {
+			c.unsupported(el, "no server name; skipped")
+			return
+		}
+		if port := setting("port"); port != "" {
+			host += ":" + port
+		}
+		if p != "" && !strings.HasPrefix(p, "/") && !strings.HasPrefix(p, "${") {
+			p = "/" + p
+		}
+		step.URL = c.value(el, protocol+"://"+host+p)
+	}
+
+	if len(scope.headers) > 0 {
+		step.Header = make(map[string]string, len(scope.headers))
+		for k, v := range scope.headers {
+			step.Header[k] = v
+		}
+	}
+
+	args := el.prop("HTTPsampler.Arguments")
+	if args == nil {
+		args = el.prop("HTTPSampler.Arguments")
+	}
+	if args != nil {
+		c.samplerArgs(el, args, &step)
+	}
+
+	if files := el.prop("HTTPsampler.Files"); files != nil && len(files.elements("HTTPFileArgs.files")) > 0 {
+		c.unsupported(el, "file uploads not supported")
+	}
+
+	if len(scope.extract) > 0 {
+		step.Extract = scope.extract
+	}
+	c.script.Steps = append(c.script.Steps, step)
+}
+
+// samplerArgs turns the sampler's parameters into a query string or a
+// form body, or takes the raw body as it is
+func (c *jmxConverter) samplerArgs(el, args *jmxNode, step *model.Step) {
+	list := args.elements("Arguments.arguments")
+	if len(list) == 0 {
+		return
+	}
+
+	if el.boolean("HTTPSampler.postBodyRaw") {
+		step.Body = c.value(el, list[0].str("Argument.value"))
+		return
+	}
+
+	var pairs []string
+	for _, arg := range list {
+		name := arg.str("Argument.name")
+		value := arg.str("Argument.value")
+		if arg.str("HTTPArgument.always_encode") == "true" {
+			name, value = url.QueryEscape(name), url.QueryEscape(value)
+		}
+		pair := name
+		if arg.str("HTTPArgument.use_equals") != "false" || value != "" {
+			pair += "=" + value
+		}
+		pairs = append(pairs, c.value(el, pair))
+	}
+	encoded := strings.Join(pairs, "&")
+
+	switch step.Method {
+	case "POST", "PUT", "PATCH":
+		step.Body = encoded
+		if step.Header == nil {
+			step.Header = make(map[string]string)
+		}
+		setHeaderDefault(step.Header, "Content-Type", "application/x-www-form-urlencoded")
+	default:
+		sep := "?"
+		if strings.Contains(step.URL, "?") {
+			sep = "&"
+		}
+		step.URL += sep + encoded
+	}
+}
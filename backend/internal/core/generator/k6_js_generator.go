@@ -2,9 +2,14 @@ package generator
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
+	"k6clone/internal/core/engine"
 	"k6clone/internal/core/model"
 )
 
@@ -20,12 +25,20 @@ type K6JSInput struct {
 }
 
 func (g *K6JSGenerator) Generate(input *K6JSInput) (string, error) {
-	const tpl = `import http from "k6/http";
-import { check, sleep } from "k6";
+	// Scripts uploaded as k6 JavaScript are returned as they are
+	if input.Script.Source != "" {
+		return input.Script.Source, nil
+	}
 
+	const tpl = `import http from "k6/http";
+import { check, group, sleep } from "k6";{{range .Imports}}
+{{.}}{{end}}
+{{range .Init}}
+{{.}}{{end}}
 export const options = {
   vus: {{.VUs}},
-  duration: "{{.Duration}}s",
+  duration: "{{.Duration}}s",{{range .Options}}
+  {{.Name}}: {{.Value}},{{end}}
   thresholds: {
     http_req_duration: ['p(95)<2000', 'p(99)<5000'],
     http_req_failed: ['rate<0.1'],
@@ -33,42 +46,159 @@ export const options = {
 };
 
 export default function () {
-{{range $i, $step := .Steps}}
-  // Step {{add $i 1}}: {{$step.Method}} {{$step.URL}}
-  const res{{$i}} = http.{{lower $step.Method}}("{{$step.URL}}"{{if $step.Body}}, {{$step.Body}}{{end}});
-  check(res{{$i}}, {
-    "status is 2xx": (r) => r.status >= 200 && r.status < 300,
-  });
-{{end}}
+{{range .Steps}}
+{{.}}{{end}}
   sleep(1);
 }
 `
+	type option struct {
+		Name  string
+		Value int
+	}
+
 	type view struct {
 		VUs      int
 		Duration int
-		Steps    []model.Step
+		Imports  []string
+		Init     []string
+		Options  []option
+		Steps    []string
+	}
+
+	v := view{
+		VUs:      input.Config.VUs,
+		Duration: input.Config.Duration,
+	}
+
+	if input.Script.Replay != nil {
+		return "", errors.New("replay scripts only run on the built-in engine")
+	}
+
+	seen := make(map[string]bool)
+	options := make(map[string]int)
+	var groups []string
+
+	// A traffic mix runs one step per iteration, picked by weight
+	total := 0
+	for _, step := range input.Script.Steps {
+		total += step.Weight
+	}
+	if total > 0 {
+		v.Steps = append(v.Steps, fmt.Sprintf("  const pick = Math.random() * %d;\n", total))
+	}
+	upTo := 0
+
+	for i := range input.Script.Steps {
+		step := &input.Script.Steps[i]
+		if total > 0 && step.Weight <= 0 {
+			continue
+		}
+
+		snippet, err := stepSnippet(i, step)
+		if err != nil {
+			return "", err
+		}
+
+		for _, imp := range snippet.Imports {
+			if !seen[imp] {
+				seen[imp] = true
+				v.Imports = append(v.Imports, imp)
+			}
+		}
+		if snippet.Init != "" {
+			v.Init = append(v.Init, snippet.Init)
+		}
+		for name, value := range snippet.Options {
+			if value > options[name] {
+				options[name] = value
+			}
+		}
+		code := snippet.Code
+		if step.ThinkTimeMs > 0 {
+			code = fmt.Sprintf("  sleep(%s);\n", strconv.FormatFloat(float64(step.ThinkTimeMs)/1000, 'f', -1, 64)) + code
+		}
+		if total > 0 {
+			code = fmt.Sprintf("  if (pick >= %d && pick < %d) {\n", upTo, upTo+step.Weight) + indent(code, 1) + "  }\n"
+			upTo += step.Weight
+		}
+		path := engine.GroupPath(step.Group)
+		var head string
+		groups, head = enterGroups(groups, path)
+		v.Steps = append(v.Steps, head+indent(code, len(groups)))
+	}
+	if _, tail := enterGroups(groups, nil); tail != "" {
+		v.Steps = append(v.Steps, tail)
 	}
 
-	funcMap := template.FuncMap{
-		"lower": func(s string) string {
-			return strings.ToLower(s)
-		},
-		"add": func(a, b int) int {
-			return a + b
-		},
+	for name, value := range options {
+		v.Options = append(v.Options, option{Name: name, Value: value})
 	}
+	sort.Slice(v.Options, func(i, j int) bool { return v.Options[i].Name < v.Options[j].Name })
 
-	t, err := template.New("k6").Funcs(funcMap).Parse(tpl)
+	t, err := template.New("k6").Parse(tpl)
 	if err != nil {
 		return "", err
 	}
 
 	var buf bytes.Buffer
-	err = t.Execute(&buf, view{
-		VUs:      input.Config.VUs,
-		Duration: input.Config.Duration,
-		Steps:    input.Script.Steps,
-	})
+	err = t.Execute(&buf, v)
 
 	return buf.String(), err
-}
\ No newline at end of file
+}
+
+// stepSnippet asks the step's executor for its k6 code. Step types
+// without a k6 equivalent are left as a comment.
+func stepSnippet(index int, step *model.Step) (engine.K6Snippet, error) {
+	stepType := step.Type
+	if stepType == "" {
+		stepType = model.HTTP
+	}
+
+	step = engine.ApplyAuth(step, func(s string) string { return s })
+
+	e, ok := engine.Executor(stepType)
+	if ok {
+		if gen, ok := e.(engine.K6Generator); ok {
+			return gen.K6JS(index, step)
+		}
+	}
+
+	return engine.K6Snippet{
+		Code: fmt.Sprintf("  // Step %d: %s %s has no k6 equivalent; skipped\n", index+1, stepType, step.URL),
+	}, nil
+}
+
+// enterGroups closes and opens group() blocks to move from the open
+// group path to the next step's path
+func enterGroups(open, path []string) ([]string, string) {
+	common := 0
+	for common < len(open) && common < len(path) && open[common] == path[common] {
+		common++
+	}
+
+	var b strings.Builder
+	for len(open) > common {
+		open = open[:len(open)-1]
+		b.WriteString(indent("  });\n", len(open)))
+	}
+	for _, name := range path[common:] {
+		b.WriteString(indent("  group("+strconv.Quote(name)+", function () {\n", len(open)))
+		open = append(open, name)
+	}
+	return open, b.String()
+}
+
+// indent shifts every line of step code into nested group blocks
+func indent(code string, depth int) string {
+	if depth == 0 {
+		return code
+	}
+	pad := strings.Repeat("  ", depth)
+	lines := strings.SplitAfter(code, "\n")
+	for i, line := range lines {
+		if line != "" && line != "\n" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "")
+}
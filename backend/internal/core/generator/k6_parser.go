@@ -0,0 +1,1151 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja/ast"
+	"github.com/dop251/goja/file"
+	"github.com/dop251/goja/parser"
+	"github.com/dop251/goja/token"
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+// The parser reads ES module syntax the same way the engine runs it:
+// imports are dropped and exports become plain declarations. Both keep
+// the line structure, so warnings point at the right lines.
+var (
+	parseImport        = regexp.MustCompile(`(?m)^\s*import\s+(?:([\w$]+)|\*\s+as\s+([\w$]+)|\{([^}]*)\})\s+from\s+['"]([^'"]+)['"]\s*;?`)
+	parseExportDefault = regexp.MustCompile(`(?m)^(\s*)export\s+default\s+`)
+	parseExport        = regexp.MustCompile(`(?m)^(\s*)export\s+`)
+)
+
+// K6Parser rebuilds a step script from k6 JavaScript. Only the static
+// subset K6JSGenerator writes, and people commonly write by hand, is
+// understood; everything else is reported.
+type K6Parser struct{}
+
+func NewK6Parser() *K6Parser {
+	return &K6Parser{}
+}
+
+// K6Import is a parsed k6 script
+type K6Import struct {
+	Script   *model.Script    `json:"script"`
+	Config   model.TestConfig `json:"config"`
+	Warnings []string         `json:"warnings"`
+}
+
+// Parse converts a k6 script. HTTP calls become steps, check() calls
+// become step checks where they test the body or JSON, sleep() becomes
+// the next step's think time, group() becomes step groups and options
+// become the test config.
+func (p *K6Parser) Parse(source string) (*K6Import, error) {
+	k := &k6Converter{
+		script: &model.Script{
+			ID:        uuid.NewString(),
+			Variables: make(map[string]string),
+		},
+		httpNames: make(map[string]bool),
+		k6Names:   make(map[string]string),
+		consts:    make(map[string]string),
+		responses: make(map[string]int),
+	}
+
+	src := parseImport.ReplaceAllStringFunc(source, k.importLine)
+	src = parseExportDefault.ReplaceAllString(src, "${1}var __default = ")
+	src = parseExport.ReplaceAllString(src, "$1")
+
+	program, err := parser.ParseFile(nil, "script.js", src, 0)
+	if err != nil {
+		return nil, errors.New("invalid k6 script: " + err.Error())
+	}
+	k.program = program
+
+	var main ast.Node
+	for _, stmt := range program.Body {
+		if fn := k.topLevel(stmt); fn != nil {
+			main = fn
+		}
+	}
+
+	switch fn := main.(type) {
+	case *ast.FunctionLiteral:
+		k.block(fn.Body.List)
+	case *ast.ArrowFunctionLiteral:
+		if body, ok := fn.Body.(*ast.BlockStatement); ok {
+			k.block(body.List)
+		}
+	default:
+		return nil, errors.New("k6 script has no default function")
+	}
+
+	// A pause at the end of the iteration has nowhere to go; the k6
+	// generator always ends iterations with sleep(1)
+	if k.pendingSleep > 0 && k.pendingSleep != 1000 {
+		k.warn(0, fmt.Sprintf("sleep(%g) at the end of the iteration dropped", float64(k.pendingSleep)/1000))
+	}
+
+	if len(k.script.Steps) == 0 {
+		return nil, errors.New("k6 script has no HTTP requests that could be converted")
+	}
+	if len(k.script.Variables) == 0 {
+		k.script.Variables = nil
+	}
+
+	k.config.ScriptID = k.script.ID
+	if k.warnings == nil {
+		k.warnings = []string{}
+	}
+	return &K6Import{Script: k.script, Config: k.config, Warnings: k.warnings}, nil
+}
+
+type k6Converter struct {
+	program  *ast.Program
+	script   *model.Script
+	config   model.TestConfig
+	warnings []string
+
+	httpNames map[string]bool   // local names of the k6/http module
+	k6Names   map[string]string // local name of check, sleep, group
+	consts    map[string]string // constant strings, already templated
+	responses map[string]int    // response variables by step index
+
+	group        []string
+	pendingSleep int // ms, applied to the next step
+}
+
+func (k *k6Converter) warn(at file.Idx, msg string) {
+	if at > 0 {
+		line := k.program.File.Position(int(at) - k.program.File.Base()).Line
+		msg = fmt.Sprintf("line %d: %s", line, msg)
+	}
+	k.warnings = append(k.warnings, msg)
+}
+
+func (k *k6Converter) importLine(line string) string {
+	m := parseImport.FindStringSubmatch(line)
+	module := m[4]
+	switch module {
+	case "k6/http":
+		if name := m[1] + m[2]; name != "" {
+			k.httpNames[name] = true
+		}
+	case "k6":
+		for _, part := range strings.Split(m[3], ",") {
+			name, local, found := strings.Cut(strings.TrimSpace(part), " as ")
+			if !found {
+				local = name
+			}
+			if name = strings.TrimSpace(name); name != "" {
+				k.k6Names[strings.TrimSpace(local)] = name
+			}
+		}
+	default:
+		k.warnings = append(k.warnings, "module "+module+" not supported; calls to it are not converted")
+	}
+	// Keep the line count so positions stay right
+	return strings.Repeat("\n", strings.Count(line, "\n"))
+}
+
+// topLevel handles one statement outside the default function and
+// returns the default function when stmt declares it
+func (k *k6Converter) topLevel(stmt ast.Statement) ast.Node {
+	switch s := stmt.(type) {
+	case *ast.FunctionDeclaration:
+		k.warn(s.Idx0(), "function "+s.Function.Name.Name.String()+" not converted")
+	case *ast.VariableStatement:
+		return k.declarations(s.List)
+	case *ast.LexicalDeclaration:
+		return k.declarations(s.List)
+	case *ast.EmptyStatement:
+	default:
+		k.warn(stmtIdx(stmt), "top-level statement not converted")
+	}
+	return nil
+}
+
+func (k *k6Converter) declarations(list []*ast.Binding) ast.Node {
+	var main ast.Node
+	for _, b := range list {
+		id, ok := b.Target.(*ast.Identifier)
+		if !ok || b.Initializer == nil {
+			k.warn(b.Idx0(), "declaration not converted")
+			continue
+		}
+
+		name := id.Name.String()
+		switch name {
+		case "__default":
+			switch fn := b.Initializer.(type) {
+			case *ast.FunctionLiteral, *ast.ArrowFunctionLiteral:
+				main = fn
+			default:
+				k.warn(b.Idx0(), "default export is not a function")
+			}
+			continue
+		case "options":
+			k.options(b.Initializer)
+			continue
+		}
+
+		if v, ok := k.str(b.Initializer); ok {
+			k.consts[name] = v
+			continue
+		}
+		k.warn(b.Idx0(), "top-level "+name+" not converted")
+	}
+	return main
+}
+
+// options maps vus, duration, iterations and stages onto the test
+// config and the script's scenario
+func (k *k6Converter) options(expr ast.Expression) {
+	obj, ok := expr.(*ast.ObjectLiteral)
+	if !ok {
+		k.warn(expr.Idx0(), "options is not an object literal; not converted")
+		return
+	}
+
+	for _, prop := range obj.Value {
+		key, value, ok := keyed(prop)
+		if !ok {
+			k.warn(prop.Idx0(), "option not converted")
+			continue
+		}
+
+		switch key {
+		case "vus":
+			k.config.VUs = k.integer(value)
+		case "duration":
+			k.config.Duration = k.seconds(value)
+		case "iterations":
+			// k6 shares iterations across VUs; the scenario counts them
+			// per VU
+			n := k.integer(value)
+			k.scenario().Iterations = n
+			k.warn(prop.Idx0(), "iterations are counted per VU")
+		case "stages":
+			k.stages(value)
+		case "thresholds":
+			k.warn(prop.Idx0(), "thresholds only apply to k6 JavaScript scripts; not converted")
+		default:
+			k.warn(prop.Idx0(), "option "+key+" not converted")
+		}
+	}
+
+	// The scenario keeps the load with the stored script; tests started
+	// without VUs or a duration take them from it
+	if k.config.VUs > 0 || k.config.Duration > 0 || k.script.Scenario != nil {
+		sc := k.scenario()
+		sc.VUs = k.config.VUs
+		sc.Duration = k.config.Duration
+	}
+}
+
+func (k *k6Converter) scenario() *model.Scenario {
+	if k.script.Scenario == nil {
+		k.script.Scenario = &model.Scenario{}
+	}
+	return k.script.Scenario
+}
+
+// stages become the peak VUs over the total duration, with the first
+// stage as ramp-up when it starts from nothing
+func (k *k6Converter) stages(expr ast.Expression) {
+	arr, ok := expr.(*ast.ArrayLiteral)
+	if !ok {
+		k.warn(expr.Idx0(), "stages is not an array literal; not converted")
+		return
+	}
+
+	total, peak, rampUp := 0, 0, 0
+	for i, item := range arr.Value {
+		obj, ok := item.(*ast.ObjectLiteral)
+		if !ok {
+			k.warn(item.Idx0(), "stage not converted")
+			continue
+		}
+		dur, target := 0, 0
+		for _, prop := range obj.Value {
+			key, value, ok := keyed(prop)
+			switch {
+			case !ok:
+			case key == "duration":
+				dur = k.seconds(value)
+			case key == "target":
+				target = k.integer(value)
+			}
+		}
+		total += dur
+		peak = max(peak, target)
+		if i == 0 {
+			rampUp = dur
+		}
+	}
+
+	k.config.VUs = peak
+	k.config.Duration = total
+	k.scenario().RampUpSeconds = rampUp
+	k.warn(expr.Idx0(), "stages approximated as a ramp-up to the peak VUs")
+}
+
+func (k *k6Converter) integer(expr ast.Expression) int {
+	if n, ok := expr.(*ast.NumberLiteral); ok {
+		switch v := n.Value.(type) {
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+	}
+	k.warn(expr.Idx0(), "expected a number")
+	return 0
+}
+
+func (k *k6Converter) seconds(expr ast.Expression) int {
+	s, ok := expr.(*ast.StringLiteral)
+	if !ok {
+		k.warn(expr.Idx0(), "expected a duration string")
+		return 0
+	}
+	d, err := time.ParseDuration(s.Value.String())
+	if err != nil {
+		k.warn(expr.Idx0(), "invalid duration "+strconv.Quote(s.Value.String()))
+		return 0
+	}
+	return int(d.Seconds())
+}
+
+// block converts the statements of the default function or a group
+func (k *k6Converter) block(stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case *ast.ExpressionStatement:
+			k.expression(s.Expression)
+		case *ast.VariableStatement:
+			k.localDeclarations(s.List)
+		case *ast.LexicalDeclaration:
+			k.localDeclarations(s.List)
+		case *ast.EmptyStatement:
+		default:
+			k.warn(stmtIdx(stmt), "statement not converted; only straight-line code is supported")
+		}
+	}
+}
+
+// stmtIdx is the start of a statement. goja leaves the position of an
+// if statement unset, so it falls back to its condition.
+func stmtIdx(stmt ast.Statement) file.Idx {
+	if s, ok := stmt.(*ast.IfStatement); ok && s.If == 0 {
+		return s.Test.Idx0()
+	}
+	return stmt.Idx0()
+}
+
+func (k *k6Converter) localDeclarations(list []*ast.Binding) {
+	for _, b := range list {
+		id, ok := b.Target.(*ast.Identifier)
+		if !ok || b.Initializer == nil {
+			k.warn(b.Idx0(), "declaration not converted")
+			continue
+		}
+		name := id.Name.String()
+
+		if call, ok := b.Initializer.(*ast.CallExpression); ok && k.isHTTP(call) {
+			if index, ok := k.request(call); ok {
+				k.responses[name] = index
+			}
+			continue
+		}
+		if k.extraction(name, b.Initializer) {
+			continue
+		}
+		if v, ok := k.str(b.Initializer); ok {
+			k.consts[name] = v
+			continue
+		}
+		k.warn(b.Idx0(), name+" not converted")
+	}
+}
+
+func (k *k6Converter) expression(expr ast.Expression) {
+	call, ok := expr.(*ast.CallExpression)
+	if !ok {
+		k.warn(expr.Idx0(), "expression not converted")
+		return
+	}
+
+	if k.isHTTP(call) {
+		k.request(call)
+		return
+	}
+
+	if id, ok := call.Callee.(*ast.Identifier); ok {
+		switch k.k6Names[id.Name.String()] {
+		case "sleep":
+			k.sleep(call)
+			return
+		case "check":
+			k.check(call)
+			return
+		case "group":
+			k.groupCall(call)
+			return
+		}
+	}
+	k.warn(expr.Idx0(), "call not converted")
+}
+
+func (k *k6Converter) sleep(call *ast.CallExpression) {
+	if len(call.ArgumentList) == 1 {
+		if n, ok := call.ArgumentList[0].(*ast.NumberLiteral); ok {
+			var secs float64
+			switch v := n.Value.(type) {
+			case int64:
+				secs = float64(v)
+			case float64:
+				secs = v
+			}
+			k.pendingSleep += int(secs * 1000)
+			return
+		}
+	}
+	k.warn(call.Idx0(), "sleep() with a computed duration not converted")
+}
+
+func (k *k6Converter) groupCall(call *ast.CallExpression) {
+	if len(call.ArgumentList) < 2 {
+		k.warn(call.Idx0(), "group() not converted")
+		return
+	}
+	name, ok := k.str(call.ArgumentList[0])
+	if !ok {
+		k.warn(call.Idx0(), "group() with a computed name not converted")
+		return
+	}
+
+	var body []ast.Statement
+	switch fn := call.ArgumentList[1].(type) {
+	case *ast.FunctionLiteral:
+		body = fn.Body.List
+	case *ast.ArrowFunctionLiteral:
+		if b, ok := fn.Body.(*ast.BlockStatement); ok {
+			body = b.List
+		}
+	}
+	if body == nil {
+		k.warn(call.Idx0(), "group() body not converted")
+		return
+	}
+
+	k.group = append(k.group, name)
+	k.block(body)
+	k.group = k.group[:len(k.group)-1]
+}
+
+func (k *k6Converter) isHTTP(call *ast.CallExpression) bool {
+	dot, ok := call.Callee.(*ast.DotExpression)
+	if !ok {
+		return false
+	}
+	id, ok := dot.Left.(*ast.Identifier)
+	return ok && k.httpNames[id.Name.String()]
+}
+
+// request converts an http.* call into a step and returns its index
+func (k *k6Converter) request(call *ast.CallExpression) (int, bool) {
+	method := call.Callee.(*ast.DotExpression).Identifier.Name.String()
+	args := call.ArgumentList
+	arg := func(i int) ast.Expression {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	step := model.Step{Type: model.HTTP}
+	var urlExpr, bodyExpr, paramsExpr ast.Expression
+
+	switch method {
+	case "get", "head":
+		step.Method = strings.ToUpper(method)
+		urlExpr, paramsExpr = arg(0), arg(1)
+	case "post", "put", "patch", "del", "options":
+		step.Method = strings.ToUpper(method)
+		if method == "del" {
+			step.Method = "DELETE"
+		}
+		urlExpr, bodyExpr, paramsExpr = arg(0), arg(1), arg(2)
+	case "request":
+		m, ok := k.str(arg(0))
+		if !ok {
+			k.warn(call.Idx0(), "http.request() with a computed method not converted")
+			return 0, false
+		}
+		step.Method = strings.ToUpper(m)
+		urlExpr, bodyExpr, paramsExpr = arg(1), arg(2), arg(3)
+	case "batch":
+		return k.batch(call)
+	default:
+		k.warn(call.Idx0(), "http."+method+"() not converted")
+		return 0, false
+	}
+
+	if !k.fillRequest(&step, urlExpr, bodyExpr, paramsExpr) {
+		k.warn(call.Idx0(), "http."+method+"() with a computed URL not converted")
+		return 0, false
+	}
+	return k.addStep(step), true
+}
+
+// fillRequest resolves the URL, body and params of a request
+func (k *k6Converter) fillRequest(step *model.Step, urlExpr, bodyExpr, paramsExpr ast.Expression) bool {
+	u, ok := k.str(urlExpr)
+	if !ok {
+		return false
+	}
+	step.URL = u
+
+	if bodyExpr != nil {
+		k.body(step, bodyExpr)
+	}
+	if paramsExpr != nil {
+		k.params(step, paramsExpr)
+	}
+	return true
+}
+
+func (k *k6Converter) addStep(step model.Step) int {
+	step.Group = strings.Join(k.group, "::")
+	step.ThinkTimeMs = k.pendingSleep
+	k.pendingSleep = 0
+
+	k.script.Steps = append(k.script.Steps, step)
+	return len(k.script.Steps) - 1
+}
+
+func (k *k6Converter) body(step *model.Step, expr ast.Expression) {
+	switch e := expr.(type) {
+	case *ast.NullLiteral:
+		return
+	case *ast.Identifier:
+		if e.Name.String() == "undefined" {
+			return
+		}
+	case *ast.ObjectLiteral:
+		// Like k6, object bodies are sent as a form
+		obj, ok := k.jsonValue(e)
+		if !ok {
+			k.warn(expr.Idx0(), "request body not converted")
+			return
+		}
+		form := url.Values{}
+		for key, v := range obj.(map[string]interface{}) {
+			form.Set(key, fmt.Sprint(v))
+		}
+		step.Body = form.Encode()
+		k.header(step, "Content-Type", "application/x-www-form-urlencoded")
+		return
+	case *ast.CallExpression:
+		// JSON.stringify({...})
+		if isCall(e, "JSON", "stringify") && len(e.ArgumentList) == 1 {
+			if v, ok := k.jsonValue(e.ArgumentList[0]); ok {
+				b, _ := json.Marshal(v)
+				step.Body = string(b)
+				return
+			}
+		}
+	}
+
+	if s, ok := k.str(expr); ok {
+		step.Body = s
+		return
+	}
+	k.warn(expr.Idx0(), "request body not converted")
+}
+
+func (k *k6Converter) header(step *model.Step, name, value string) {
+	if step.Header == nil {
+		step.Header = make(map[string]string)
+	}
+	setHeaderDefault(step.Header, name, value)
+}
+
+func (k *k6Converter) params(step *model.Step, expr ast.Expression) {
+	obj, ok := expr.(*ast.ObjectLiteral)
+	if !ok {
+		if _, isNull := expr.(*ast.NullLiteral); !isNull {
+			k.warn(expr.Idx0(), "request params not converted")
+		}
+		return
+	}
+
+	for _, prop := range obj.Value {
+		key, value, ok := keyed(prop)
+		if !ok {
+			k.warn(prop.Idx0(), "request param not converted")
+			continue
+		}
+		switch key {
+		case "headers":
+			headers, ok := value.(*ast.ObjectLiteral)
+			if !ok {
+				k.warn(value.Idx0(), "headers not converted")
+				continue
+			}
+			for _, h := range headers.Value {
+				name, hv, ok := keyed(h)
+				if !ok {
+					k.warn(h.Idx0(), "header not converted")
+					continue
+				}
+				s, ok := k.str(hv)
+				if !ok {
+					k.warn(h.Idx0(), "header "+name+" not converted")
+					continue
+				}
+				if step.Header == nil {
+					step.Header = make(map[string]string)
+				}
+				step.Header[name] = s
+			}
+		default:
+			k.warn(prop.Idx0(), "request param "+key+" not converted")
+		}
+	}
+}
+
+// batch converts http.batch([...]) with static requests into a BATCH
+// step
+func (k *k6Converter) batch(call *ast.CallExpression) (int, bool) {
+	arr, ok := firstArg(call).(*ast.ArrayLiteral)
+	if !ok {
+		k.warn(call.Idx0(), "http.batch() without an array literal not converted")
+		return 0, false
+	}
+
+	batch := &model.BatchStep{}
+	for _, item := range arr.Value {
+		req := model.Step{Type: model.HTTP, Method: "GET"}
+		var urlExpr, bodyExpr, paramsExpr ast.Expression
+
+		switch e := item.(type) {
+		case *ast.ArrayLiteral:
+			if len(e.Value) < 2 {
+				k.warn(item.Idx0(), "batch request not converted")
+				continue
+			}
+			m, ok := k.str(e.Value[0])
+			if !ok {
+				k.warn(item.Idx0(), "batch request with a computed method not converted")
+				continue
+			}
+			req.Method = strings.ToUpper(m)
+			urlExpr = e.Value[1]
+			if len(e.Value) > 2 {
+				bodyExpr = e.Value[2]
+			}
+			if len(e.Value) > 3 {
+				paramsExpr = e.Value[3]
+			}
+		case *ast.ObjectLiteral:
+			for _, prop := range e.Value {
+				key, value, ok := keyed(prop)
+				switch {
+				case !ok:
+				case key == "method":
+					if m, ok := k.str(value); ok {
+						req.Method = strings.ToUpper(m)
+					}
+				case key == "url":
+					urlExpr = value
+				case key == "body":
+					bodyExpr = value
+				case key == "params":
+					paramsExpr = value
+				}
+			}
+		default:
+			urlExpr = item
+		}
+
+		if !k.fillRequest(&req, urlExpr, bodyExpr, paramsExpr) {
+			k.warn(item.Idx0(), "batch request with a computed URL not converted")
+			continue
+		}
+		batch.Requests = append(batch.Requests, req)
+	}
+
+	if len(batch.Requests) == 0 {
+		return 0, false
+	}
+	return k.addStep(model.Step{Type: model.Batch, Batch: batch}), true
+}
+
+// extraction turns `const x = res.json("a.b")` and
+// `const x = res.body.match(/re/)[1]` into an extraction on res's step
+func (k *k6Converter) extraction(name string, expr ast.Expression) bool {
+	res, rule, ok := k.responseRule(expr)
+	if !ok {
+		return false
+	}
+	index, known := k.responses[res]
+	if !known {
+		return false
+	}
+
+	step := &k.script.Steps[index]
+	step.Extract = append(step.Extract, model.Extraction{Var: name, Type: rule.Type, Expr: rule.Expr})
+	k.consts[name] = "{{" + name + "}}"
+	return true
+}
+
+// responseRule recognizes the ways scripts read values from a response
+// variable: res.json("a.b"), res.json().a.b and res.body.match(/re/)[n]
+func (k *k6Converter) responseRule(expr ast.Expression) (string, model.Check, bool) {
+	var path []string
+	for {
+		switch e := expr.(type) {
+		case *ast.DotExpression:
+			path = append([]string{"." + e.Identifier.Name.String()}, path...)
+			expr = e.Left
+			continue
+		case *ast.BracketExpression:
+			n, ok := e.Member.(*ast.NumberLiteral)
+			if !ok {
+				return "", model.Check{}, false
+			}
+			path = append([]string{"[" + n.Literal + "]"}, path...)
+			expr = e.Left
+			continue
+		case *ast.CallExpression:
+			dot, ok := e.Callee.(*ast.DotExpression)
+			if !ok {
+				return "", model.Check{}, false
+			}
+			switch dot.Identifier.Name.String() {
+			case "json":
+				res, ok := dot.Left.(*ast.Identifier)
+				if !ok {
+					return "", model.Check{}, false
+				}
+				sel := ""
+				if len(e.ArgumentList) > 0 {
+					s, ok := e.ArgumentList[0].(*ast.StringLiteral)
+					if !ok {
+						return "", model.Check{}, false
+					}
+					sel = selectorPath(s.Value.String())
+				}
+				return res.Name.String(), model.Check{
+					Type: model.JSONPathRule,
+					Expr: "$" + sel + strings.Join(path, ""),
+				}, true
+			case "match":
+				// res.body.match(/re/)[1]
+				body, ok := dot.Left.(*ast.DotExpression)
+				if !ok || body.Identifier.Name.String() != "body" || len(e.ArgumentList) != 1 {
+					return "", model.Check{}, false
+				}
+				res, ok := body.Left.(*ast.Identifier)
+				re, isRe := e.ArgumentList[0].(*ast.RegExpLiteral)
+				if !ok || !isRe || len(path) != 1 || path[0] != "[1]" {
+					return "", model.Check{}, false
+				}
+				// With g, match() returns every whole match instead of groups
+				pattern, ok := k.regex(re, "ims")
+				if !ok {
+					return "", model.Check{}, false
+				}
+				return res.Name.String(), model.Check{Type: model.RegexRule, Expr: pattern}, true
+			}
+		}
+		return "", model.Check{}, false
+	}
+}
+
+// selectorPath turns a k6 JSON selector such as "data.items.0.id" or
+// "data.items[0].id" into a JSONPath suffix
+func selectorPath(sel string) string {
+	var b strings.Builder
+	sel = strings.NewReplacer("[", ".", "]", "").Replace(sel)
+	for _, part := range strings.Split(sel, ".") {
+		if part == "" {
+			continue
+		}
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+		} else {
+			b.WriteString("." + part)
+		}
+	}
+	return b.String()
+}
+
+// check converts check(res, {...}). Status checks are covered by the
+// engine counting 4xx and 5xx responses as failures; body and JSON
+// checks become step checks.
+func (k *k6Converter) check(call *ast.CallExpression) {
+	if len(call.ArgumentList) < 2 {
+		k.warn(call.Idx0(), "check() not converted")
+		return
+	}
+	res, ok := call.ArgumentList[0].(*ast.Identifier)
+	index, known := 0, false
+	if ok {
+		index, known = k.responses[res.Name.String()]
+	}
+	obj, isObj := call.ArgumentList[1].(*ast.ObjectLiteral)
+	if !known || !isObj {
+		k.warn(call.Idx0(), "check() on something other than a response not converted")
+		return
+	}
+
+	step := &k.script.Steps[index]
+	for _, prop := range obj.Value {
+		name, value, ok := keyed(prop)
+		if !ok {
+			k.warn(prop.Idx0(), "check not converted")
+			continue
+		}
+		fn, ok := value.(*ast.ArrowFunctionLiteral)
+		var body ast.Expression
+		if ok {
+			if eb, isExpr := fn.Body.(*ast.ExpressionBody); isExpr {
+				body = eb.Expression
+			}
+		}
+		if body == nil || len(fn.ParameterList.List) != 1 {
+			k.warn(prop.Idx0(), "check "+strconv.Quote(name)+" not converted")
+			continue
+		}
+		param, _ := fn.ParameterList.List[0].Target.(*ast.Identifier)
+		if param == nil {
+			k.warn(prop.Idx0(), "check "+strconv.Quote(name)+" not converted")
+			continue
+		}
+
+		checks, ok := k.condition(param.Name.String(), body)
+		if !ok {
+			k.warn(prop.Idx0(), "check "+strconv.Quote(name)+" not converted")
+			continue
+		}
+		step.Checks = append(step.Checks, checks...)
+	}
+}
+
+// condition converts a check function's body. ok with no checks means
+// the condition is a status check the engine already applies.
+func (k *k6Converter) condition(param string, expr ast.Expression) ([]model.Check, bool) {
+	switch e := expr.(type) {
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case token.LOGICAL_AND:
+			left, ok := k.condition(param, e.Left)
+			if !ok {
+				return nil, false
+			}
+			right, ok := k.condition(param, e.Right)
+			return append(left, right...), ok
+		case token.STRICT_EQUAL, token.EQUAL, token.LESS, token.LESS_OR_EQUAL,
+			token.GREATER, token.GREATER_OR_EQUAL, token.STRICT_NOT_EQUAL, token.NOT_EQUAL:
+			if isStatus(param, e.Left) {
+				return nil, successStatus(e.Operator, e.Right)
+			}
+			// r.body.indexOf("x") !== -1
+			if call, ok := e.Left.(*ast.CallExpression); ok && isBodyCall(param, call, "indexOf") {
+				if n, ok := e.Right.(*ast.UnaryExpression); ok && n.Operator == token.MINUS &&
+					(e.Operator == token.STRICT_NOT_EQUAL || e.Operator == token.NOT_EQUAL || e.Operator == token.GREATER) {
+					return k.bodyContains(call)
+				}
+				return nil, false
+			}
+			// r.json("a") === "v"
+			if e.Operator == token.STRICT_EQUAL || e.Operator == token.EQUAL {
+				res, rule, ok := k.responseRule(e.Left)
+				if !ok || res != param {
+					return nil, false
+				}
+				want, ok := k.literal(e.Right)
+				if !ok {
+					return nil, false
+				}
+				rule.Equals = want
+				return []model.Check{rule}, true
+			}
+		}
+	case *ast.CallExpression:
+		// r.body.includes("x")
+		if isBodyCall(param, e, "includes") {
+			return k.bodyContains(e)
+		}
+		// /re/.test(r.body)
+		if dot, ok := e.Callee.(*ast.DotExpression); ok && dot.Identifier.Name.String() == "test" && len(e.ArgumentList) == 1 {
+			if re, ok := dot.Left.(*ast.RegExpLiteral); ok && isBody(param, e.ArgumentList[0]) {
+				pattern, ok := k.regex(re, "gims")
+				if !ok {
+					return nil, false
+				}
+				return []model.Check{{Type: model.RegexRule, Expr: pattern}}, true
+			}
+		}
+		// r.json("a") is truthy
+		if res, rule, ok := k.responseRule(e); ok && res == param {
+			return []model.Check{rule}, true
+		}
+	case *ast.DotExpression, *ast.BracketExpression:
+		if res, rule, ok := k.responseRule(e); ok && res == param {
+			return []model.Check{rule}, true
+		}
+	}
+	return nil, false
+}
+
+func (k *k6Converter) bodyContains(call *ast.CallExpression) ([]model.Check, bool) {
+	s, ok := call.ArgumentList[0].(*ast.StringLiteral)
+	if !ok {
+		return nil, false
+	}
+	return []model.Check{{Type: model.RegexRule, Expr: regexp.QuoteMeta(s.Value.String())}}, true
+}
+
+// regex turns a JavaScript regular expression literal into an RE2
+// pattern. The i, m and s flags become inline flags; other flags, unless
+// allowed, and syntax RE2 lacks, such as lookarounds and backreferences,
+// are warned about.
+func (k *k6Converter) regex(re *ast.RegExpLiteral, allowed string) (string, bool) {
+	inline := ""
+	for _, f := range re.Flags {
+		if !strings.ContainsRune(allowed, f) {
+			k.warn(re.Idx0(), "regular expression flag "+string(f)+" not supported")
+			return "", false
+		}
+		if strings.ContainsRune("ims", f) {
+			inline += string(f)
+		}
+	}
+
+	pattern := re.Pattern
+	if inline != "" {
+		pattern = "(?" + inline + ")" + pattern
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		k.warn(re.Idx0(), "regular expression /"+re.Pattern+"/ not supported: "+strings.TrimPrefix(err.Error(), "error parsing regexp: "))
+		return "", false
+	}
+	return pattern, true
+}
+
+// literal renders a compared value the way extraction stores it
+func (k *k6Converter) literal(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.StringLiteral:
+		return e.Value.String(), true
+	case *ast.NumberLiteral:
+		return e.Literal, true
+	case *ast.BooleanLiteral:
+		return strconv.FormatBool(e.Value), true
+	}
+	return "", false
+}
+
+func isStatus(param string, expr ast.Expression) bool {
+	dot, ok := expr.(*ast.DotExpression)
+	if !ok || dot.Identifier.Name.String() != "status" {
+		return false
+	}
+	id, ok := dot.Left.(*ast.Identifier)
+	return ok && id.Name.String() == param
+}
+
+// successStatus reports whether a status comparison accepts only
+// statuses below 400, which is what the engine treats as success
+func successStatus(op token.Token, expr ast.Expression) bool {
+	n, ok := expr.(*ast.NumberLiteral)
+	if !ok {
+		return false
+	}
+	v, err := strconv.Atoi(n.Literal)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case token.STRICT_EQUAL, token.EQUAL:
+		return v >= 100 && v < 400
+	case token.LESS:
+		return v <= 400
+	case token.LESS_OR_EQUAL:
+		return v < 400
+	case token.GREATER, token.GREATER_OR_EQUAL:
+		// the lower bound of a range such as r.status >= 200
+		return v < 400
+	}
+	return false
+}
+
+func isBody(param string, expr ast.Expression) bool {
+	dot, ok := expr.(*ast.DotExpression)
+	if !ok || dot.Identifier.Name.String() != "body" {
+		return false
+	}
+	id, ok := dot.Left.(*ast.Identifier)
+	return ok && id.Name.String() == param
+}
+
+func isBodyCall(param string, call *ast.CallExpression, method string) bool {
+	dot, ok := call.Callee.(*ast.DotExpression)
+	return ok && dot.Identifier.Name.String() == method && len(call.ArgumentList) == 1 && isBody(param, dot.Left)
+}
+
+func isCall(call *ast.CallExpression, object, method string) bool {
+	dot, ok := call.Callee.(*ast.DotExpression)
+	if !ok || dot.Identifier.Name.String() != method {
+		return false
+	}
+	id, ok := dot.Left.(*ast.Identifier)
+	return ok && id.Name.String() == object
+}
+
+func firstArg(call *ast.CallExpression) ast.Expression {
+	if len(call.ArgumentList) == 0 {
+		return nil
+	}
+	return call.ArgumentList[0]
+}
+
+// keyed returns the name and value of a plain object property
+func keyed(prop ast.Property) (string, ast.Expression, bool) {
+	p, ok := prop.(*ast.PropertyKeyed)
+	if !ok || p.Computed {
+		return "", nil, false
+	}
+	switch key := p.Key.(type) {
+	case *ast.StringLiteral:
+		return key.Value.String(), p.Value, true
+	case *ast.Identifier:
+		return key.Name.String(), p.Value, true
+	case *ast.NumberLiteral:
+		return key.Literal, p.Value, true
+	}
+	return "", nil, false
+}
+
+// str resolves an expression to a string with {{var}} placeholders.
+// __ENV.NAME becomes {{NAME}}, seeded from `__ENV.NAME || "default"`.
+func (k *k6Converter) str(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case nil:
+		return "", false
+	case *ast.StringLiteral:
+		return e.Value.String(), true
+	case *ast.NumberLiteral:
+		return e.Literal, true
+	case *ast.TemplateLiteral:
+		if e.Tag != nil {
+			return "", false
+		}
+		var b strings.Builder
+		for i, el := range e.Elements {
+			b.WriteString(el.Parsed.String())
+			if i < len(e.Expressions) {
+				s, ok := k.str(e.Expressions[i])
+				if !ok {
+					return "", false
+				}
+				b.WriteString(s)
+			}
+		}
+		return b.String(), true
+	case *ast.BinaryExpression:
+		switch e.Operator {
+		case token.PLUS:
+			left, ok := k.str(e.Left)
+			if !ok {
+				return "", false
+			}
+			right, ok := k.str(e.Right)
+			return left + right, ok
+		case token.LOGICAL_OR, token.COALESCE:
+			name, ok := envName(e.Left)
+			if !ok {
+				return "", false
+			}
+			if def, ok := k.str(e.Right); ok {
+				if _, set := k.script.Variables[name]; !set {
+					k.script.Variables[name] = def
+				}
+			}
+			return "{{" + name + "}}", true
+		}
+	case *ast.Identifier:
+		v, ok := k.consts[e.Name.String()]
+		return v, ok
+	case *ast.DotExpression, *ast.BracketExpression:
+		if name, ok := envName(e); ok {
+			return "{{" + name + "}}", true
+		}
+	}
+	return "", false
+}
+
+// envName reads __ENV.NAME and __ENV["NAME"]
+func envName(expr ast.Expression) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.DotExpression:
+		if id, ok := e.Left.(*ast.Identifier); ok && id.Name.String() == "__ENV" {
+			return e.Identifier.Name.String(), true
+		}
+	case *ast.BracketExpression:
+		id, ok := e.Left.(*ast.Identifier)
+		s, isStr := e.Member.(*ast.StringLiteral)
+		if ok && isStr && id.Name.String() == "__ENV" {
+			return s.Value.String(), true
+		}
+	}
+	return "", false
+}
+
+// jsonValue evaluates a literal object, array or scalar
+func (k *k6Converter) jsonValue(expr ast.Expression) (interface{}, bool) {
+	switch e := expr.(type) {
+	case *ast.ObjectLiteral:
+		obj := make(map[string]interface{}, len(e.Value))
+		for _, prop := range e.Value {
+			key, value, ok := keyed(prop)
+			if !ok {
+				return nil, false
+			}
+			v, ok := k.jsonValue(value)
+			if !ok {
+				return nil, false
+			}
+			obj[key] = v
+		}
+		return obj, true
+	case *ast.ArrayLiteral:
+		arr := make([]interface{}, 0, len(e.Value))
+		for _, item := range e.Value {
+			v, ok := k.jsonValue(item)
+			if !ok {
+				return nil, false
+			}
+			arr = append(arr, v)
+		}
+		return arr, true
+	case *ast.NumberLiteral:
+		return e.Value, true
+	case *ast.BooleanLiteral:
+		return e.Value, true
+	case *ast.NullLiteral:
+		return nil, true
+	}
+	return k.str(expr)
+}
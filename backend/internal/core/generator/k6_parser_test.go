@@ -0,0 +1,358 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"k6clone/internal/core/model"
+)
+
+const k6Imports = `import http from 'k6/http';
+import { check, group, sleep } from 'k6';
+`
+
+func TestK6ParserOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  string
+		config   model.TestConfig
+		scenario *model.Scenario
+		warnings []string
+	}{
+		{
+			name:     "vus and duration",
+			options:  `{ vus: 10, duration: '30s' }`,
+			config:   model.TestConfig{VUs: 10, Duration: 30},
+			scenario: &model.Scenario{VUs: 10, Duration: 30},
+		},
+		{
+			name:     "iterations",
+			options:  `{ vus: 2, iterations: 5 }`,
+			config:   model.TestConfig{VUs: 2},
+			scenario: &model.Scenario{VUs: 2, Iterations: 5},
+			warnings: []string{"line 3: iterations are counted per VU"},
+		},
+		{
+			name:     "stages",
+			options:  `{ stages: [{ duration: '10s', target: 5 }, { duration: '1m', target: 20 }, { duration: '10s', target: 0 }] }`,
+			config:   model.TestConfig{VUs: 20, Duration: 80},
+			scenario: &model.Scenario{VUs: 20, Duration: 80, RampUpSeconds: 10},
+			warnings: []string{"line 3: stages approximated as a ramp-up to the peak VUs"},
+		},
+		{
+			name:     "thresholds",
+			options:  `{ vus: 1, duration: '1s', thresholds: { http_req_duration: ['p(95)<500'] } }`,
+			config:   model.TestConfig{VUs: 1, Duration: 1},
+			scenario: &model.Scenario{VUs: 1, Duration: 1},
+			warnings: []string{"line 3: thresholds only apply to k6 JavaScript scripts; not converted"},
+		},
+		{
+			name:     "unknown option and bad duration",
+			options:  `{ vus: 1, duration: 'soon', noConnectionReuse: true }`,
+			config:   model.TestConfig{VUs: 1},
+			scenario: &model.Scenario{VUs: 1},
+			warnings: []string{
+				`line 3: invalid duration "soon"`,
+				"line 3: option noConnectionReuse not converted",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := k6Imports + "export const options = " + tt.options + ";\n" +
+				"export default function () {\n  http.get('http://test.k6.io/');\n}\n"
+			imp := parseK6(t, src)
+
+			tt.config.ScriptID = imp.Script.ID
+			if imp.Config != tt.config {
+				t.Errorf("config = %+v, want %+v", imp.Config, tt.config)
+			}
+			if !reflect.DeepEqual(imp.Script.Scenario, tt.scenario) {
+				t.Errorf("scenario = %+v, want %+v", imp.Script.Scenario, tt.scenario)
+			}
+			assertWarnings(t, imp, tt.warnings)
+		})
+	}
+}
+
+func TestK6ParserRequests(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		steps []model.Step
+	}{
+		{
+			name: "get with headers",
+			body: `http.get('http://api.test/users', { headers: { Authorization: 'Bearer abc' } });`,
+			steps: []model.Step{{
+				Type: model.HTTP, Method: "GET", URL: "http://api.test/users",
+				Header: map[string]string{"Authorization": "Bearer abc"},
+			}},
+		},
+		{
+			name: "post JSON and form bodies",
+			body: `http.post('http://api.test/users', JSON.stringify({ name: 'a' }), { headers: { 'Content-Type': 'application/json' } });
+  http.put('http://api.test/login', { user: 'a' });`,
+			steps: []model.Step{
+				{
+					Type: model.HTTP, Method: "POST", URL: "http://api.test/users", Body: `{"name":"a"}`,
+					Header: map[string]string{"Content-Type": "application/json"},
+				},
+				{
+					Type: model.HTTP, Method: "PUT", URL: "http://api.test/login", Body: "user=a",
+					Header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				},
+			},
+		},
+		{
+			name: "del, request and constants",
+			body: `const base = 'http://api.test';
+  http.del(base + '/users/1');
+  http.request('PATCH', ` + "`${base}/users/2`" + `, 'x');`,
+			steps: []model.Step{
+				{Type: model.HTTP, Method: "DELETE", URL: "http://api.test/users/1"},
+				{Type: model.HTTP, Method: "PATCH", URL: "http://api.test/users/2", Body: "x"},
+			},
+		},
+		{
+			name: "groups and sleep",
+			body: `group('login', function () {
+    http.get('http://api.test/a');
+    sleep(0.5);
+  });
+  http.get('http://api.test/b');`,
+			steps: []model.Step{
+				{Type: model.HTTP, Method: "GET", URL: "http://api.test/a", Group: "login"},
+				{Type: model.HTTP, Method: "GET", URL: "http://api.test/b", ThinkTimeMs: 500},
+			},
+		},
+		{
+			name: "batch",
+			body: `http.batch([['GET', 'http://api.test/a'], ['POST', 'http://api.test/b', 'x']]);`,
+			steps: []model.Step{{
+				Type: model.Batch,
+				Batch: &model.BatchStep{Requests: []model.Step{
+					{Type: model.HTTP, Method: "GET", URL: "http://api.test/a"},
+					{Type: model.HTTP, Method: "POST", URL: "http://api.test/b", Body: "x"},
+				}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := parseK6(t, k6Imports+"export default function () {\n  "+tt.body+"\n}\n")
+			if !reflect.DeepEqual(imp.Script.Steps, tt.steps) {
+				t.Errorf("steps =\n%+v\nwant\n%+v", imp.Script.Steps, tt.steps)
+			}
+			assertWarnings(t, imp, nil)
+		})
+	}
+}
+
+func TestK6ParserChecksAndExtractions(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		checks   []model.Check
+		extract  []model.Extraction
+		warnings []string
+	}{
+		{
+			name: "status checks are implied",
+			body: `check(res, { 'ok': (r) => r.status === 200 });`,
+		},
+		{
+			name: "body and JSON checks",
+			body: `check(res, {
+    'has name': (r) => r.body.includes('name'),
+    'id is 1': (r) => r.json('data.id') === 1,
+    'indexOf': (r) => r.body.indexOf('x') !== -1,
+  });`,
+			checks: []model.Check{
+				{Type: model.RegexRule, Expr: "name"},
+				{Type: model.JSONPathRule, Expr: "$.data.id", Equals: "1"},
+				{Type: model.RegexRule, Expr: "x"},
+			},
+		},
+		{
+			name: "extractions",
+			body: `const token = res.json('auth.token');
+  const first = res.json().items[0].id;
+  const csrf = res.body.match(/csrf=(\w+)/)[1];
+  http.get('http://api.test/me?t=' + token);`,
+			extract: []model.Extraction{
+				{Var: "token", Type: model.JSONPathRule, Expr: "$.auth.token"},
+				{Var: "first", Type: model.JSONPathRule, Expr: "$.items[0].id"},
+				{Var: "csrf", Type: model.RegexRule, Expr: `csrf=(\w+)`},
+			},
+		},
+		{
+			name: "regex flags",
+			body: `const id = res.body.match(/ID=(\d+)/i)[1];
+  check(res, {
+    'multiline': (r) => /^ok$/m.test(r.body),
+    'global': (r) => /ok/g.test(r.body),
+  });`,
+			checks: []model.Check{
+				{Type: model.RegexRule, Expr: "(?m)^ok$"},
+				{Type: model.RegexRule, Expr: "ok"},
+			},
+			extract: []model.Extraction{{Var: "id", Type: model.RegexRule, Expr: `(?i)ID=(\d+)`}},
+		},
+		{
+			name: "unsupported regexes",
+			body: `const a = res.body.match(/a(?=b)(c)/)[1];
+  const b = res.body.match(/id=(\d+)/g)[1];
+  check(res, {
+    'backref': (r) => /(a)\1/.test(r.body),
+    'sticky': (r) => /x/y.test(r.body),
+  });`,
+			warnings: []string{
+				"line 5: regular expression /a(?=b)(c)/ not supported: invalid or unsupported Perl syntax: `(?=`",
+				"line 5: a not converted",
+				"line 6: regular expression flag g not supported",
+				"line 6: b not converted",
+				"line 8: regular expression /(a)\\1/ not supported: invalid escape sequence: `\\1`",
+				`line 8: check "backref" not converted`,
+				"line 9: regular expression flag y not supported",
+				`line 9: check "sticky" not converted`,
+			},
+		},
+		{
+			name: "unsupported checks",
+			body: `check(res, {
+    'computed': (r) => r.timings.duration < 200,
+    'block': (r) => { return true; },
+  });
+  check(42, { 'x': () => true });`,
+			warnings: []string{
+				`line 6: check "computed" not converted`,
+				`line 7: check "block" not converted`,
+				"line 9: check() on something other than a response not converted",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := k6Imports + "export default function () {\n  const res = http.get('http://api.test/');\n  " + tt.body + "\n}\n"
+			imp := parseK6(t, src)
+
+			step := imp.Script.Steps[0]
+			if !reflect.DeepEqual(step.Checks, tt.checks) {
+				t.Errorf("checks = %+v, want %+v", step.Checks, tt.checks)
+			}
+			if !reflect.DeepEqual(step.Extract, tt.extract) {
+				t.Errorf("extract = %+v, want %+v", step.Extract, tt.extract)
+			}
+			assertWarnings(t, imp, tt.warnings)
+		})
+	}
+}
+
+func TestK6ParserExtractedValuesBecomePlaceholders(t *testing.T) {
+	imp := parseK6(t, k6Imports+`export default function () {
+  const res = http.post('http://api.test/login', JSON.stringify({ user: 'a' }));
+  const token = res.json('token');
+  http.get('http://api.test/me', { headers: { Authorization: 'Bearer ' + token } });
+}
+`)
+
+	if got := imp.Script.Steps[1].Header["Authorization"]; got != "Bearer {{token}}" {
+		t.Errorf("Authorization = %q, want Bearer {{token}}", got)
+	}
+}
+
+func TestK6ParserEnvironmentVariables(t *testing.T) {
+	imp := parseK6(t, k6Imports+`export default function () {
+  http.get(__ENV.BASE_URL + '/users');
+  http.get((__ENV.HOST || 'http://localhost') + '/health');
+}
+`)
+
+	if got := imp.Script.Steps[0].URL; got != "{{BASE_URL}}/users" {
+		t.Errorf("URL = %q, want {{BASE_URL}}/users", got)
+	}
+	if got := imp.Script.Steps[1].URL; got != "{{HOST}}/health" {
+		t.Errorf("URL = %q, want {{HOST}}/health", got)
+	}
+	if got := imp.Script.Variables["HOST"]; got != "http://localhost" {
+		t.Errorf("HOST = %q, want the default http://localhost", got)
+	}
+}
+
+func TestK6ParserWarnings(t *testing.T) {
+	imp := parseK6(t, `import http from 'k6/http';
+import { sleep } from 'k6';
+import ws from 'k6/ws';
+
+function helper() {}
+
+export default function () {
+  for (let i = 0; i < 3; i++) {
+    http.get('http://api.test/loop');
+  }
+  http.get(pick());
+  http.get('http://api.test/');
+  console.log('hi');
+  sleep(Math.random());
+  sleep(2);
+}
+`)
+
+	if len(imp.Script.Steps) != 1 || imp.Script.Steps[0].URL != "http://api.test/" {
+		t.Errorf("steps = %+v, want only the static GET", imp.Script.Steps)
+	}
+	assertWarnings(t, imp, []string{
+		"module k6/ws not supported; calls to it are not converted",
+		"line 5: function helper not converted",
+		"line 8: statement not converted; only straight-line code is supported",
+		"line 11: http.get() with a computed URL not converted",
+		"line 13: call not converted",
+		"line 14: sleep() with a computed duration not converted",
+		"sleep(2) at the end of the iteration dropped",
+	})
+}
+
+func TestK6ParserErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"syntax error", "export default function () {", "invalid k6 script"},
+		{"no default function", k6Imports + "http.get('http://x/');", "no default function"},
+		{"no requests", k6Imports + "export default function () { sleep(1); }", "no HTTP requests"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewK6Parser().Parse(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func parseK6(t *testing.T, source string) *K6Import {
+	t.Helper()
+	imp, err := NewK6Parser().Parse(source)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return imp
+}
+
+func assertWarnings(t *testing.T, imp *K6Import, want []string) {
+	t.Helper()
+	if want == nil {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(imp.Warnings, want) {
+		t.Errorf("warnings =\n  %s\nwant\n  %s", strings.Join(imp.Warnings, "\n  "), strings.Join(want, "\n  "))
+	}
+}
@@ -0,0 +1,635 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"k6clone/internal/core/model"
+)
+
+// maxSampleDepth keeps generated example bodies small for deeply
+// nested or recursive schemas
+const maxSampleDepth = 5
+
+// defaultBaseURL is used when the document has no absolute server URL;
+// it is stored as the baseUrl variable so it is easy to change
+const defaultBaseURL = "http://localhost"
+
+var (
+	openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	pathParam      = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// OpenAPIGenerator builds a script with one HTTP step per operation of
+// an OpenAPI 3 document
+type OpenAPIGenerator struct{}
+
+// OpenAPIOptions select the operations to include. With neither set,
+// every operation is included; otherwise an operation is included if
+// it matches either list.
+type OpenAPIOptions struct {
+	// Tags includes operations carrying any of these tags
+	Tags []string `json:"tags"`
+
+	// Operations includes operations by operationId or "METHOD /path"
+	Operations []string `json:"operations"`
+}
+
+func NewOpenAPIGenerator() *OpenAPIGenerator {
+	return &OpenAPIGenerator{}
+}
+
+// Generate reads a local OpenAPI document, JSON or YAML
+func (g *OpenAPIGenerator) Generate(path string) (*model.Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	script, _, err := g.FromOpenAPI(data, OpenAPIOptions{})
+	return script, err
+}
+
+type oaSpec struct {
+	OpenAPI string `json:"openapi"`
+	Swagger string `json:"swagger"`
+	Servers []struct {
+		URL       string `json:"url"`
+		Variables map[string]struct {
+			Default string `json:"default"`
+		} `json:"variables"`
+	} `json:"servers"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Security   []map[string][]string                 `json:"security"`
+	Components struct {
+		Schemas         map[string]*oaSchema         `json:"schemas"`
+		Parameters      map[string]*oaParameter      `json:"parameters"`
+		RequestBodies   map[string]*oaRequestBody    `json:"requestBodies"`
+		Examples        map[string]*oaExample        `json:"examples"`
+		SecuritySchemes map[string]*oaSecurityScheme `json:"securitySchemes"`
+	} `json:"components"`
+
+	// warnings collects what the conversion had to guess
+	warnings []string
+}
+
+func (s *oaSpec) warn(format string, args ...interface{}) {
+	s.warnings = append(s.warnings, fmt.Sprintf(format, args...))
+}
+
+type oaOperation struct {
+	OperationID string                 `json:"operationId"`
+	Tags        []string               `json:"tags"`
+	Parameters  []*oaParameter         `json:"parameters"`
+	RequestBody *oaRequestBody         `json:"requestBody"`
+	Security    *[]map[string][]string `json:"security"` // nil inherits the document's
+}
+
+type oaParameter struct {
+	Ref      string                `json:"$ref"`
+	Name     string                `json:"name"`
+	In       string                `json:"in"`
+	Required bool                  `json:"required"`
+	Schema   *oaSchema             `json:"schema"`
+	Example  interface{}           `json:"example"`
+	Examples map[string]*oaExample `json:"examples"`
+}
+
+type oaRequestBody struct {
+	Ref     string                  `json:"$ref"`
+	Content map[string]*oaMediaType `json:"content"`
+}
+
+type oaMediaType struct {
+	Schema   *oaSchema             `json:"schema"`
+	Example  interface{}           `json:"example"`
+	Examples map[string]*oaExample `json:"examples"`
+}
+
+type oaExample struct {
+	Ref   string      `json:"$ref"`
+	Value interface{} `json:"value"`
+}
+
+type oaSchema struct {
+	Ref        string               `json:"$ref"`
+	Type       interface{}          `json:"type"` // a string, or a list in 3.1
+	Format     string               `json:"format"`
+	Properties map[string]*oaSchema `json:"properties"`
+	Items      *oaSchema            `json:"items"`
+	Example    interface{}          `json:"example"`
+	Examples   []interface{}        `json:"examples"`
+	Default    interface{}          `json:"default"`
+	Enum       []interface{}        `json:"enum"`
+	AllOf      []*oaSchema          `json:"allOf"`
+	OneOf      []*oaSchema          `json:"oneOf"`
+	AnyOf      []*oaSchema          `json:"anyOf"`
+}
+
+type oaSecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme"`
+	Name   string `json:"name"`
+	In     string `json:"in"`
+}
+
+// FromOpenAPI converts an OpenAPI 3 document. Path parameters become
+// {{name}} placeholders seeded from their examples, example bodies come
+// from the document's examples or are built from the schemas, and the
+// security schemes add the matching credentials as placeholders.
+// Parameters without any example are returned as warnings.
+func (g *OpenAPIGenerator) FromOpenAPI(data []byte, opts OpenAPIOptions) (*model.Script, []string, error) {
+	spec, err := parseOpenAPI(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if spec.Swagger != "" {
+		return nil, nil, errors.New("swagger 2.0 documents are not supported; convert to OpenAPI 3 first")
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		return nil, nil, errors.New("not an OpenAPI 3 document")
+	}
+
+	script := &model.Script{
+		ID:        uuid.NewString(),
+		Variables: make(map[string]string),
+	}
+	base := g.baseURL(spec, script)
+
+	paths := make([]string, 0, len(spec.Paths))
+	for p := range spec.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := spec.Paths[p]
+
+		var shared []*oaParameter
+		if raw, ok := item["parameters"]; ok {
+			json.Unmarshal(raw, &shared)
+		}
+
+		for _, method := range openAPIMethods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op oaOperation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, nil, fmt.Errorf("invalid operation %s %s: %v", strings.ToUpper(method), p, err)
+			}
+			if !opts.includes(method, p, &op) {
+				continue
+			}
+
+			script.Steps = append(script.Steps, spec.step(base, method, p, &op, shared, script.Variables))
+		}
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, nil, errors.New("no operations matched")
+	}
+	if len(script.Variables) == 0 {
+		script.Variables = nil
+	}
+	return script, spec.warnings, nil
+}
+
+// parseOpenAPI reads JSON or YAML into the typed document
+func parseOpenAPI(data []byte) (*oaSpec, error) {
+	var spec oaSpec
+	if json.Unmarshal(data, &spec) == nil {
+		return &spec, nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.New("invalid OpenAPI document: " + err.Error())
+	}
+	// YAML allows non-string keys such as unquoted response codes,
+	// which JSON does not
+	converted, err := json.Marshal(stringKeys(doc))
+	if err != nil {
+		return nil, errors.New("invalid OpenAPI document: " + err.Error())
+	}
+	if err := json.Unmarshal(converted, &spec); err != nil {
+		return nil, errors.New("invalid OpenAPI document: " + err.Error())
+	}
+	return &spec, nil
+}
+
+func stringKeys(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = stringKeys(item)
+		}
+		return val
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = stringKeys(item)
+		}
+		return out
+	case []interface{}:
+		for i, item := range val {
+			val[i] = stringKeys(item)
+		}
+	}
+	return v
+}
+
+func (o OpenAPIOptions) includes(method, path string, op *oaOperation) bool {
+	if len(o.Tags) == 0 && len(o.Operations) == 0 {
+		return true
+	}
+	for _, want := range o.Operations {
+		if want == op.OperationID || strings.EqualFold(want, method+" "+path) {
+			return true
+		}
+	}
+	for _, want := range o.Tags {
+		for _, tag := range op.Tags {
+			if strings.EqualFold(want, tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// baseURL picks the first server. Relative or missing servers are
+// resolved against a baseUrl variable.
+func (g *OpenAPIGenerator) baseURL(spec *oaSpec, script *model.Script) string {
+	base := ""
+	if len(spec.Servers) > 0 {
+		base = spec.Servers[0].URL
+		for name, v := range spec.Servers[0].Variables {
+			base = strings.ReplaceAll(base, "{"+name+"}", v.Default)
+		}
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	if u, err := url.Parse(base); err == nil && u.IsAbs() {
+		return base
+	}
+	script.Variables["baseUrl"] = defaultBaseURL
+	return "{{baseUrl}}" + base
+}
+
+func (s *oaSpec) step(base, method, path string, op *oaOperation, shared []*oaParameter, vars map[string]string) model.Step {
+	step := model.Step{
+		Type:   model.HTTP,
+		Method: strings.ToUpper(method),
+	}
+	header := make(map[string]string)
+	query := url.Values{}
+
+	// Operation parameters override path-level ones with the same
+	// name and location
+	params := make(map[string]*oaParameter)
+	var order []string
+	for _, p := range append(append([]*oaParameter(nil), shared...), op.Parameters...) {
+		p = s.parameter(p)
+		if p == nil {
+			continue
+		}
+		key := p.In + ":" + p.Name
+		if _, seen := params[key]; !seen {
+			order = append(order, key)
+		}
+		params[key] = p
+	}
+
+	for _, key := range order {
+		p := params[key]
+		example := s.paramExample(p)
+		value := fmt.Sprint(example)
+		if example == nil {
+			// Nothing to seed it with; leave a variable to fill in
+			s.warn("%s %s: no example for %s parameter %q; set the {{%s}} variable", step.Method, path, p.In, p.Name, p.Name)
+			setDefault(vars, p.Name)
+			value = "{{" + p.Name + "}}"
+			if p.In == "path" {
+				continue
+			}
+		}
+
+		switch p.In {
+		case "path":
+			if _, ok := vars[p.Name]; !ok {
+				vars[p.Name] = value
+			}
+		case "query":
+			if p.Required {
+				query.Set(p.Name, value)
+			}
+		case "header":
+			if p.Required {
+				header[p.Name] = value
+			}
+		}
+	}
+
+	s.applySecurity(op, header, query, vars)
+
+	u := base + pathParam.ReplaceAllString(path, "{{$1}}")
+	if len(query) > 0 {
+		// Encode keeps {{placeholders}} escaped; restore them
+		u += "?" + strings.NewReplacer("%7B%7B", "{{", "%7D%7D", "}}").Replace(query.Encode())
+	}
+	step.URL = u
+
+	if body := s.requestBody(op.RequestBody); body != nil {
+		contentType, media := pickMedia(body.Content)
+		if media != nil {
+			header["Content-Type"] = contentType
+			step.Body = s.bodyExample(contentType, media)
+		}
+	}
+
+	if len(header) > 0 {
+		step.Header = header
+	}
+	return step
+}
+
+// applySecurity adds credentials for the first security requirement
+// of the operation, or of the document when the operation has none
+func (s *oaSpec) applySecurity(op *oaOperation, header map[string]string, query url.Values, vars map[string]string) {
+	reqs := s.Security
+	if op.Security != nil {
+		reqs = *op.Security
+	}
+	if len(reqs) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(reqs[0]))
+	for name := range reqs[0] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		scheme := s.Components.SecuritySchemes[name]
+		if scheme == nil {
+			continue
+		}
+
+		switch strings.ToLower(scheme.Type) {
+		case "http":
+			if strings.EqualFold(scheme.Scheme, "basic") {
+				header["Authorization"] = "Basic {{basicAuth}}"
+				setDefault(vars, "basicAuth")
+			} else {
+				header["Authorization"] = "Bearer {{token}}"
+				setDefault(vars, "token")
+			}
+		case "apikey":
+			switch scheme.In {
+			case "query":
+				query.Set(scheme.Name, "{{apiKey}}")
+			case "cookie":
+				header["Cookie"] = scheme.Name + "={{apiKey}}"
+			default:
+				header[scheme.Name] = "{{apiKey}}"
+			}
+			setDefault(vars, "apiKey")
+		case "oauth2", "openidconnect":
+			header["Authorization"] = "Bearer {{accessToken}}"
+			setDefault(vars, "accessToken")
+		}
+	}
+}
+
+// setDefault declares a credential variable for the user to fill in
+func setDefault(vars map[string]string, name string) {
+	if _, ok := vars[name]; !ok {
+		vars[name] = ""
+	}
+}
+
+func (s *oaSpec) parameter(p *oaParameter) *oaParameter {
+	for i := 0; p != nil && p.Ref != "" && i < maxSampleDepth; i++ {
+		p = s.Components.Parameters[refName(p.Ref)]
+	}
+	return p
+}
+
+func (s *oaSpec) requestBody(b *oaRequestBody) *oaRequestBody {
+	for i := 0; b != nil && b.Ref != "" && i < maxSampleDepth; i++ {
+		b = s.Components.RequestBodies[refName(b.Ref)]
+	}
+	return b
+}
+
+func (s *oaSpec) paramExample(p *oaParameter) interface{} {
+	if p.Example != nil {
+		return p.Example
+	}
+	if ex, ok := s.firstExample(p.Examples); ok {
+		return ex
+	}
+	return s.sample(p.Schema, 0)
+}
+
+// pickMedia prefers JSON, then forms, then whatever comes first
+func pickMedia(content map[string]*oaMediaType) (string, *oaMediaType) {
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, prefer := range []string{"application/json", "application/x-www-form-urlencoded"} {
+		for _, t := range types {
+			if strings.HasPrefix(t, prefer) {
+				return t, content[t]
+			}
+		}
+	}
+	for _, t := range types {
+		if strings.HasSuffix(t, "+json") {
+			return t, content[t]
+		}
+	}
+	if len(types) > 0 {
+		return types[0], content[types[0]]
+	}
+	return "", nil
+}
+
+func (s *oaSpec) bodyExample(contentType string, media *oaMediaType) string {
+	value := media.Example
+	if value == nil {
+		if ex, ok := s.firstExample(media.Examples); ok {
+			value = ex
+		} else {
+			value = s.sample(media.Schema, 0)
+		}
+	}
+
+	if str, ok := value.(string); ok && !strings.Contains(contentType, "json") {
+		return str
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		form := url.Values{}
+		if obj, ok := value.(map[string]interface{}); ok {
+			for k, v := range obj {
+				form.Set(k, fmt.Sprint(v))
+			}
+		}
+		return form.Encode()
+	}
+
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *oaSpec) firstExample(examples map[string]*oaExample) (interface{}, bool) {
+	names := make([]string, 0, len(examples))
+	for name := range examples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ex := examples[name]
+		for i := 0; ex != nil && ex.Ref != "" && i < maxSampleDepth; i++ {
+			ex = s.Components.Examples[refName(ex.Ref)]
+		}
+		if ex != nil && ex.Value != nil {
+			return ex.Value, true
+		}
+	}
+	return nil, false
+}
+
+// sample builds an example value from a schema, preferring the
+// schema's own example, default or first enum value
+func (s *oaSpec) sample(schema *oaSchema, depth int) interface{} {
+	return s.sampleOf(schema, make(map[string]bool), depth)
+}
+
+// sampleOf skips references already being expanded, so recursive
+// schemas such as a tree node's children end at the first repeat
+func (s *oaSpec) sampleOf(schema *oaSchema, expanding map[string]bool, depth int) interface{} {
+	if schema == nil || depth > maxSampleDepth {
+		return nil
+	}
+	if schema.Ref != "" {
+		if expanding[schema.Ref] {
+			return nil
+		}
+		expanding[schema.Ref] = true
+		defer delete(expanding, schema.Ref)
+		return s.sampleOf(s.Components.Schemas[refName(schema.Ref)], expanding, depth+1)
+	}
+
+	switch {
+	case schema.Example != nil:
+		return schema.Example
+	case len(schema.Examples) > 0:
+		return schema.Examples[0]
+	case schema.Default != nil:
+		return schema.Default
+	case len(schema.Enum) > 0:
+		return schema.Enum[0]
+	}
+
+	if len(schema.AllOf) > 0 {
+		merged := make(map[string]interface{})
+		for _, part := range schema.AllOf {
+			if obj, ok := s.sampleOf(part, expanding, depth+1).(map[string]interface{}); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		return merged
+	}
+	if len(schema.OneOf) > 0 {
+		return s.sampleOf(schema.OneOf[0], expanding, depth+1)
+	}
+	if len(schema.AnyOf) > 0 {
+		return s.sampleOf(schema.AnyOf[0], expanding, depth+1)
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		obj := make(map[string]interface{}, len(schema.Properties))
+		for name, prop := range schema.Properties {
+			if v := s.sampleOf(prop, expanding, depth+1); v != nil {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		if item := s.sampleOf(schema.Items, expanding, depth+1); item != nil {
+			return []interface{}{item}
+		}
+		return []interface{}{}
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	case "string":
+		return sampleString(schema.Format)
+	}
+	return nil
+}
+
+func schemaType(schema *oaSchema) string {
+	switch t := schema.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		// 3.1 allows ["string", "null"]; the first non-null type wins
+		for _, item := range t {
+			if name, ok := item.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if len(schema.Properties) > 0 {
+		return "object"
+	}
+	return ""
+}
+
+func sampleString(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	}
+	return "string"
+}
+
+// refName returns the last segment of a local reference such as
+// "#/components/schemas/Pet"
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
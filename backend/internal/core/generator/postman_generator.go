@@ -0,0 +1,464 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+var (
+	// postmanPathVar matches :name path segments in Postman URLs
+	postmanPathVar = regexp.MustCompile(`/:([A-Za-z_][\w-]*)`)
+
+	// postmanDynamicVar matches Postman's built-in generators such as
+	// {{$guid}} and {{$randomInt}}, which have no equivalent here
+	postmanDynamicVar = regexp.MustCompile(`\{\{\s*\$\w+\s*\}\}`)
+)
+
+// PostmanGenerator builds a script from a Postman Collection v2.1
+// export. Folders become step groups and requests become steps.
+type PostmanGenerator struct{}
+
+func NewPostmanGenerator() *PostmanGenerator {
+	return &PostmanGenerator{}
+}
+
+type postmanCollection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+	Auth     *postmanAuth      `json:"auth"`
+	Event    []postmanEvent    `json:"event"`
+}
+
+// postmanItem is either a folder, with Item set, or a request
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []postmanItem   `json:"item"`
+	Request *postmanRequest `json:"request"`
+	Auth    *postmanAuth    `json:"auth"`
+	Event   []postmanEvent  `json:"event"`
+}
+
+type postmanRequest struct {
+	Method string            `json:"method"`
+	URL    postmanURL        `json:"url"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *struct {
+		Mode       string            `json:"mode"`
+		Raw        string            `json:"raw"`
+		URLEncoded []postmanKeyValue `json:"urlencoded"`
+		FormData   []postmanKeyValue `json:"formdata"`
+		GraphQL    *struct {
+			Query     string `json:"query"`
+			Variables string `json:"variables"`
+		} `json:"graphql"`
+		Options struct {
+			Raw struct {
+				Language string `json:"language"`
+			} `json:"raw"`
+		} `json:"options"`
+	} `json:"body"`
+	Auth *postmanAuth `json:"auth"`
+}
+
+// postmanURL is a plain string or an object with the raw URL and its
+// parts
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Query    []postmanKeyValue `json:"query"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+func (u *postmanURL) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &u.Raw)
+	}
+	type plain postmanURL
+	return json.Unmarshal(data, (*plain)(u))
+}
+
+type postmanKeyValue struct {
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value"`
+	Type     string      `json:"type"`
+	Disabled bool        `json:"disabled"`
+
+	// Enabled is used by environment values instead of Disabled
+	Enabled *bool `json:"enabled"`
+}
+
+func (kv postmanKeyValue) value() string {
+	if kv.Value == nil {
+		return ""
+	}
+	if s, ok := kv.Value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(kv.Value)
+}
+
+func (kv postmanKeyValue) active() bool {
+	return !kv.Disabled && (kv.Enabled == nil || *kv.Enabled)
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Basic  []postmanKeyValue `json:"basic"`
+	Bearer []postmanKeyValue `json:"bearer"`
+	APIKey []postmanKeyValue `json:"apikey"`
+	OAuth2 []postmanKeyValue `json:"oauth2"`
+}
+
+// param looks up one of the auth type's settings
+func (a *postmanAuth) param(list []postmanKeyValue, key string) string {
+	for _, kv := range list {
+		if kv.Key == key {
+			return kv.value()
+		}
+	}
+	return ""
+}
+
+type postmanEvent struct {
+	Listen string `json:"listen"`
+	Script struct {
+		Exec interface{} `json:"exec"` // a string or a list of lines
+	} `json:"script"`
+}
+
+type postmanEnvironment struct {
+	Values []postmanKeyValue `json:"values"`
+}
+
+// FromPostman converts a collection and an optional environment export.
+// Environment values override collection variables, as in Postman.
+// Parts that cannot be converted, such as pre-request and test scripts
+// or unsupported auth types, are returned as warnings.
+func (g *PostmanGenerator) FromPostman(collection, environment []byte) (*model.Script, []string, error) {
+	var c postmanCollection
+	if err := json.Unmarshal(collection, &c); err != nil {
+		return nil, nil, errors.New("invalid Postman collection: " + err.Error())
+	}
+	if c.Info.Schema != "" && !strings.Contains(c.Info.Schema, "v2.1") {
+		return nil, nil, errors.New("only Postman Collection v2.1 is supported")
+	}
+
+	conv := &postmanConverter{
+		script: &model.Script{
+			ID:        uuid.NewString(),
+			Variables: make(map[string]string),
+		},
+	}
+
+	for _, kv := range c.Variable {
+		if kv.active() && kv.Key != "" {
+			conv.script.Variables[kv.Key] = kv.value()
+		}
+	}
+	if len(environment) > 0 {
+		var env postmanEnvironment
+		if err := json.Unmarshal(environment, &env); err != nil {
+			return nil, nil, errors.New("invalid Postman environment: " + err.Error())
+		}
+		for _, kv := range env.Values {
+			if kv.active() && kv.Key != "" {
+				conv.script.Variables[kv.Key] = kv.value()
+			}
+		}
+	}
+
+	conv.events("collection", c.Event)
+	conv.items(c.Item, nil, c.Auth)
+
+	if len(conv.script.Steps) == 0 {
+		return nil, nil, errors.New("collection has no requests")
+	}
+	if len(conv.script.Variables) == 0 {
+		conv.script.Variables = nil
+	}
+	return conv.script, conv.warnings, nil
+}
+
+type postmanConverter struct {
+	script   *model.Script
+	warnings []string
+}
+
+func (c *postmanConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// items walks folders depth first. Auth is inherited from the nearest
+// folder, or the collection, that sets it.
+func (c *postmanConverter) items(items []postmanItem, folders []string, auth *postmanAuth) {
+	for _, item := range items {
+		path := append(append([]string(nil), folders...), item.Name)
+		where := strings.Join(path, " / ")
+
+		if item.Request == nil {
+			c.events("folder "+where, item.Event)
+			c.items(item.Item, path, inheritAuth(item.Auth, auth))
+			continue
+		}
+
+		c.events("request "+where, item.Event)
+
+		step, ok := c.request(where, item.Request, inheritAuth(item.Request.Auth, auth))
+		if !ok {
+			continue
+		}
+		step.Group = strings.Join(folders, "::")
+		c.script.Steps = append(c.script.Steps, step)
+	}
+}
+
+func inheritAuth(own, parent *postmanAuth) *postmanAuth {
+	if own == nil || own.Type == "inherit" {
+		return parent
+	}
+	return own
+}
+
+func (c *postmanConverter) events(where string, events []postmanEvent) {
+	for _, e := range events {
+		if scriptText(e.Script.Exec) == "" {
+			continue
+		}
+		switch e.Listen {
+		case "prerequest":
+			c.warn("%s: pre-request script not converted", where)
+		case "test":
+			c.warn("%s: test script not converted", where)
+		default:
+			c.warn("%s: %s script not converted", where, e.Listen)
+		}
+	}
+}
+
+func scriptText(exec interface{}) string {
+	switch v := exec.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		var lines []string
+		for _, line := range v {
+			lines = append(lines, fmt.Sprint(line))
+		}
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	return ""
+}
+
+func (c *postmanConverter) request(where string, req *postmanRequest, auth *postmanAuth) (model.Step, bool) {
+	step := model.Step{
+		Type:   model.HTTP,
+		Method: strings.ToUpper(req.Method),
+	}
+	if step.Method == "" {
+		step.Method = "GET"
+	}
+
+	rawURL := req.URL.Raw
+	if rawURL == "" {
+		c.warn("%s: request has no URL; skipped", where)
+		return step, false
+	}
+
+	// Disabled query parameters are still part of the raw URL
+	for _, q := range req.URL.Query {
+		if q.Disabled {
+			rawURL = removeQueryParam(rawURL, q.Key, q.value())
+		}
+	}
+
+	// :name path variables become {{name}} placeholders seeded with
+	// the request's value
+	for _, pv := range req.URL.Variable {
+		if _, ok := c.script.Variables[pv.Key]; !ok && pv.Key != "" {
+			c.script.Variables[pv.Key] = pv.value()
+		}
+	}
+	step.URL = postmanPathVar.ReplaceAllString(rawURL, "/{{$1}}")
+
+	for _, h := range req.Header {
+		if !h.active() || h.Key == "" {
+			continue
+		}
+		if step.Header == nil {
+			step.Header = make(map[string]string)
+		}
+		step.Header[h.Key] = h.value()
+	}
+
+	if req.Body != nil {
+		c.body(where, req, &step)
+	}
+
+	step.Auth = c.auth(where, auth)
+
+	if postmanDynamicVar.MatchString(step.URL + step.Body + fmt.Sprint(step.Header)) {
+		c.warn("%s: Postman dynamic variables such as {{$guid}} are not supported", where)
+	}
+	return step, true
+}
+
+func (c *postmanConverter) body(where string, req *postmanRequest, step *model.Step) {
+	b := req.Body
+
+	setType := func(contentType string) {
+		if step.Header == nil {
+			step.Header = make(map[string]string)
+		}
+		for k := range step.Header {
+			if strings.EqualFold(k, "Content-Type") {
+				return
+			}
+		}
+		step.Header["Content-Type"] = contentType
+	}
+
+	switch b.Mode {
+	case "raw":
+		step.Body = b.Raw
+		switch b.Options.Raw.Language {
+		case "json":
+			setType("application/json")
+		case "xml":
+			setType("application/xml")
+		}
+	case "urlencoded":
+		form := url.Values{}
+		for _, kv := range b.URLEncoded {
+			if kv.active() {
+				form.Add(kv.Key, kv.value())
+			}
+		}
+		step.Body = form.Encode()
+		setType("application/x-www-form-urlencoded")
+	case "formdata":
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		for _, kv := range b.FormData {
+			if !kv.active() {
+				continue
+			}
+			if kv.Type == "file" {
+				c.warn("%s: file field %q not converted", where, kv.Key)
+				continue
+			}
+			w.WriteField(kv.Key, kv.value())
+		}
+		w.Close()
+		step.Body = buf.String()
+		setType(w.FormDataContentType())
+	case "graphql":
+		if b.GraphQL == nil {
+			return
+		}
+		step.Type = model.GraphQL
+		step.GraphQL = &model.GraphQLStep{Query: b.GraphQL.Query}
+		if vars := strings.TrimSpace(b.GraphQL.Variables); vars != "" {
+			if json.Valid([]byte(vars)) {
+				step.GraphQL.Variables = json.RawMessage(vars)
+			} else {
+				c.warn("%s: GraphQL variables are not valid JSON; dropped", where)
+			}
+		}
+	case "file":
+		c.warn("%s: file body not converted", where)
+	case "":
+	default:
+		c.warn("%s: %s body not converted", where, b.Mode)
+	}
+}
+
+func (c *postmanConverter) auth(where string, a *postmanAuth) *model.Auth {
+	if a == nil {
+		return nil
+	}
+
+	switch a.Type {
+	case "", "noauth":
+		return nil
+	case "basic":
+		username := a.param(a.Basic, "username")
+		if username == "" {
+			c.warn("%s: basic auth has no username; not converted", where)
+			return nil
+		}
+		return &model.Auth{
+			Type:     model.BasicAuth,
+			Username: username,
+			Password: a.param(a.Basic, "password"),
+		}
+	case "bearer":
+		token := a.param(a.Bearer, "token")
+		if token == "" {
+			c.warn("%s: bearer auth has no token; not converted", where)
+			return nil
+		}
+		return &model.Auth{Type: model.BearerAuth, Token: token}
+	case "apikey":
+		key := a.param(a.APIKey, "key")
+		if key == "" {
+			c.warn("%s: API key auth has no key name; not converted", where)
+			return nil
+		}
+		in := "header"
+		if a.param(a.APIKey, "in") == "query" {
+			in = "query"
+		}
+		return &model.Auth{
+			Type:  model.APIKeyAuth,
+			Key:   key,
+			Value: a.param(a.APIKey, "value"),
+			In:    in,
+		}
+	case "oauth2":
+		// A token fetched in Postman can be replayed as a bearer token;
+		// the grant flow itself is not
+		if token := a.param(a.OAuth2, "accessToken"); token != "" {
+			c.warn("%s: OAuth 2.0 converted to its current access token", where)
+			return &model.Auth{Type: model.BearerAuth, Token: token}
+		}
+	}
+
+	c.warn("%s: %s auth not converted", where, a.Type)
+	return nil
+}
+
+// removeQueryParam drops one key=value pair from a raw URL, keeping the
+// rest of the URL, including placeholders, untouched
+func removeQueryParam(raw, key, value string) string {
+	base, query, ok := strings.Cut(raw, "?")
+	if !ok {
+		return raw
+	}
+
+	pairs := strings.Split(query, "&")
+	kept := pairs[:0]
+	removed := false
+	for _, p := range pairs {
+		if !removed && (p == key+"="+value || (value == "" && p == key)) {
+			removed = true
+			continue
+		}
+		kept = append(kept, p)
+	}
+	if len(kept) == 0 {
+		return base
+	}
+	return base + "?" + strings.Join(kept, "&")
+}
@@ -0,0 +1,335 @@
+package generator
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/model"
+)
+
+const (
+	soap11EnvelopeNS = "http://schemas.xmlsoap.org/soap/envelope/"
+	soap12EnvelopeNS = "http://www.w3.org/2003/05/soap-envelope"
+
+	// maxSkeletonDepth keeps starter envelopes readable for deeply
+	// nested or recursive schemas
+	maxSkeletonDepth = 4
+)
+
+// WSDLGenerator builds a starter script with one SOAP step per
+// operation of a WSDL 1.1 document
+type WSDLGenerator struct{}
+
+func NewWSDLGenerator() *WSDLGenerator {
+	return &WSDLGenerator{}
+}
+
+// Generate reads a local WSDL file
+func (g *WSDLGenerator) Generate(path string) (*model.Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return g.FromWSDL(data)
+}
+
+type wsdlDefinitions struct {
+	TargetNamespace string `xml:"targetNamespace,attr"`
+	Types           struct {
+		Schemas []xsdSchema `xml:"schema"`
+	} `xml:"types"`
+	Messages  []wsdlMessage  `xml:"message"`
+	PortTypes []wsdlPortType `xml:"portType"`
+	Bindings  []wsdlBinding  `xml:"binding"`
+	Services  []wsdlService  `xml:"service"`
+}
+
+type wsdlMessage struct {
+	Name  string `xml:"name,attr"`
+	Parts []struct {
+		Name    string `xml:"name,attr"`
+		Element string `xml:"element,attr"`
+		Type    string `xml:"type,attr"`
+	} `xml:"part"`
+}
+
+type wsdlPortType struct {
+	Name       string `xml:"name,attr"`
+	Operations []struct {
+		Name  string `xml:"name,attr"`
+		Input struct {
+			Message string `xml:"message,attr"`
+		} `xml:"input"`
+	} `xml:"operation"`
+}
+
+type wsdlSOAPOperation struct {
+	SOAPAction string `xml:"soapAction,attr"`
+	Style      string `xml:"style,attr"`
+}
+
+type wsdlBinding struct {
+	Name   string `xml:"name,attr"`
+	Type   string `xml:"type,attr"`
+	SOAP11 *struct {
+		Style string `xml:"style,attr"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/soap/ binding"`
+	SOAP12 *struct {
+		Style string `xml:"style,attr"`
+	} `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ binding"`
+	Operations []struct {
+		Name   string             `xml:"name,attr"`
+		SOAP11 *wsdlSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+		SOAP12 *wsdlSOAPOperation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+		Input  struct {
+			Body struct {
+				Namespace string `xml:"namespace,attr"`
+			} `xml:"body"`
+		} `xml:"input"`
+	} `xml:"operation"`
+}
+
+type wsdlService struct {
+	Name  string `xml:"name,attr"`
+	Ports []struct {
+		Binding  string       `xml:"binding,attr"`
+		Address  *wsdlAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap/ address"`
+		Address2 *wsdlAddress `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ address"`
+	} `xml:"port"`
+}
+
+type wsdlAddress struct {
+	Location string `xml:"location,attr"`
+}
+
+type xsdSchema struct {
+	TargetNamespace    string           `xml:"targetNamespace,attr"`
+	ElementFormDefault string           `xml:"elementFormDefault,attr"`
+	Elements           []xsdElement     `xml:"element"`
+	ComplexTypes       []xsdComplexType `xml:"complexType"`
+}
+
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr"`
+	Ref         string          `xml:"ref,attr"`
+	ComplexType *xsdComplexType `xml:"complexType"`
+}
+
+type xsdComplexType struct {
+	Name     string       `xml:"name,attr"`
+	Sequence []xsdElement `xml:"sequence>element"`
+	All      []xsdElement `xml:"all>element"`
+	Content  []xsdElement `xml:"complexContent>extension>sequence>element"`
+}
+
+func (t *xsdComplexType) children() []xsdElement {
+	out := append([]xsdElement(nil), t.Sequence...)
+	out = append(out, t.All...)
+	return append(out, t.Content...)
+}
+
+// wsdlSchemas indexes global elements and types across all schemas
+type wsdlSchemas struct {
+	elements map[string]xsdElement
+	types    map[string]*xsdComplexType
+	ns       map[string]string // element name -> target namespace
+	prefixed map[string]bool   // namespace -> elementFormDefault="qualified"
+}
+
+// FromWSDL parses a WSDL 1.1 document
+func (g *WSDLGenerator) FromWSDL(data []byte) (*model.Script, error) {
+	var defs wsdlDefinitions
+	if err := xml.Unmarshal(data, &defs); err != nil {
+		return nil, errors.New("invalid WSDL: " + err.Error())
+	}
+
+	schemas := &wsdlSchemas{
+		elements: make(map[string]xsdElement),
+		types:    make(map[string]*xsdComplexType),
+		ns:       make(map[string]string),
+		prefixed: make(map[string]bool),
+	}
+	for _, s := range defs.Types.Schemas {
+		schemas.prefixed[s.TargetNamespace] = s.ElementFormDefault == "qualified"
+		for _, el := range s.Elements {
+			schemas.elements[el.Name] = el
+			schemas.ns[el.Name] = s.TargetNamespace
+		}
+		for i := range s.ComplexTypes {
+			schemas.types[s.ComplexTypes[i].Name] = &s.ComplexTypes[i]
+		}
+	}
+
+	messages := make(map[string]wsdlMessage)
+	for _, m := range defs.Messages {
+		messages[m.Name] = m
+	}
+	portTypes := make(map[string]wsdlPortType)
+	for _, pt := range defs.PortTypes {
+		portTypes[pt.Name] = pt
+	}
+	bindings := make(map[string]wsdlBinding)
+	for _, b := range defs.Bindings {
+		bindings[b.Name] = b
+	}
+
+	script := &model.Script{ID: uuid.NewString()}
+	seen := make(map[string]bool)
+
+	for _, svc := range defs.Services {
+		for _, port := range svc.Ports {
+			binding, ok := bindings[localName(port.Binding)]
+			if !ok {
+				continue
+			}
+
+			version, location := "1.1", ""
+			switch {
+			case port.Address != nil && binding.SOAP11 != nil:
+				location = port.Address.Location
+			case port.Address2 != nil && binding.SOAP12 != nil:
+				version, location = "1.2", port.Address2.Location
+			default:
+				continue // not a SOAP port
+			}
+
+			// The same operations are often exposed on a 1.1 and a 1.2
+			// port; one step per operation is enough
+			portType := portTypes[localName(binding.Type)]
+
+			for _, op := range binding.Operations {
+				key := portType.Name + "/" + op.Name
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				soapOp := op.SOAP11
+				style := ""
+				if version == "1.2" {
+					soapOp = op.SOAP12
+					if binding.SOAP12 != nil {
+						style = binding.SOAP12.Style
+					}
+				} else if binding.SOAP11 != nil {
+					style = binding.SOAP11.Style
+				}
+
+				action := ""
+				if soapOp != nil {
+					action = soapOp.SOAPAction
+					if soapOp.Style != "" {
+						style = soapOp.Style
+					}
+				}
+
+				var input wsdlMessage
+				for _, ptOp := range portType.Operations {
+					if ptOp.Name == op.Name {
+						input = messages[localName(ptOp.Input.Message)]
+					}
+				}
+
+				ns := op.Input.Body.Namespace
+				if ns == "" {
+					ns = defs.TargetNamespace
+				}
+
+				script.Steps = append(script.Steps, model.Step{
+					Type:   model.SOAP,
+					Method: http.MethodPost,
+					URL:    location,
+					SOAP: &model.SOAPStep{
+						Envelope: soapEnvelope(version, style, op.Name, ns, input, schemas),
+						Action:   action,
+						Version:  version,
+					},
+				})
+			}
+		}
+	}
+
+	if len(script.Steps) == 0 {
+		return nil, errors.New("WSDL has no SOAP operations")
+	}
+	return script, nil
+}
+
+func soapEnvelope(version, style, operation, ns string, input wsdlMessage, schemas *wsdlSchemas) string {
+	envNS := soap11EnvelopeNS
+	if version == "1.2" {
+		envNS = soap12EnvelopeNS
+	}
+
+	var body strings.Builder
+	if style == "rpc" {
+		body.WriteString("    <tns:" + operation + ">\n")
+		for _, part := range input.Parts {
+			body.WriteString("      <" + part.Name + ">?</" + part.Name + ">\n")
+		}
+		body.WriteString("    </tns:" + operation + ">\n")
+	} else {
+		for _, part := range input.Parts {
+			name := localName(part.Element)
+			if name == "" {
+				continue
+			}
+			if elNS, ok := schemas.ns[name]; ok {
+				ns = elNS
+			}
+			schemas.writeElement(&body, schemas.elements[name], "tns:", schemas.prefixed[ns], 2)
+		}
+	}
+
+	return `<soapenv:Envelope xmlns:soapenv="` + envNS + `" xmlns:tns="` + ns + `">
+  <soapenv:Header/>
+  <soapenv:Body>
+` + body.String() + `  </soapenv:Body>
+</soapenv:Envelope>`
+}
+
+// writeElement writes a skeleton for el with "?" in place of values
+func (s *wsdlSchemas) writeElement(b *strings.Builder, el xsdElement, prefix string, qualified bool, depth int) {
+	if el.Ref != "" {
+		el = s.elements[localName(el.Ref)]
+	}
+	if el.Name == "" {
+		return
+	}
+
+	indent := strings.Repeat("  ", depth)
+	name := prefix + el.Name
+
+	ct := el.ComplexType
+	if ct == nil {
+		ct = s.types[localName(el.Type)]
+	}
+	if ct == nil || depth-2 >= maxSkeletonDepth {
+		b.WriteString(indent + "<" + name + ">?</" + name + ">\n")
+		return
+	}
+
+	childPrefix := ""
+	if qualified {
+		childPrefix = "tns:"
+	}
+
+	b.WriteString(indent + "<" + name + ">\n")
+	for _, child := range ct.children() {
+		s.writeElement(b, child, childPrefix, qualified, depth+1)
+	}
+	b.WriteString(indent + "</" + name + ">\n")
+}
+
+// localName strips a namespace prefix such as "tns:"
+func localName(qname string) string {
+	if i := strings.LastIndex(qname, ":"); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
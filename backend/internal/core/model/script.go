@@ -1,9 +1,23 @@
 package model
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type StepType string
 
 const (
-	HTTP StepType = "HTTP"
+	HTTP    StepType = "HTTP"
+	Batch   StepType = "BATCH"
+	WS      StepType = "WS"
+	GRPC    StepType = "GRPC"
+	GraphQL StepType = "GRAPHQL"
+	SSE     StepType = "SSE"
+	SOAP    StepType = "SOAP"
+	TCP     StepType = "TCP"
+	UDP     StepType = "UDP"
+	Redis   StepType = "REDIS"
 )
 
 type Step struct {
@@ -12,9 +26,283 @@ type Step struct {
 	URL    string            `json:"url"`
 	Header map[string]string `json:"header,omitempty"`
 	Body   string            `json:"body,omitempty"`
+
+	// Batch is set for BATCH steps
+	Batch *BatchStep `json:"batch,omitempty"`
+
+	// WS is set for WS steps; URL is the ws:// or wss:// endpoint
+	WS *WSStep `json:"ws,omitempty"`
+
+	// GRPC is set for GRPC steps; URL is the target host:port and
+	// Header entries are sent as metadata
+	GRPC *GRPCStep `json:"grpc,omitempty"`
+
+	// GraphQL is set for GRAPHQL steps; URL is the GraphQL endpoint
+	GraphQL *GraphQLStep `json:"graphql,omitempty"`
+
+	// SSE is set for SSE steps; URL is the text/event-stream endpoint
+	SSE *SSEStep `json:"sse,omitempty"`
+
+	// SOAP is set for SOAP steps; URL is the service endpoint
+	SOAP *SOAPStep `json:"soap,omitempty"`
+
+	// Socket is set for TCP and UDP steps; URL is host:port
+	Socket *SocketStep `json:"socket,omitempty"`
+
+	// Redis is set for REDIS steps; URL is host:port or
+	// redis://[:password@]host:port[/db]
+	Redis *RedisStep `json:"redis,omitempty"`
+
+	// Checks must all pass for the step to count as a success
+	Checks []Check `json:"checks,omitempty"`
+
+	// Extract stores values from the response in VU variables, which
+	// later steps reference as {{name}}
+	Extract []Extraction `json:"extract,omitempty"`
+
+	// ThinkTimeMs is a pause before the step runs, e.g. the user's
+	// reading time taken from a recording
+	ThinkTimeMs int `json:"thinkTimeMs,omitempty"`
+
+	// Weight turns the script into a traffic mix: when any step has a
+	// weight, each iteration runs one step picked at random in
+	// proportion to the weights. Steps without a weight never run.
+	Weight int `json:"weight,omitempty"`
+
+	// OffsetMs is when the step starts in a replay script, relative to
+	// the start of the test
+	OffsetMs int64 `json:"offsetMs,omitempty"`
+
+	// Group names the group the step belongs to, like k6's group().
+	// Nested groups are joined with "::", e.g. "users::admin".
+	Group string `json:"group,omitempty"`
+
+	// Auth adds credentials to the request
+	Auth *Auth `json:"auth,omitempty"`
+}
+
+type AuthType string
+
+const (
+	BasicAuth  AuthType = "basic"
+	BearerAuth AuthType = "bearer"
+	APIKeyAuth AuthType = "apikey"
+)
+
+// Auth describes request credentials. Values may contain {{var}}
+// placeholders.
+type Auth struct {
+	Type AuthType `json:"type"`
+
+	// Username and Password are used by basic auth
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Token is used by bearer auth
+	Token string `json:"token,omitempty"`
+
+	// Key and Value are used by API key auth, sent as a header unless
+	// In is "query"
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value,omitempty"`
+	In    string `json:"in,omitempty"`
+}
+
+type RuleType string
+
+const (
+	XPathRule RuleType = "xpath"
+
+	// RegexRule matches a regular expression; the value is the first
+	// capture group, or the whole match without groups
+	RegexRule RuleType = "regex"
+
+	// JSONPathRule selects from a JSON body with a simple path such as
+	// $.data.items[0].id
+	JSONPathRule RuleType = "jsonpath"
+)
+
+// Check asserts something about a response. With Equals empty it
+// passes when the expression matches.
+type Check struct {
+	Type   RuleType `json:"type"`
+	Expr   string   `json:"expr"`
+	Equals string   `json:"equals,omitempty"`
+}
+
+// Extraction stores the first match of Expr in the variable Var
+type Extraction struct {
+	Var  string   `json:"var"`
+	Type RuleType `json:"type"`
+	Expr string   `json:"expr"`
+
+	// Default is stored when nothing matches; without it the variable
+	// keeps its previous value
+	Default string `json:"default,omitempty"`
+}
+
+// BatchStep fires a set of HTTP requests concurrently inside one VU,
+// like k6's http.batch.
+type BatchStep struct {
+	Requests    []Step `json:"requests"`
+	Parallelism int    `json:"parallelism,omitempty"` // 0 = all at once
+}
+
+// WSStep describes a WebSocket session: connect, send messages on a
+// schedule, wait for expected replies, then close.
+type WSStep struct {
+	Messages []WSMessage `json:"messages,omitempty"`
+
+	// HoldMs keeps the session open after the last message
+	HoldMs int `json:"holdMs,omitempty"`
+}
+
+type WSMessage struct {
+	Data    string `json:"data"`
+	DelayMs int    `json:"delayMs,omitempty"` // wait before sending
+
+	// Expect, if set, is a substring that a received message must
+	// contain within TimeoutMs for the exchange to succeed
+	Expect    string `json:"expect,omitempty"`
+	TimeoutMs int    `json:"timeoutMs,omitempty"` // default 5000
+}
+
+// GRPCStep calls a unary or server-streaming gRPC method
+type GRPCStep struct {
+	Service string          `json:"service"` // fully qualified, e.g. "helloworld.Greeter"
+	Method  string          `json:"method"`  // e.g. "SayHello"
+	Message json.RawMessage `json:"message,omitempty"`
+
+	// ProtoFiles names uploaded .proto files that define the service.
+	// When empty the schema is fetched through server reflection.
+	ProtoFiles []string `json:"protoFiles,omitempty"`
+
+	TLS       bool `json:"tls,omitempty"` // plaintext by default
+	TimeoutMs int  `json:"timeoutMs,omitempty"`
+}
+
+// GraphQLStep posts a GraphQL operation. A response with a non-empty
+// "errors" array counts as a failure even when the status is 200.
+type GraphQLStep struct {
+	Query         string          `json:"query"`
+	Variables     json.RawMessage `json:"variables,omitempty"`
+	OperationName string          `json:"operationName,omitempty"`
+}
+
+// SSEStep reads a Server-Sent Events stream until MaxEvents events
+// arrive, an event matches, or TimeoutMs passes, whichever is first.
+type SSEStep struct {
+	MaxEvents int `json:"maxEvents,omitempty"`
+	TimeoutMs int `json:"timeoutMs,omitempty"` // default 30000
+
+	// UntilEvent and UntilData stop the stream at the first event whose
+	// type equals UntilEvent and whose data contains UntilData
+	UntilEvent string `json:"untilEvent,omitempty"`
+	UntilData  string `json:"untilData,omitempty"`
+}
+
+// SOAPStep posts a SOAP envelope. A SOAP Fault in the response counts
+// as a failure.
+type SOAPStep struct {
+	Envelope string `json:"envelope"` // may contain {{var}} placeholders
+	Action   string `json:"action,omitempty"`
+	Version  string `json:"version,omitempty"` // "1.1" (default) or "1.2"
+
+	// Namespaces binds prefixes used in XPath checks and extractions
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+}
+
+// SocketStep sends a payload over a raw socket and optionally reads a
+// reply. Without ReadUntil or ReadBytes nothing is read.
+type SocketStep struct {
+	Payload   string `json:"payload"`             // may contain {{var}} placeholders
+	ReadUntil string `json:"readUntil,omitempty"` // delimiter, e.g. "\n"
+	ReadBytes int    `json:"readBytes,omitempty"`
+	TimeoutMs int    `json:"timeoutMs,omitempty"` // default 5000
+}
+
+// RedisStep runs RESP commands, e.g. [["SET", "user:{{id}}", "x"]].
+// With Pipeline set all commands are written before any reply is read.
+type RedisStep struct {
+	Commands  [][]string `json:"commands"`
+	Pipeline  bool       `json:"pipeline,omitempty"`
+	TimeoutMs int        `json:"timeoutMs,omitempty"` // default 5000
 }
 
 type Script struct {
 	ID    string `json:"id"`
 	Steps []Step `json:"steps"`
+
+	// Version counts saves, starting at 1. Updates must name the version
+	// they were made from so concurrent edits don't overwrite each other.
+	Version int `json:"version,omitempty"`
+
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+
+	// Project files the script in a project or folder. Nested folders
+	// are separated by "/", e.g. "shop/checkout".
+	Project string `json:"project,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Variables seed each VU's variables for {{name}} placeholders.
+	// k6 scripts see them as __ENV.
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// Source is a k6 JavaScript script. When set, the engine runs it
+	// instead of Steps.
+	Source string `json:"source,omitempty"`
+
+	// Datasets feed rows of CSV data into variables, one row per
+	// iteration
+	Datasets []Dataset `json:"datasets,omitempty"`
+
+	// Scenario holds the script's default load settings, used when a
+	// test does not set its own
+	Scenario *Scenario `json:"scenario,omitempty"`
+
+	// Replay runs the steps once, each at its OffsetMs, instead of
+	// looping over them in every VU
+	Replay *Replay `json:"replay,omitempty"`
+}
+
+// Replay reproduces recorded traffic with its original timing. VUs
+// bound how many requests are in flight at once.
+type Replay struct {
+	// Speed scales the recorded timing: 2 replays twice as fast.
+	// Defaults to 1.
+	Speed float64 `json:"speed,omitempty"`
+}
+
+// Dataset reads a CSV file from the data directory. Each iteration of
+// each VU takes the next row, shared across VUs, and stores its columns
+// in the named variables.
+type Dataset struct {
+	File string `json:"file"` // relative to the data directory
+
+	// Variables name the columns; when empty the first line is used
+	// as the header
+	Variables []string `json:"variables,omitempty"`
+
+	Delimiter       string `json:"delimiter,omitempty"` // default ","
+	IgnoreFirstLine bool   `json:"ignoreFirstLine,omitempty"`
+
+	// StopAtEnd stops VUs once every row has been used instead of
+	// starting over from the first row
+	StopAtEnd bool `json:"stopAtEnd,omitempty"`
+}
+
+// Scenario describes how load is applied
+type Scenario struct {
+	VUs           int `json:"vus"`
+	Duration      int `json:"duration,omitempty"`      // seconds
+	RampUpSeconds int `json:"rampUpSeconds,omitempty"` // time to start all VUs
+
+	// Iterations caps how often each VU runs the script; 0 means no
+	// limit. Without a duration the run ends once every VU is done.
+	Iterations int `json:"iterations,omitempty"`
 }
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// ScriptVersion is a script as it was saved at one version
+type ScriptVersion struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"savedAt"`
+	Author  string    `json:"author,omitempty"`
+
+	// Note says how the version came about, e.g. "rollback to 3"
+	Note string `json:"note,omitempty"`
+
+	// Script is left out of version listings
+	Script *Script `json:"script,omitempty"`
+}
+
+// ScriptDiff lists what changed from one version of a script to another
+type ScriptDiff struct {
+	ScriptID string         `json:"scriptId"`
+	From     int            `json:"from"`
+	To       int            `json:"to"`
+	Changes  []ScriptChange `json:"changes"`
+}
+
+// ScriptChange is one added, removed or changed value. Path addresses
+// it in the script's JSON, e.g. "steps[2].header.Accept".
+type ScriptChange struct {
+	Path string      `json:"path"`
+	Op   string      `json:"op"` // "added", "removed" or "changed"
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
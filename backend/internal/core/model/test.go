@@ -11,16 +11,31 @@ const (
 	Spike  TestType = "spike"
 )
 
+// Engines that can run a test
+const (
+	NativeEngine = "native"
+	K6Engine     = "k6"
+)
+
 type TestConfig struct {
 	ScriptID string   `json:"scriptId"`
 	Type     TestType `json:"type"`
 	VUs      int      `json:"vus"`
 	Duration int      `json:"duration"` // seconds
+
+	// Engine selects the built-in engine (default) or an external k6
+	// binary
+	Engine string `json:"engine,omitempty"`
+
+	// TestID is assigned before the run; runners report progress and
+	// the result under it
+	TestID string `json:"-"`
 }
 
 type TestResult struct {
 	TestID        string    `json:"testId"`
 	ScriptID      string    `json:"scriptId"`
+	ScriptVersion int       `json:"scriptVersion,omitempty"`
 	TotalRequests int       `json:"totalRequests"`
 	Success       int       `json:"success"`
 	Failure       int       `json:"failure"`
@@ -31,5 +46,64 @@ type TestResult struct {
 	RPS           float64   `json:"rps"`
 	Iterations    int       `json:"iterations"`
 	StartedAt     time.Time `json:"startedAt"`
+
+	// Metrics holds named trends beyond the overall request latency,
+	// e.g. "batch_duration{step:1}"
+	Metrics map[string]MetricSummary `json:"metrics,omitempty"`
+
+	// Counters holds named totals, e.g. "ws_msgs_sent{step:2}"
+	Counters map[string]int64 `json:"counters,omitempty"`
+
+	// Thresholds are the pass/fail criteria declared in a k6 script's
+	// options
+	Thresholds []ThresholdResult `json:"thresholds,omitempty"`
+
+	// Snapshot is exactly what ran, so the result still makes sense
+	// after the script is edited or deleted and the run can be repeated
+	Snapshot *RunSnapshot `json:"snapshot,omitempty"`
+
+	// RerunOf is the test this one repeated
+	RerunOf string `json:"rerunOf,omitempty"`
+}
+
+// RunSnapshot records a test run's inputs. It is written once, with
+// the result, and never changed.
+type RunSnapshot struct {
+	// Script is a copy of the script as it ran
+	Script *Script `json:"script"`
+
+	// Config is the effective config, with defaults from the script's
+	// scenario filled in
+	Config TestConfig `json:"config"`
+
+	// Executor names how VUs and iterations were scheduled, e.g.
+	// "constant-vus"
+	Executor string `json:"executor"`
+
+	// Thresholds are the expressions evaluated, by metric
+	Thresholds map[string][]string `json:"thresholds,omitempty"`
+
+	EngineVersion string `json:"engineVersion,omitempty"`
+}
+
+// ThresholdResult is the outcome of one threshold expression such as
+// "p(95)<2000" on http_req_duration
+type ThresholdResult struct {
+	Metric    string  `json:"metric"`
+	Threshold string  `json:"threshold"`
+	Value     float64 `json:"value"`
+	Passed    bool    `json:"passed"`
+}
+
+// MetricSummary aggregates the samples recorded for one named metric.
+// Durations are in milliseconds.
+type MetricSummary struct {
+	Count int     `json:"count"`
+	Avg   float64 `json:"avg"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
 }
 
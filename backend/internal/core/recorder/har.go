@@ -0,0 +1,125 @@
+package recorder
+
+import (
+	"encoding/json"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// The recording is written as HAR 1.2, so it goes through the same
+// filters as an uploaded browser recording and can be saved as is
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	QueryString []harHeader `json:"queryString"`
+	PostData    *harPost    `json:"postData,omitempty"`
+}
+
+type harPost struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	Content     struct {
+		Size     int64  `json:"size"`
+		MimeType string `json:"mimeType"`
+	} `json:"content"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func (s *session) har() ([]byte, error) {
+	s.mu.Lock()
+	entries := append([]entry(nil), s.entries...)
+	s.mu.Unlock()
+
+	var doc harLog
+	doc.Log.Version = "1.2"
+	doc.Log.Creator = harCreator{Name: "k6clone recorder", Version: "1.0"}
+	doc.Log.Entries = make([]harEntry, 0, len(entries))
+
+	for _, e := range entries {
+		he := harEntry{
+			StartedDateTime: e.started,
+			Time:            float64(e.duration.Microseconds()) / 1000,
+			Request: harRequest{
+				Method:      e.method,
+				URL:         e.url,
+				HTTPVersion: e.proto,
+				Headers:     harHeaders(e.header),
+				QueryString: []harHeader{},
+			},
+			Response: harResponse{
+				Status:      e.status,
+				StatusText:  e.statusText,
+				HTTPVersion: e.proto,
+				Headers:     harHeaders(e.respHeader),
+			},
+		}
+		if u, err := url.Parse(e.url); err == nil {
+			for name, values := range u.Query() {
+				for _, v := range values {
+					he.Request.QueryString = append(he.Request.QueryString, harHeader{Name: name, Value: v})
+				}
+			}
+		}
+		if len(e.body) > 0 {
+			he.Request.PostData = &harPost{MimeType: e.header.Get("Content-Type"), Text: string(e.body)}
+		}
+		he.Response.Content.Size = e.responseSize
+		he.Response.Content.MimeType = e.respHeader.Get("Content-Type")
+
+		doc.Log.Entries = append(doc.Log.Entries, he)
+	}
+
+	return json.Marshal(doc)
+}
+
+// harHeaders lists headers in name order, so recordings are stable
+func harHeaders(h map[string][]string) []harHeader {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := []harHeader{}
+	for _, name := range names {
+		for _, v := range h[name] {
+			out = append(out, harHeader{Name: name, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,540 @@
+package recorder
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAddr = "127.0.0.1:8888"
+
+var (
+	ErrRecording = errors.New("a recording is already running")
+
+	// ErrAddr is returned for a listen address that is malformed or not
+	// on a loopback interface. The proxy intercepts HTTPS, so it is never
+	// opened to other machines.
+	ErrAddr = errors.New("the recorder only listens on a loopback address such as 127.0.0.1:8888")
+)
+
+// hopHeaders only apply to one connection and are not forwarded
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Options configure a recording session
+type Options struct {
+	// Addr is where the proxy listens; defaults to 127.0.0.1:8888. The
+	// host must be loopback.
+	Addr string `json:"addr"`
+
+	// Hosts limits recording to these hosts and their subdomains;
+	// traffic to other hosts is proxied but not recorded
+	Hosts []string `json:"hosts"`
+
+	// Insecure skips verifying upstream certificates, for test servers
+	// with self-signed certificates
+	Insecure bool `json:"insecure"`
+}
+
+// Status describes the recording session, if any
+type Status struct {
+	Recording bool       `json:"recording"`
+	Addr      string     `json:"addr,omitempty"`
+	Hosts     []string   `json:"hosts,omitempty"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	Requests  int        `json:"requests"`
+}
+
+// Recorder runs a forward proxy that records the requests passing
+// through it. HTTPS to recorded hosts is intercepted with certificates
+// signed by the recorder's CA; other tunnels are relayed untouched. One
+// session runs at a time.
+type Recorder struct {
+	caDir string
+
+	mu      sync.Mutex
+	ca      *authority
+	session *session
+	stopped *session // the last stopped session, until it is discarded
+}
+
+// NewRecorder creates a recorder keeping its CA in caDir
+func NewRecorder(caDir string) *Recorder {
+	return &Recorder{caDir: caDir}
+}
+
+// CACert returns the PEM certificate clients must trust to record HTTPS
+func (r *Recorder) CACert() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ca, err := r.authority()
+	if err != nil {
+		return nil, err
+	}
+	return ca.certPEM, nil
+}
+
+func (r *Recorder) authority() (*authority, error) {
+	if r.ca == nil {
+		ca, err := loadAuthority(r.caDir)
+		if err != nil {
+			return nil, err
+		}
+		r.ca = ca
+	}
+	return r.ca, nil
+}
+
+// Start starts the proxy and a new recording. It fails with ErrRecording
+// while another session runs and with ErrAddr for an address other
+// machines could reach.
+func (r *Recorder) Start(opts Options) (Status, error) {
+	addr := opts.Addr
+	if addr == "" {
+		addr = defaultAddr
+	}
+	if !isLoopback(addr) {
+		return Status{}, ErrAddr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session != nil {
+		return Status{}, fmt.Errorf("%w on %s", ErrRecording, r.session.addr)
+	}
+
+	ca, err := r.authority()
+	if err != nil {
+		return Status{}, errors.New("recorder CA: " + err.Error())
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var hosts []string
+	for _, h := range opts.Hosts {
+		if h = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(h), ".")); h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+
+	s := &session{
+		ca:      ca,
+		addr:    ln.Addr().String(),
+		hosts:   hosts,
+		started: time.Now(),
+		conns:   make(map[net.Conn]struct{}),
+		transport: &http.Transport{
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     30 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: opts.Insecure},
+		},
+	}
+	s.server = &http.Server{Handler: s, ErrorLog: quietLog}
+	go s.server.Serve(ln)
+
+	r.session = s
+	r.stopped = nil
+	return s.status(), nil
+}
+
+// isLoopback reports whether addr is host:port with a loopback host
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Stop stops the proxy and returns the recording as a HAR document.
+// The recording is kept until Discard or the next Start, so calling
+// Stop again returns it once more, e.g. to convert it with other
+// filters after a failed import.
+func (r *Recorder) Stop() ([]byte, Status, error) {
+	r.mu.Lock()
+	s := r.session
+	if s != nil {
+		r.session = nil
+		r.stopped = s
+	} else {
+		s = r.stopped
+	}
+	r.mu.Unlock()
+
+	if s == nil {
+		return nil, Status{}, errors.New("no recording is running")
+	}
+	s.close()
+
+	status := s.status()
+	status.Recording = false
+	if status.Requests == 0 {
+		return nil, status, errors.New("no requests were recorded")
+	}
+
+	har, err := s.har()
+	return har, status, err
+}
+
+// Discard drops the last stopped recording once it has been saved
+func (r *Recorder) Discard() {
+	r.mu.Lock()
+	r.stopped = nil
+	r.mu.Unlock()
+}
+
+// Status reports the current recording
+func (r *Recorder) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session == nil {
+		return Status{}
+	}
+	return r.session.status()
+}
+
+// quietLog drops the server's connection errors; clients that do not
+// trust the CA fail every handshake
+var quietLog = log.New(io.Discard, "", 0)
+
+type session struct {
+	ca        *authority
+	addr      string
+	hosts     []string
+	started   time.Time
+	server    *http.Server
+	transport *http.Transport
+
+	mu      sync.Mutex
+	entries []entry
+	conns   map[net.Conn]struct{} // hijacked tunnel connections
+	closed  bool
+}
+
+// entry is one recorded exchange
+type entry struct {
+	started      time.Time
+	duration     time.Duration
+	method       string
+	url          string
+	proto        string
+	header       http.Header
+	body         []byte
+	status       int
+	statusText   string
+	respHeader   http.Header
+	responseSize int64
+}
+
+func (s *session) status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	started := s.started
+	return Status{
+		Recording: !s.closed,
+		Addr:      s.addr,
+		Hosts:     s.hosts,
+		StartedAt: &started,
+		Requests:  len(s.entries),
+	}
+}
+
+func (s *session) close() {
+	s.server.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.transport.CloseIdleConnections()
+}
+
+func (s *session) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		if s.records(hostname(req.Host)) {
+			s.intercept(w, req)
+		} else {
+			s.tunnel(w, req)
+		}
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "this is the recording proxy; configure it as your HTTP proxy", http.StatusBadRequest)
+		return
+	}
+	s.forward(w, req)
+}
+
+// intercept terminates TLS for a CONNECT tunnel with a certificate for
+// the requested host and serves the requests inside it
+func (s *session) intercept(w http.ResponseWriter, req *http.Request) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "tunnelling not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, _, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	if !s.track(conn) {
+		conn.Close()
+		return
+	}
+	defer s.untrack(conn)
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		return
+	}
+
+	host := req.Host
+	tlsConn := tls.Server(conn, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			name := hello.ServerName
+			if name == "" {
+				name = hostname(host)
+			}
+			return s.ca.leaf(name)
+		},
+	})
+
+	inner := &http.Server{
+		ErrorLog: quietLog,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.URL.Scheme = "https"
+			r.URL.Host = r.Host
+			if r.URL.Host == "" {
+				r.URL.Host = host
+			}
+			r.URL.Host = strings.TrimSuffix(r.URL.Host, ":443")
+			s.forward(w, r)
+		}),
+	}
+	ln := &connListener{conn: tlsConn, done: make(chan struct{})}
+	inner.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateClosed || state == http.StateHijacked {
+			ln.Close()
+		}
+	}
+	inner.Serve(ln)
+}
+
+// tunnel relays a CONNECT tunnel to a host that is not recorded
+// without terminating TLS, so clients need not trust the CA for it
+func (s *session) tunnel(w http.ResponseWriter, req *http.Request) {
+	upstream, err := net.DialTimeout("tcp", req.Host, 10*time.Second)
+	if err != nil {
+		http.Error(w, "upstream connection failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !s.track(upstream) {
+		upstream.Close()
+		http.Error(w, "the recording has stopped", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.untrack(upstream)
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunnelling not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if !s.track(conn) {
+		conn.Close()
+		upstream.Close()
+		return
+	}
+	defer s.untrack(conn)
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		conn.Close()
+		upstream.Close()
+		return
+	}
+
+	// Closing both ends once either side is done unblocks the other copy
+	done := make(chan struct{}, 2)
+	go func() {
+		// The client may have sent the start of the handshake already
+		io.Copy(upstream, io.MultiReader(buf, conn))
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+	conn.Close()
+	upstream.Close()
+	<-done
+}
+
+func (s *session) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *session) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+}
+
+// forward sends the request upstream, relays the response and records
+// the exchange
+func (s *session) forward(w http.ResponseWriter, req *http.Request) {
+	started := time.Now()
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	out, err := http.NewRequestWithContext(req.Context(), req.Method, req.URL.String(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out.Header = req.Header.Clone()
+	removeHopHeaders(out.Header)
+	if len(body) == 0 {
+		out.Body = http.NoBody
+	}
+
+	resp, err := s.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, "upstream request failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	respHeader := resp.Header.Clone()
+	removeHopHeaders(respHeader)
+	for k, v := range respHeader {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	n, _ := io.Copy(w, resp.Body)
+
+	if !s.records(req.URL.Hostname()) {
+		return
+	}
+
+	header := req.Header.Clone()
+	removeHopHeaders(header)
+	s.mu.Lock()
+	s.entries = append(s.entries, entry{
+		started:      started,
+		duration:     time.Since(started),
+		method:       req.Method,
+		url:          req.URL.String(),
+		proto:        req.Proto,
+		header:       header,
+		body:         body,
+		status:       resp.StatusCode,
+		statusText:   http.StatusText(resp.StatusCode),
+		respHeader:   respHeader,
+		responseSize: n,
+	})
+	s.mu.Unlock()
+}
+
+// records reports whether traffic to host is part of the recording
+func (s *session) records(host string) bool {
+	if len(s.hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	for _, h := range s.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, name := range strings.Split(h.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			h.Del(name)
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return hostport
+}
+
+// connListener serves a single connection with an http.Server. Accept
+// blocks after handing out the connection until it is closed, so the
+// server keeps running for the connection's lifetime.
+type connListener struct {
+	conn net.Conn
+	once sync.Once
+	done chan struct{}
+	used bool
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	if !l.used {
+		l.used = true
+		return l.conn, nil
+	}
+	<-l.done
+	return nil, net.ErrClosed
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
@@ -0,0 +1,425 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/model"
+)
+
+const (
+	// thresholdsFailedExit is k6's exit code when the run completed but
+	// thresholds failed; the summary is still valid
+	thresholdsFailedExit = 99
+
+	// runGrace bounds how long k6 may overrun the configured duration
+	// (graceful stop, teardown, summary) before it is killed
+	runGrace = 2 * time.Minute
+
+	progressEvery = time.Second
+	tailPoll      = 200 * time.Millisecond
+)
+
+// Progress is a snapshot of a running k6 process, built from its JSON
+// output
+type Progress struct {
+	TestID     string        `json:"testId"`
+	ScriptID   string        `json:"scriptId"`
+	Elapsed    time.Duration `json:"-"`
+	VUs        int           `json:"vus"`
+	Iterations int           `json:"iterations"`
+	Requests   int           `json:"requests"`
+
+	// Done is set on the last report, once k6 has exited
+	Done bool `json:"done"`
+}
+
+// MarshalJSON reports the elapsed time in seconds
+func (p Progress) MarshalJSON() ([]byte, error) {
+	type plain Progress
+	return json.Marshal(struct {
+		plain
+		ElapsedSeconds float64 `json:"elapsedSeconds"`
+	}{plain(p), p.Elapsed.Seconds()})
+}
+
+// K6Runner runs scripts with an external k6 binary instead of the
+// built-in engine
+type K6Runner struct {
+	binary  string
+	k6JSGen *generator.K6JSGenerator
+
+	// OnProgress is called about once a second while k6 runs, and once
+	// more with Done set when it exits
+	OnProgress func(Progress)
+
+	versionMu sync.Mutex
+	version   string
+}
+
+// NewK6Runner creates a runner for the given k6 binary, looked up on
+// PATH when it is not a path
+func NewK6Runner(binary string, gen *generator.K6JSGenerator) *K6Runner {
+	if binary == "" {
+		binary = "k6"
+	}
+	return &K6Runner{
+		binary:  binary,
+		k6JSGen: gen,
+		OnProgress: func(p Progress) {
+			log.Printf("k6 %s: %s elapsed, %d VUs, %d iterations, %d requests",
+				p.ScriptID, p.Elapsed.Round(time.Second), p.VUs, p.Iterations, p.Requests)
+		},
+	}
+}
+
+// Version reports the k6 binary's version, e.g. "k6 v0.52.0", or ""
+// when it cannot be run
+func (k *K6Runner) Version() string {
+	k.versionMu.Lock()
+	defer k.versionMu.Unlock()
+
+	if k.version == "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, k.binary, "version").Output()
+		if err != nil {
+			return ""
+		}
+		// "k6 v0.52.0 (go1.22.4, linux/amd64)" and the extensions
+		// after it; the first two words are the version
+		if f := strings.Fields(string(out)); len(f) >= 2 {
+			k.version = f[0] + " " + f[1]
+		}
+	}
+	return k.version
+}
+
+// Run writes the script as k6 JavaScript to a temp dir, runs k6 on it
+// and maps k6's summary export into a TestResult
+func (k *K6Runner) Run(script *model.Script, config model.TestConfig) (model.TestResult, error) {
+	bin, err := exec.LookPath(k.binary)
+	if err != nil {
+		return model.TestResult{}, errors.New("k6 binary not found: " + k.binary)
+	}
+
+	code, err := k.k6JSGen.Generate(&generator.K6JSInput{Script: script, Config: config})
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "k6run-")
+	if err != nil {
+		return model.TestResult{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	scriptPath := filepath.Join(dir, "script.js")
+	summaryPath := filepath.Join(dir, "summary.json")
+	outPath := filepath.Join(dir, "out.json")
+	if err := os.WriteFile(scriptPath, []byte(code), 0644); err != nil {
+		return model.TestResult{}, err
+	}
+
+	args := []string{
+		"run", "--quiet", "--no-color",
+		"--summary-export", summaryPath,
+		"--summary-trend-stats", "avg,min,med,max,p(90),p(95),p(99)",
+		"--out", "json=" + outPath,
+	}
+	if config.VUs > 0 {
+		args = append(args, "--vus", strconv.Itoa(config.VUs))
+	}
+	if config.Duration > 0 {
+		args = append(args, "--duration", strconv.Itoa(config.Duration)+"s")
+	}
+	args = append(args, scriptPath)
+
+	ctx := context.Background()
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Duration)*time.Second+runGrace)
+		defer cancel()
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Dir = dir
+	cmd.Stdout = io.Discard
+	cmd.Stderr = &stderr
+
+	startedAt := time.Now()
+	if err := cmd.Start(); err != nil {
+		return model.TestResult{}, err
+	}
+
+	done := make(chan struct{})
+	testID := config.TestID
+	if testID == "" {
+		testID = uuid.NewString()
+	}
+	tail := &outputTail{testID: testID, scriptID: config.ScriptID, startedAt: startedAt, onProgress: k.OnProgress}
+	if k.OnProgress != nil {
+		k.OnProgress(tail.progress()) // announce the run before k6 writes output
+	}
+
+	tailed := make(chan struct{})
+	go func() {
+		defer close(tailed)
+		tail.follow(outPath, done)
+	}()
+
+	runErr := cmd.Wait()
+	close(done)
+	<-tailed
+
+	if k.OnProgress != nil {
+		final := tail.progress()
+		final.Done = true
+		k.OnProgress(final)
+	}
+
+	var exitErr *exec.ExitError
+	if runErr != nil && !(errors.As(runErr, &exitErr) && exitErr.ExitCode() == thresholdsFailedExit) {
+		return model.TestResult{}, errors.New("k6 failed: " + lastLines(stderr.String(), 5))
+	}
+
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		return model.TestResult{}, errors.New("k6 wrote no summary: " + lastLines(stderr.String(), 5))
+	}
+
+	result, err := mapSummary(data, tail.counts)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+	result.TestID = testID
+	result.ScriptID = config.ScriptID
+	result.StartedAt = startedAt
+	return result, nil
+}
+
+// outputTail follows k6's JSON output while the process runs, counting
+// samples per metric and reporting progress
+type outputTail struct {
+	testID     string
+	scriptID   string
+	startedAt  time.Time
+	onProgress func(Progress)
+
+	mu     sync.Mutex
+	counts map[string]int
+	vus    int
+}
+
+type k6Point struct {
+	Type   string `json:"type"`
+	Metric string `json:"metric"`
+	Data   struct {
+		Value float64 `json:"value"`
+	} `json:"data"`
+}
+
+// follow reads the output file until done is closed and the file has
+// been read to the end
+func (t *outputTail) follow(path string, done <-chan struct{}) {
+	t.counts = make(map[string]int)
+
+	var f *os.File
+	for f == nil {
+		var err error
+		if f, err = os.Open(path); err == nil {
+			break
+		}
+		select {
+		case <-done:
+			return // k6 exited before writing any output
+		case <-time.After(tailPoll):
+		}
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	lastReport := time.Now()
+	var partial []byte
+	finished := false
+
+	for {
+		line, err := r.ReadBytes('\n')
+		partial = append(partial, line...)
+
+		if err == nil {
+			t.add(partial)
+			partial = nil
+
+			if t.onProgress != nil && time.Since(lastReport) >= progressEvery {
+				t.onProgress(t.progress())
+				lastReport = time.Now()
+			}
+			continue
+		}
+
+		// At EOF: wait for more unless k6 has exited, then drain once
+		if finished {
+			return
+		}
+		select {
+		case <-done:
+			finished = true
+		case <-time.After(tailPoll):
+		}
+	}
+}
+
+func (t *outputTail) add(line []byte) {
+	var p k6Point
+	if json.Unmarshal(line, &p) != nil || p.Type != "Point" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.counts[p.Metric]++
+	if p.Metric == "vus" {
+		t.vus = int(p.Data.Value)
+	}
+}
+
+func (t *outputTail) progress() Progress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return Progress{
+		TestID:     t.testID,
+		ScriptID:   t.scriptID,
+		Elapsed:    time.Since(t.startedAt),
+		VUs:        t.vus,
+		Iterations: t.counts["iterations"],
+		Requests:   t.counts["http_reqs"],
+	}
+}
+
+// k6Summary is the --summary-export format. Each metric holds its
+// aggregates (avg, p(95), count, rate, passes, ...) and, when the
+// script declares them, a map of threshold expression to whether it
+// failed.
+type k6Summary struct {
+	Metrics map[string]map[string]json.RawMessage `json:"metrics"`
+}
+
+var thresholdAgg = regexp.MustCompile(`^\s*(avg|min|max|med|count|rate|value|p\(\s*[\d.]+\s*\))`)
+
+func mapSummary(data []byte, counts map[string]int) (model.TestResult, error) {
+	var summary k6Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return model.TestResult{}, errors.New("invalid k6 summary: " + err.Error())
+	}
+
+	num := func(metric, key string) float64 {
+		var v float64
+		json.Unmarshal(summary.Metrics[metric][key], &v)
+		return v
+	}
+
+	var result model.TestResult
+	result.TotalRequests = int(num("http_reqs", "count"))
+	result.RPS = num("http_reqs", "rate")
+	result.Iterations = int(num("iterations", "count"))
+
+	// http_req_failed is a rate of failed requests, so its "passes"
+	// are the failures
+	result.Failure = int(num("http_req_failed", "passes"))
+	result.Success = result.TotalRequests - result.Failure
+
+	result.AvgLatencyMs = int64(num("http_req_duration", "avg"))
+	result.P90LatencyMs = int64(num("http_req_duration", "p(90)"))
+	result.P95LatencyMs = int64(num("http_req_duration", "p(95)"))
+	result.P99LatencyMs = int64(num("http_req_duration", "p(99)"))
+
+	names := make([]string, 0, len(summary.Metrics))
+	for name := range summary.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		m := summary.Metrics[name]
+
+		switch {
+		case m["avg"] != nil:
+			if result.Metrics == nil {
+				result.Metrics = make(map[string]model.MetricSummary)
+			}
+			result.Metrics[name] = model.MetricSummary{
+				Count: counts[name],
+				Avg:   num(name, "avg"),
+				Min:   num(name, "min"),
+				Max:   num(name, "max"),
+				P90:   num(name, "p(90)"),
+				P95:   num(name, "p(95)"),
+				P99:   num(name, "p(99)"),
+			}
+		case m["count"] != nil:
+			if result.Counters == nil {
+				result.Counters = make(map[string]int64)
+			}
+			result.Counters[name] = int64(num(name, "count"))
+		}
+
+		var thresholds map[string]bool
+		if json.Unmarshal(m["thresholds"], &thresholds) != nil {
+			continue
+		}
+		exprs := make([]string, 0, len(thresholds))
+		for expr := range thresholds {
+			exprs = append(exprs, expr)
+		}
+		sort.Strings(exprs)
+
+		for _, expr := range exprs {
+			res := model.ThresholdResult{
+				Metric:    name,
+				Threshold: expr,
+				Passed:    !thresholds[expr],
+			}
+			if agg := thresholdAgg.FindStringSubmatch(expr); agg != nil {
+				key := strings.ReplaceAll(agg[1], " ", "")
+				if key == "rate" && m["rate"] == nil {
+					key = "value" // rate metrics export their rate as "value"
+				}
+				res.Value = num(name, key)
+			}
+			result.Thresholds = append(result.Thresholds, res)
+		}
+	}
+	return result, nil
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	out := strings.Join(lines, "\n")
+	if out == "" {
+		return "no output"
+	}
+	return out
+}
@@ -0,0 +1,319 @@
+package runner
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"k6clone/internal/core/generator"
+	"k6clone/internal/core/model"
+)
+
+// fakeK6 answers "k6 version" and, for "k6 run", writes canned JSON
+// output and a summary export, then exits with $FAKE_K6_EXIT. Its
+// arguments are saved to args.txt next to it.
+const fakeK6 = `#!/bin/sh
+dir=$(dirname "$0")
+if [ "$1" = "version" ]; then
+  echo "k6 v0.99.0 (go1.22.0, linux/amd64)"
+  exit 0
+fi
+echo "$@" > "$dir/args.txt"
+
+summary= out=
+while [ $# -gt 0 ]; do
+  case "$1" in
+    --summary-export) summary=$2; shift ;;
+    --out) out=${2#json=}; shift ;;
+  esac
+  shift
+done
+
+if [ "$FAKE_K6_EXIT" = "1" ]; then
+  echo "some warning" >&2
+  echo "ERRO[0000] script exception" >&2
+  exit 1
+fi
+
+point() { echo "{\"type\":\"Point\",\"metric\":\"$1\",\"data\":{\"value\":$2}}" >> "$out"; }
+echo '{"type":"Metric","metric":"http_reqs","data":{}}' > "$out"
+point vus 3
+point http_reqs 1
+point http_req_duration 100
+point http_reqs 1
+point http_req_duration 200
+point iterations 1
+sleep 1.2
+point http_reqs 1
+point http_req_duration 300
+point http_req_duration 400
+point iterations 1
+point login_duration 50
+
+cat > "$summary" <<'EOF'
+{
+  "metrics": {
+    "http_reqs": {"count": 4, "rate": 2.5},
+    "iterations": {"count": 2, "rate": 1},
+    "http_req_failed": {"passes": 1, "fails": 3, "value": 0.25,
+      "thresholds": {"rate<0.1": true}},
+    "http_req_duration": {"avg": 250, "min": 100, "med": 250, "max": 400,
+      "p(90)": 370, "p(95)": 385, "p(99)": 397,
+      "thresholds": {"p(95)<500": false}},
+    "login_duration": {"avg": 50, "min": 50, "med": 50, "max": 50,
+      "p(90)": 50, "p(95)": 50, "p(99)": 50},
+    "ws_msgs_sent": {"count": 7, "rate": 3.5}
+  }
+}
+EOF
+exit ${FAKE_K6_EXIT:-0}
+`
+
+func newFakeK6(t *testing.T) (*K6Runner, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake k6 is a shell script")
+	}
+
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "k6")
+	if err := os.WriteFile(bin, []byte(fakeK6), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return NewK6Runner(bin, generator.NewK6JSGenerator()), dir
+}
+
+func httpScript() *model.Script {
+	return &model.Script{ID: "s1", Steps: []model.Step{{Type: model.HTTP, Method: "GET", URL: "http://localhost/"}}}
+}
+
+func TestK6RunnerMapsSummary(t *testing.T) {
+	k, dir := newFakeK6(t)
+
+	var mu sync.Mutex
+	var reports []Progress
+	k.OnProgress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	}
+
+	result, err := k.Run(httpScript(), model.TestConfig{ScriptID: "s1", VUs: 3, Duration: 2})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(dir, "args.txt"))
+	for _, want := range []string{"--vus 3", "--duration 2s", "--summary-export", "--out json="} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("args %q missing %q", args, want)
+		}
+	}
+
+	if result.ScriptID != "s1" || result.TestID == "" || result.StartedAt.IsZero() {
+		t.Errorf("identity not set: %+v", result)
+	}
+	if result.TotalRequests != 4 || result.Failure != 1 || result.Success != 3 {
+		t.Errorf("requests = %d, failure = %d, success = %d; want 4, 1, 3",
+			result.TotalRequests, result.Failure, result.Success)
+	}
+	if result.RPS != 2.5 || result.Iterations != 2 {
+		t.Errorf("rps = %v, iterations = %d; want 2.5, 2", result.RPS, result.Iterations)
+	}
+	if result.AvgLatencyMs != 250 || result.P90LatencyMs != 370 || result.P95LatencyMs != 385 || result.P99LatencyMs != 397 {
+		t.Errorf("latencies = %d/%d/%d/%d", result.AvgLatencyMs, result.P90LatencyMs, result.P95LatencyMs, result.P99LatencyMs)
+	}
+
+	// Trend counts come from the JSON output, not the summary
+	if m := result.Metrics["http_req_duration"]; m.Count != 4 || m.Max != 400 {
+		t.Errorf("http_req_duration = %+v, want count 4 and max 400", m)
+	}
+	if m := result.Metrics["login_duration"]; m.Count != 1 || m.Avg != 50 {
+		t.Errorf("login_duration = %+v, want count 1 and avg 50", m)
+	}
+	if got := result.Counters["ws_msgs_sent"]; got != 7 {
+		t.Errorf("ws_msgs_sent = %d, want 7", got)
+	}
+
+	thresholds := map[string]model.ThresholdResult{}
+	for _, th := range result.Thresholds {
+		thresholds[th.Metric+" "+th.Threshold] = th
+	}
+	if th := thresholds["http_req_duration p(95)<500"]; !th.Passed || th.Value != 385 {
+		t.Errorf("p(95)<500 = %+v, want passed with value 385", th)
+	}
+	if th := thresholds["http_req_failed rate<0.1"]; th.Passed || th.Value != 0.25 {
+		t.Errorf("rate<0.1 = %+v, want failed with value 0.25", th)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) < 2 {
+		t.Fatalf("got %d progress reports, want a start and a final one", len(reports))
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.TestID != result.TestID || last.VUs != 3 || last.Requests != 3 || last.Iterations != 2 {
+		t.Errorf("final progress = %+v", last)
+	}
+	for _, p := range reports[:len(reports)-1] {
+		if p.Done {
+			t.Errorf("progress before the end marked done: %+v", p)
+		}
+	}
+}
+
+func TestK6RunnerThresholdsFailedExit(t *testing.T) {
+	k, _ := newFakeK6(t)
+	k.OnProgress = nil
+	t.Setenv("FAKE_K6_EXIT", "99")
+
+	result, err := k.Run(httpScript(), model.TestConfig{ScriptID: "s1", VUs: 1, Duration: 1})
+	if err != nil {
+		t.Fatalf("exit 99 means thresholds failed, not the run: %v", err)
+	}
+	if result.TotalRequests != 4 {
+		t.Errorf("requests = %d, want 4", result.TotalRequests)
+	}
+}
+
+func TestK6RunnerFailure(t *testing.T) {
+	k, _ := newFakeK6(t)
+	k.OnProgress = nil
+	t.Setenv("FAKE_K6_EXIT", "1")
+
+	_, err := k.Run(httpScript(), model.TestConfig{ScriptID: "s1", VUs: 1, Duration: 1})
+	if err == nil || !strings.Contains(err.Error(), "script exception") {
+		t.Errorf("err = %v, want k6's stderr", err)
+	}
+}
+
+func TestK6RunnerVersion(t *testing.T) {
+	k, _ := newFakeK6(t)
+	if got := k.Version(); got != "k6 v0.99.0" {
+		t.Errorf("Version = %q, want k6 v0.99.0", got)
+	}
+
+	missing := NewK6Runner(filepath.Join(t.TempDir(), "nope"), generator.NewK6JSGenerator())
+	if got := missing.Version(); got != "" {
+		t.Errorf("Version of a missing binary = %q, want empty", got)
+	}
+}
+
+func TestProgressHub(t *testing.T) {
+	hub := NewProgressHub()
+	if _, _, ok := hub.Subscribe("t1"); ok {
+		t.Fatal("subscribed to a test that is not running")
+	}
+
+	hub.Publish(Progress{TestID: "t1", Requests: 1})
+	updates, cancel, ok := hub.Subscribe("t1")
+	if !ok {
+		t.Fatal("could not subscribe to a running test")
+	}
+	defer cancel()
+
+	if p := <-updates; p.Requests != 1 {
+		t.Errorf("first update = %+v, want the latest report", p)
+	}
+	if running := hub.Running(); len(running) != 1 || running[0].TestID != "t1" {
+		t.Errorf("Running = %+v", running)
+	}
+
+	// A slow subscriber only sees the newest report
+	hub.Publish(Progress{TestID: "t1", Requests: 2})
+	hub.Publish(Progress{TestID: "t1", Requests: 3})
+	if p := <-updates; p.Requests != 3 {
+		t.Errorf("update = %+v, want requests 3", p)
+	}
+
+	hub.Publish(Progress{TestID: "t1", Requests: 4, Done: true})
+	if p := <-updates; !p.Done {
+		t.Errorf("update = %+v, want done", p)
+	}
+	if _, open := <-updates; open {
+		t.Error("channel still open after done")
+	}
+	if running := hub.Running(); len(running) != 0 {
+		t.Errorf("Running after done = %+v", running)
+	}
+}
+
+func TestProgressHubConcurrentTests(t *testing.T) {
+	hub := NewProgressHub()
+	hub.Publish(Progress{TestID: "a", Requests: 1})
+	hub.Publish(Progress{TestID: "b", Requests: 10})
+
+	a, cancelA, _ := hub.Subscribe("a")
+	defer cancelA()
+	b, cancelB, _ := hub.Subscribe("b")
+	defer cancelB()
+	<-a
+	<-b
+
+	// The first test ending leaves the second one running
+	hub.Publish(Progress{TestID: "a", Requests: 2, Done: true})
+	if p := <-a; !p.Done || p.Requests != 2 {
+		t.Errorf("a = %+v, want done with 2 requests", p)
+	}
+	if _, open := <-a; open {
+		t.Error("a still open after done")
+	}
+
+	hub.Publish(Progress{TestID: "b", Requests: 11})
+	if p, open := <-b; !open || p.Done || p.Requests != 11 {
+		t.Errorf("b = %+v (open %v), want a running report with 11 requests", p, open)
+	}
+	if running := hub.Running(); len(running) != 1 || running[0].TestID != "b" {
+		t.Errorf("Running = %+v, want only b", running)
+	}
+}
+
+func TestK6RunnerTestID(t *testing.T) {
+	k, _ := newFakeK6(t)
+
+	var mu sync.Mutex
+	ids := map[string]bool{}
+	k.OnProgress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		ids[p.TestID] = true
+	}
+
+	result, err := k.Run(httpScript(), model.TestConfig{ScriptID: "s1", VUs: 1, Duration: 1, TestID: "given"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TestID != "given" {
+		t.Errorf("TestID = %q, want the one passed in", result.TestID)
+	}
+
+	// Without one, runs started in the same second still differ
+	var wg sync.WaitGroup
+	generated := make([]string, 2)
+	for i := range generated {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := k.Run(httpScript(), model.TestConfig{ScriptID: "s1", VUs: 1, Duration: 1})
+			if err != nil {
+				t.Error(err)
+			}
+			generated[i] = result.TestID
+		}()
+	}
+	wg.Wait()
+	if generated[0] == "" || generated[0] == generated[1] {
+		t.Errorf("concurrent runs got test IDs %q", generated)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range append(generated, "given") {
+		if !ids[id] {
+			t.Errorf("no progress reported for %q", id)
+		}
+	}
+}
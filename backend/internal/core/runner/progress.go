@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"sort"
+	"sync"
+)
+
+// ProgressHub keeps the latest progress of each running k6 test and
+// passes it on to subscribers, so API clients can follow a run
+type ProgressHub struct {
+	mu     sync.Mutex
+	latest map[string]Progress
+	subs   map[string][]chan Progress
+}
+
+func NewProgressHub() *ProgressHub {
+	return &ProgressHub{
+		latest: make(map[string]Progress),
+		subs:   make(map[string][]chan Progress),
+	}
+}
+
+// Publish records a progress report. Subscribers that have not read
+// the previous report only get the newest one. A Done report ends the
+// test's subscriptions.
+func (h *ProgressHub) Publish(p Progress) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, ch := range h.subs[p.TestID] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- p
+	}
+
+	if p.Done {
+		for _, ch := range h.subs[p.TestID] {
+			close(ch)
+		}
+		delete(h.subs, p.TestID)
+		delete(h.latest, p.TestID)
+		return
+	}
+	h.latest[p.TestID] = p
+}
+
+// Running returns the latest progress of every running test
+func (h *ProgressHub) Running() []Progress {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	running := make([]Progress, 0, len(h.latest))
+	for _, p := range h.latest {
+		running = append(running, p)
+	}
+	sort.Slice(running, func(i, j int) bool { return running[i].TestID < running[j].TestID })
+	return running
+}
+
+// Subscribe follows a running test. The channel starts with the latest
+// report and is closed after the Done report. ok is false when the
+// test is not running. cancel stops the subscription early.
+func (h *ProgressHub) Subscribe(testID string) (updates <-chan Progress, cancel func(), ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	latest, ok := h.latest[testID]
+	if !ok {
+		return nil, nil, false
+	}
+
+	ch := make(chan Progress, 1)
+	ch <- latest
+	h.subs[testID] = append(h.subs[testID], ch)
+
+	cancel = func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		subs := h.subs[testID]
+		for i, c := range subs {
+			if c == ch {
+				h.subs[testID] = append(subs[:i], subs[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, cancel, true
+}
@@ -2,16 +2,26 @@ package repository
 
 import (
 	"encoding/json"
-	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 
 	"k6clone/internal/core/model"
 )
 
+// indexFile lists every script's metadata so listing and filtering
+// don't read each script file. It has no .json extension so it is never
+// taken for a script.
+const indexFile = ".index"
+
+// FileScriptRepository stores each script in <dir>/<id>.json. Scripts
+// are read when first used and then kept in memory; the index holds
+// what listing needs for all of them.
 type FileScriptRepository struct {
 	data       map[string]*model.Script
+	index      map[string]scriptEntry
 	scriptsDir string
 	mu         sync.RWMutex
 }
@@ -22,11 +32,13 @@ func NewFileScriptRepository(dir string) *FileScriptRepository {
 
 	repo := &FileScriptRepository{
 		data:       make(map[string]*model.Script),
+		index:      make(map[string]scriptEntry),
 		scriptsDir: dir,
 	}
 
-	// Load existing scripts from disk
-	repo.loadFromDisk()
+	// Load the index, catching up with script files changed outside
+	// the repository
+	repo.loadIndex()
 
 	return repo
 }
@@ -37,33 +49,125 @@ func (r *FileScriptRepository) Save(script *model.Script) error {
 
 	// Save to memory
 	r.data[script.ID] = script
+	r.index[script.ID] = entryOf(script)
 
 	// Save to disk
-	return r.saveToDisk(script)
+	if err := r.saveToDisk(script); err != nil {
+		return err
+	}
+	return r.saveIndex()
 }
 
 func (r *FileScriptRepository) FindByID(id string) (*model.Script, error) {
 	r.mu.RLock()
-	defer r.mu.RUnlock()
-
 	script, ok := r.data[id]
-	if !ok {
-		return nil, errors.New("script not found")
+	r.mu.RUnlock()
+	if ok {
+		return script, nil
 	}
-	return script, nil
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.load(id)
 }
 
 func (r *FileScriptRepository) FindAll() ([]*model.Script, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
 	var scripts []*model.Script
-	for _, s := range r.data {
+	for id := range r.index {
+		s, err := r.load(id)
+		if err != nil {
+			continue
+		}
 		scripts = append(scripts, s)
 	}
 	return scripts, nil
 }
 
+func (r *FileScriptRepository) List(q ScriptQuery) ([]*model.Script, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]scriptEntry, 0, len(r.index))
+	for _, e := range r.index {
+		entries = append(entries, e)
+	}
+
+	ids, total, err := queryEntries(entries, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Only the scripts on the page are read
+	scripts := make([]*model.Script, 0, len(ids))
+	for _, id := range ids {
+		s, err := r.load(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		scripts = append(scripts, s)
+	}
+	return scripts, total, nil
+}
+
+func (r *FileScriptRepository) Update(script *model.Script, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.load(script.ID)
+	if err != nil {
+		return err
+	}
+	if current.Version != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	script.Version = current.Version + 1
+	r.data[script.ID] = script
+	r.index[script.ID] = entryOf(script)
+
+	if err := r.saveToDisk(script); err != nil {
+		return err
+	}
+	return r.saveIndex()
+}
+
+func (r *FileScriptRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.index[id]; !ok {
+		return ErrScriptNotFound
+	}
+
+	if err := os.Remove(filepath.Join(r.scriptsDir, id+".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(r.data, id)
+	delete(r.index, id)
+	return r.saveIndex()
+}
+
+// load returns a script from memory or reads it from disk. The caller
+// holds the write lock.
+func (r *FileScriptRepository) load(id string) (*model.Script, error) {
+	if script, ok := r.data[id]; ok {
+		return script, nil
+	}
+	if _, ok := r.index[id]; !ok {
+		return nil, ErrScriptNotFound
+	}
+
+	script, err := r.readScript(filepath.Join(r.scriptsDir, id+".json"))
+	if err != nil {
+		return nil, err
+	}
+	r.data[id] = script
+	return script, nil
+}
+
 func (r *FileScriptRepository) saveToDisk(script *model.Script) error {
 	path := filepath.Join(r.scriptsDir, script.ID+".json")
 
@@ -75,30 +179,106 @@ func (r *FileScriptRepository) saveToDisk(script *model.Script) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-func (r *FileScriptRepository) loadFromDisk() error {
+// saveIndex writes the index to a temp file first so a crash never
+// leaves half of it behind
+func (r *FileScriptRepository) saveIndex() error {
+	entries := make([]scriptEntry, 0, len(r.index))
+	for _, e := range r.index {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(r.scriptsDir, indexFile)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// loadIndex reads the index and then only the script files that are
+// missing from it or changed after it was written
+func (r *FileScriptRepository) loadIndex() error {
+	path := filepath.Join(r.scriptsDir, indexFile)
+	var written int64
+	if data, err := os.ReadFile(path); err == nil {
+		var entries []scriptEntry
+		if json.Unmarshal(data, &entries) == nil {
+			for _, e := range entries {
+				r.index[e.ID] = e
+			}
+			if info, err := os.Stat(path); err == nil {
+				written = info.ModTime().UnixNano()
+			}
+		}
+	}
+
 	files, err := os.ReadDir(r.scriptsDir)
 	if err != nil {
 		return err
 	}
 
+	changed := false
+	onDisk := make(map[string]bool, len(files))
 	for _, file := range files {
 		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
 			continue
 		}
+		id := strings.TrimSuffix(file.Name(), ".json")
+		onDisk[id] = true
 
-		path := filepath.Join(r.scriptsDir, file.Name())
-		data, err := os.ReadFile(path)
+		info, err := file.Info()
 		if err != nil {
 			continue
 		}
+		if _, ok := r.index[id]; ok && info.ModTime().UnixNano() <= written {
+			continue
+		}
 
-		var script model.Script
-		if err := json.Unmarshal(data, &script); err != nil {
+		script, err := r.readScript(filepath.Join(r.scriptsDir, file.Name()))
+		if err != nil || script.ID != id {
 			continue
 		}
+		r.data[id] = script
+		r.index[id] = entryOf(script)
+		changed = true
+	}
 
-		r.data[script.ID] = &script
+	for id := range r.index {
+		if !onDisk[id] {
+			delete(r.index, id)
+			changed = true
+		}
 	}
 
+	if changed {
+		return r.saveIndex()
+	}
 	return nil
 }
+
+// readScript reads a script file. Scripts saved before timestamps
+// existed take them from the file.
+func (r *FileScriptRepository) readScript(path string) (*model.Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var script model.Script
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, err
+	}
+
+	if script.CreatedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			script.CreatedAt = info.ModTime()
+			script.UpdatedAt = info.ModTime()
+		}
+	}
+	return &script, nil
+}
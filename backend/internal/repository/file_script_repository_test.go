@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+func writeScriptFile(t *testing.T, dir string, script model.Script, modTime time.Time) {
+	t.Helper()
+
+	path := filepath.Join(dir, script.ID+".json")
+	data, err := json.Marshal(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileScriptRepositoryIndexCatchesUp(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileScriptRepository(dir)
+	for _, s := range []*model.Script{
+		{ID: "edited", Name: "before", Tags: []string{"old"}},
+		{ID: "deleted", Name: "gone"},
+		{ID: "untouched", Name: "same"},
+	} {
+		if err := repo.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatalf("no index written: %v", err)
+	}
+	indexed := info.ModTime()
+
+	// Changed by hand after the index was written
+	writeScriptFile(t, dir, model.Script{ID: "edited", Name: "after", Tags: []string{"new"}}, indexed.Add(time.Second))
+	writeScriptFile(t, dir, model.Script{ID: "added", Name: "added"}, indexed.Add(time.Second))
+	if err := os.Remove(filepath.Join(dir, "deleted.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file older than the index is taken from the index, not re-read
+	writeScriptFile(t, dir, model.Script{ID: "untouched", Name: "not re-read"}, indexed.Add(-time.Second))
+
+	reopened := NewFileScriptRepository(dir)
+	scripts, total, err := reopened.List(ScriptQuery{Sort: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 {
+		t.Errorf("total = %d, want 3", total)
+	}
+
+	names := map[string]string{}
+	for _, s := range scripts {
+		names[s.ID] = s.Name
+	}
+	if names["edited"] != "after" || names["added"] != "added" {
+		t.Errorf("scripts = %v, want the edited and added files re-read", names)
+	}
+	if _, ok := names["deleted"]; ok {
+		t.Error("deleted script still listed")
+	}
+	if _, err := reopened.FindByID("deleted"); !errors.Is(err, ErrScriptNotFound) {
+		t.Errorf("FindByID(deleted) = %v, want ErrScriptNotFound", err)
+	}
+
+	if _, total, _ := reopened.List(ScriptQuery{Tag: "new"}); total != 1 {
+		t.Errorf("tag new matched %d scripts, want the edited one", total)
+	}
+	if _, total, _ := reopened.List(ScriptQuery{Tag: "old"}); total != 0 {
+		t.Errorf("tag old matched %d scripts, want none", total)
+	}
+	if _, total, _ := reopened.List(ScriptQuery{Search: "not re-read"}); total != 0 {
+		t.Error("a file older than the index was re-read")
+	}
+
+	// The caught-up index is written back for the next start
+	data, err := os.ReadFile(filepath.Join(dir, indexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []scriptEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	if want := []string{"added", "edited", "untouched"}; len(ids) != 3 || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Errorf("index = %v, want %v", ids, want)
+	}
+}
+
+func TestFileScriptRepositoryWithoutIndex(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	writeScriptFile(t, dir, model.Script{ID: "legacy", Name: "legacy"}, modTime)
+
+	// Scripts saved before timestamps existed take the file's
+	repo := NewFileScriptRepository(dir)
+	script, err := repo.FindByID("legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !script.CreatedAt.Equal(modTime) || !script.UpdatedAt.Equal(modTime) {
+		t.Errorf("timestamps = %v/%v, want %v", script.CreatedAt, script.UpdatedAt, modTime)
+	}
+	if _, err := os.Stat(filepath.Join(dir, indexFile)); err != nil {
+		t.Errorf("index not written: %v", err)
+	}
+}
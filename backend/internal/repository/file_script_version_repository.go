@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"k6clone/internal/core/model"
+)
+
+// FileScriptVersionRepository stores each version in its own file,
+// <dir>/<scriptID>/<version>.json, so saving never rewrites history
+type FileScriptVersionRepository struct {
+	versionsDir string
+	mu          sync.RWMutex
+}
+
+func NewFileScriptVersionRepository(dir string) *FileScriptVersionRepository {
+	// Ensure directory exists
+	os.MkdirAll(dir, 0755)
+
+	return &FileScriptVersionRepository{
+		versionsDir: dir,
+	}
+}
+
+func (r *FileScriptVersionRepository) Save(scriptID string, version model.ScriptVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dir := filepath.Join(r.versionsDir, scriptID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(version, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, strconv.Itoa(version.Version)+".json"), data, 0644)
+}
+
+func (r *FileScriptVersionRepository) FindByScriptID(scriptID string) ([]model.ScriptVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	files, err := os.ReadDir(filepath.Join(r.versionsDir, scriptID))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []model.ScriptVersion
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+
+		v, err := r.read(scriptID, n)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, *v)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+
+	return versions, nil
+}
+
+func (r *FileScriptVersionRepository) Find(scriptID string, version int) (*model.ScriptVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.read(scriptID, version)
+}
+
+func (r *FileScriptVersionRepository) DeleteByScriptID(scriptID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return os.RemoveAll(filepath.Join(r.versionsDir, scriptID))
+}
+
+func (r *FileScriptVersionRepository) read(scriptID string, version int) (*model.ScriptVersion, error) {
+	data, err := os.ReadFile(filepath.Join(r.versionsDir, scriptID, strconv.Itoa(version)+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var v model.ScriptVersion
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
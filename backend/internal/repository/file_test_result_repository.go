@@ -92,9 +92,60 @@ func (r *FileTestResultRepository) FindByScriptID(scriptID string) []model.TestR
 	return filtered
 }
 
+func (r *FileTestResultRepository) FindByTestID(testID string) (model.TestResult, bool) {
+	for _, result := range r.FindAll() {
+		if result.TestID == testID {
+			return result, true
+		}
+	}
+	return model.TestResult{}, false
+}
+
+func (r *FileTestResultRepository) DeleteByScriptID(scriptID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files, err := os.ReadDir(r.resultsDir)
+	if err != nil {
+		return 0
+	}
+
+	deleted := 0
+	for _, file := range files {
+		// Script IDs may contain dashes, so match on the stored ID
+		// rather than the file name
+		if file.IsDir() || !strings.HasPrefix(file.Name(), "result-"+scriptID+"-") {
+			continue
+		}
+
+		path := filepath.Join(r.resultsDir, file.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var result model.TestResult
+		if err := json.Unmarshal(data, &result); err != nil || result.ScriptID != scriptID {
+			continue
+		}
+
+		if os.Remove(path) == nil {
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// generateFilename names a result after its test, so results started in
+// the same second don't overwrite each other. Results without a test ID
+// fall back to their start time.
 func (r *FileTestResultRepository) generateFilename(result model.TestResult) string {
-	timestamp := result.StartedAt.Format("20060102-150405")
-	return "result-" + result.ScriptID + "-" + timestamp + ".json"
+	id := result.TestID
+	if id == "" {
+		id = result.StartedAt.Format("20060102-150405")
+	}
+	return "result-" + result.ScriptID + "-" + id + ".json"
 }
 
 // Cleanup removes results older than the specified duration
@@ -1,7 +1,6 @@
 package repository
 
 import (
-	"errors"
 	"sync"
 
 	"k6clone/internal/core/model"
@@ -31,7 +30,7 @@ func (r *MemoryScriptRepository) FindByID(id string) (*model.Script, error) {
 
 	script, ok := r.data[id]
 	if !ok {
-		return nil, errors.New("script not found")
+		return nil, ErrScriptNotFound
 	}
 	return script, nil
 }
@@ -46,3 +45,52 @@ func (r *MemoryScriptRepository) FindAll() ([]*model.Script, error) {
 	}
 	return scripts, nil
 }
+
+func (r *MemoryScriptRepository) List(q ScriptQuery) ([]*model.Script, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]scriptEntry, 0, len(r.data))
+	for _, s := range r.data {
+		entries = append(entries, entryOf(s))
+	}
+
+	ids, total, err := queryEntries(entries, q)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	scripts := make([]*model.Script, len(ids))
+	for i, id := range ids {
+		scripts[i] = r.data[id]
+	}
+	return scripts, total, nil
+}
+
+func (r *MemoryScriptRepository) Update(script *model.Script, expectedVersion int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.data[script.ID]
+	if !ok {
+		return ErrScriptNotFound
+	}
+	if current.Version != expectedVersion {
+		return ErrVersionConflict
+	}
+
+	script.Version = current.Version + 1
+	r.data[script.ID] = script
+	return nil
+}
+
+func (r *MemoryScriptRepository) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.data[id]; !ok {
+		return ErrScriptNotFound
+	}
+	delete(r.data, id)
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ProtoRepository stores uploaded .proto files used by GRPC steps
+type ProtoRepository interface {
+	Save(name string, content []byte) error
+	FindAll() ([]string, error)
+}
+
+type FileProtoRepository struct {
+	protoDir string
+	mu       sync.Mutex
+}
+
+func NewFileProtoRepository(dir string) *FileProtoRepository {
+	// Ensure directory exists
+	os.MkdirAll(dir, 0755)
+
+	return &FileProtoRepository{
+		protoDir: dir,
+	}
+}
+
+// Save writes a proto file. Names may contain directories so that
+// import paths like "google/api/annotations.proto" resolve.
+func (r *FileProtoRepository) Save(name string, content []byte) error {
+	clean, err := cleanProtoName(name)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := filepath.Join(r.protoDir, clean)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// FindAll lists stored proto files by their import path
+func (r *FileProtoRepository) FindAll() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var names []string
+	err := filepath.WalkDir(r.protoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".proto" {
+			return err
+		}
+		rel, err := filepath.Rel(r.protoDir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+
+	sort.Strings(names)
+	return names, err
+}
+
+func cleanProtoName(name string) (string, error) {
+	if filepath.Ext(name) != ".proto" {
+		return "", errors.New("proto file name must end in .proto")
+	}
+
+	clean := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("invalid proto file name")
+	}
+	return clean, nil
+}
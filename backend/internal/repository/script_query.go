@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"k6clone/internal/core/model"
+)
+
+// ScriptQuery selects, orders and pages scripts for listing
+type ScriptQuery struct {
+	// Search matches name, description, tags and ID, ignoring case
+	Search string
+
+	Tag   string
+	Owner string
+
+	// Project matches the project and the folders nested in it
+	Project string
+
+	// Sort is "name", "createdAt" or "updatedAt", prefixed with "-" for
+	// descending order. Defaults to "-updatedAt".
+	Sort string
+
+	// Offset and Limit page the results; a Limit of 0 returns them all
+	Offset int
+	Limit  int
+}
+
+var ErrInvalidSort = errors.New(`sort must be "name", "createdAt" or "updatedAt", optionally prefixed with "-"`)
+
+// scriptEntry is what listing needs to know about a script without
+// loading its steps
+type scriptEntry struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Project     string    `json:"project,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func entryOf(script *model.Script) scriptEntry {
+	return scriptEntry{
+		ID:          script.ID,
+		Name:        script.Name,
+		Description: script.Description,
+		Tags:        script.Tags,
+		Owner:       script.Owner,
+		Project:     script.Project,
+		CreatedAt:   script.CreatedAt,
+		UpdatedAt:   script.UpdatedAt,
+	}
+}
+
+// queryEntries returns the IDs of the page of entries q selects and the
+// number of entries that matched before paging
+func queryEntries(entries []scriptEntry, q ScriptQuery) ([]string, int, error) {
+	less, err := entryOrder(q.Sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	search := strings.ToLower(strings.TrimSpace(q.Search))
+	var matched []scriptEntry
+	for _, e := range entries {
+		if q.Tag != "" && !hasTag(e.Tags, q.Tag) {
+			continue
+		}
+		if q.Owner != "" && !strings.EqualFold(e.Owner, q.Owner) {
+			continue
+		}
+		if q.Project != "" && e.Project != q.Project && !strings.HasPrefix(e.Project, q.Project+"/") {
+			continue
+		}
+		if search != "" && !matchesSearch(e, search) {
+			continue
+		}
+		matched = append(matched, e)
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		if less(matched[i], matched[j]) {
+			return true
+		}
+		if less(matched[j], matched[i]) {
+			return false
+		}
+		return matched[i].ID < matched[j].ID // stable pages
+	})
+
+	total := len(matched)
+	start := min(max(q.Offset, 0), total)
+	end := total
+	if q.Limit > 0 {
+		end = min(start+q.Limit, total)
+	}
+
+	ids := make([]string, 0, end-start)
+	for _, e := range matched[start:end] {
+		ids = append(ids, e.ID)
+	}
+	return ids, total, nil
+}
+
+func entryOrder(sortBy string) (func(a, b scriptEntry) bool, error) {
+	if sortBy == "" {
+		sortBy = "-updatedAt"
+	}
+	field, desc := strings.CutPrefix(sortBy, "-")
+
+	var less func(a, b scriptEntry) bool
+	switch field {
+	case "name":
+		less = func(a, b scriptEntry) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) }
+	case "createdAt":
+		less = func(a, b scriptEntry) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updatedAt":
+		less = func(a, b scriptEntry) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	default:
+		return nil, ErrInvalidSort
+	}
+
+	if desc {
+		return func(a, b scriptEntry) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSearch(e scriptEntry, search string) bool {
+	fields := append([]string{e.ID, e.Name, e.Description}, e.Tags...)
+	for _, f := range fields {
+		if strings.Contains(strings.ToLower(f), search) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQueryEntries(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	entries := []scriptEntry{
+		{ID: "a", Name: "Checkout", Tags: []string{"smoke", "Shop"}, Project: "shop", Owner: "ann", CreatedAt: day(1), UpdatedAt: day(5)},
+		{ID: "b", Name: "browse", Tags: []string{"shop"}, Project: "shop/web", Owner: "bob", CreatedAt: day(2), UpdatedAt: day(4)},
+		{ID: "c", Name: "Admin", Tags: []string{"smoke"}, Project: "shopping", Owner: "Ann", CreatedAt: day(3), UpdatedAt: day(3)},
+		{ID: "d", Name: "api", Description: "Shop API", Project: "api", CreatedAt: day(4), UpdatedAt: day(2)},
+		{ID: "e", Name: "Browse", Project: "shop", CreatedAt: day(4), UpdatedAt: day(1)},
+	}
+
+	tests := []struct {
+		name  string
+		q     ScriptQuery
+		ids   []string
+		total int
+	}{
+		{"default is newest update first", ScriptQuery{}, []string{"a", "b", "c", "d", "e"}, 5},
+		{"name ignores case, ties by ID", ScriptQuery{Sort: "name"}, []string{"c", "d", "b", "e", "a"}, 5},
+		{"name descending", ScriptQuery{Sort: "-name"}, []string{"a", "b", "e", "d", "c"}, 5},
+		{"createdAt ties by ID", ScriptQuery{Sort: "createdAt"}, []string{"a", "b", "c", "d", "e"}, 5},
+		{"createdAt descending", ScriptQuery{Sort: "-createdAt"}, []string{"d", "e", "c", "b", "a"}, 5},
+		{"tag ignores case", ScriptQuery{Tag: "SHOP", Sort: "name"}, []string{"b", "a"}, 2},
+		{"project includes nested folders", ScriptQuery{Project: "shop", Sort: "name"}, []string{"b", "e", "a"}, 3},
+		{"project is not a name prefix", ScriptQuery{Project: "shop/web"}, []string{"b"}, 1},
+		{"owner ignores case", ScriptQuery{Owner: "ann", Sort: "name"}, []string{"c", "a"}, 2},
+		{"tag and project together", ScriptQuery{Tag: "smoke", Project: "shop"}, []string{"a"}, 1},
+		{"search covers description and tags", ScriptQuery{Search: " shop ", Sort: "name"}, []string{"d", "b", "a"}, 3},
+		{"first page", ScriptQuery{Sort: "name", Limit: 2}, []string{"c", "d"}, 5},
+		{"middle page", ScriptQuery{Sort: "name", Offset: 2, Limit: 2}, []string{"b", "e"}, 5},
+		{"last page is short", ScriptQuery{Sort: "name", Offset: 4, Limit: 2}, []string{"a"}, 5},
+		{"past the end", ScriptQuery{Sort: "name", Offset: 6, Limit: 2}, []string{}, 5},
+		{"filtered page counts matches", ScriptQuery{Project: "shop", Sort: "name", Offset: 1, Limit: 1}, []string{"e"}, 3},
+		{"no match", ScriptQuery{Tag: "none"}, []string{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, total, err := queryEntries(entries, tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) || total != tt.total {
+				t.Errorf("got %v (total %d), want %v (total %d)", ids, total, tt.ids, tt.total)
+			}
+		})
+	}
+
+	if _, _, err := queryEntries(entries, ScriptQuery{Sort: "owner"}); !errors.Is(err, ErrInvalidSort) {
+		t.Errorf("sort by owner = %v, want ErrInvalidSort", err)
+	}
+}
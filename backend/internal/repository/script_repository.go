@@ -1,9 +1,29 @@
 package repository
 
-import "k6clone/internal/core/model"
+import (
+	"errors"
+
+	"k6clone/internal/core/model"
+)
+
+var (
+	ErrScriptNotFound = errors.New("script not found")
+
+	// ErrVersionConflict means the script was saved by someone else
+	// since the version an update was based on
+	ErrVersionConflict = errors.New("script was modified by another update")
+)
 
 type ScriptRepository interface {
 	Save(script *model.Script) error
 	FindByID(id string) (*model.Script, error)
 	FindAll() ([]*model.Script, error)
+
+	// List returns the page of scripts q selects and how many matched
+	List(q ScriptQuery) ([]*model.Script, int, error)
+
+	// Update replaces a stored script if it is still at expectedVersion
+	// and moves it to the next version
+	Update(script *model.Script, expectedVersion int) error
+	Delete(id string) error
 }
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"k6clone/internal/core/model"
+)
+
+func scriptRepositories(t *testing.T) map[string]func() ScriptRepository {
+	return map[string]func() ScriptRepository{
+		"memory": func() ScriptRepository { return NewMemoryScriptRepository() },
+		"file":   func() ScriptRepository { return NewFileScriptRepository(t.TempDir()) },
+	}
+}
+
+func TestScriptRepositoryUpdateVersionConflict(t *testing.T) {
+	for name, newRepo := range scriptRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+			if err := repo.Save(&model.Script{ID: "s1", Name: "v1", Version: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := repo.Update(&model.Script{ID: "s1", Name: "v2"}, 1); err != nil {
+				t.Fatalf("Update from the current version: %v", err)
+			}
+
+			// A second writer still holding version 1 loses
+			err := repo.Update(&model.Script{ID: "s1", Name: "stale"}, 1)
+			if !errors.Is(err, ErrVersionConflict) {
+				t.Fatalf("stale Update = %v, want ErrVersionConflict", err)
+			}
+
+			stored, err := repo.FindByID("s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != "v2" || stored.Version != 2 {
+				t.Errorf("stored %q version %d, want v2 version 2", stored.Name, stored.Version)
+			}
+
+			if err := repo.Update(&model.Script{ID: "nope"}, 1); !errors.Is(err, ErrScriptNotFound) {
+				t.Errorf("Update of a missing script = %v, want ErrScriptNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileScriptRepositoryUpdateConflictAfterReload(t *testing.T) {
+	dir := t.TempDir()
+	repo := NewFileScriptRepository(dir)
+	if err := repo.Save(&model.Script{ID: "s1", Name: "v1", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(&model.Script{ID: "s1", Name: "v2"}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The version is checked against what is on disk, not just in memory
+	reopened := NewFileScriptRepository(dir)
+	if err := reopened.Update(&model.Script{ID: "s1", Name: "stale"}, 1); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("stale Update after reopening = %v, want ErrVersionConflict", err)
+	}
+	if err := reopened.Update(&model.Script{ID: "s1", Name: "v3"}, 2); err != nil {
+		t.Errorf("Update from version 2 after reopening: %v", err)
+	}
+}
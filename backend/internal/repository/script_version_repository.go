@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"k6clone/internal/core/model"
+)
+
+var ErrVersionNotFound = errors.New("script version not found")
+
+// ScriptVersionRepository keeps every saved version of each script
+type ScriptVersionRepository interface {
+	Save(scriptID string, version model.ScriptVersion) error
+
+	// FindByScriptID returns a script's versions, oldest first
+	FindByScriptID(scriptID string) ([]model.ScriptVersion, error)
+	Find(scriptID string, version int) (*model.ScriptVersion, error)
+	DeleteByScriptID(scriptID string) error
+}
+
+type MemoryScriptVersionRepository struct {
+	mu       sync.RWMutex
+	versions map[string][]model.ScriptVersion
+}
+
+func NewMemoryScriptVersionRepository() *MemoryScriptVersionRepository {
+	return &MemoryScriptVersionRepository{
+		versions: make(map[string][]model.ScriptVersion),
+	}
+}
+
+func (r *MemoryScriptVersionRepository) Save(scriptID string, version model.ScriptVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.versions[scriptID]
+	for i, v := range versions {
+		if v.Version == version.Version {
+			versions[i] = version
+			return nil
+		}
+	}
+	versions = append(versions, version)
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Version < versions[j].Version
+	})
+	r.versions[scriptID] = versions
+	return nil
+}
+
+func (r *MemoryScriptVersionRepository) FindByScriptID(scriptID string) ([]model.ScriptVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]model.ScriptVersion(nil), r.versions[scriptID]...), nil
+}
+
+func (r *MemoryScriptVersionRepository) Find(scriptID string, version int) (*model.ScriptVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, v := range r.versions[scriptID] {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+func (r *MemoryScriptVersionRepository) DeleteByScriptID(scriptID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.versions, scriptID)
+	return nil
+}
@@ -10,6 +10,11 @@ type TestResultRepository interface {
 	Save(result model.TestResult)
 	FindAll() []model.TestResult
 	FindByScriptID(scriptID string) []model.TestResult
+	FindByTestID(testID string) (model.TestResult, bool)
+
+	// DeleteByScriptID removes a script's results and returns how many
+	// there were
+	DeleteByScriptID(scriptID string) int
 }
 
 type MemoryTestResultRepository struct {
@@ -47,3 +52,30 @@ func (r *MemoryTestResultRepository) FindByScriptID(scriptID string) []model.Tes
 	}
 	return filtered
 }
+
+func (r *MemoryTestResultRepository) FindByTestID(testID string) (model.TestResult, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, result := range r.results {
+		if result.TestID == testID {
+			return result, true
+		}
+	}
+	return model.TestResult{}, false
+}
+
+func (r *MemoryTestResultRepository) DeleteByScriptID(scriptID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.results[:0]
+	for _, result := range r.results {
+		if result.ScriptID != scriptID {
+			kept = append(kept, result)
+		}
+	}
+	deleted := len(r.results) - len(kept)
+	r.results = kept
+	return deleted
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"k6clone/internal/core/model"
+)
+
+// DiffScripts compares two scripts field by field through their JSON
+// form, so the paths match what the API returns. Lists such as steps
+// are aligned on unchanged elements first: inserting a step reports
+// one added step rather than every later step as changed.
+func DiffScripts(from, to *model.Script) ([]model.ScriptChange, error) {
+	a, err := toJSONValue(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toJSONValue(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []model.ScriptChange{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func toJSONValue(script *model.Script) (interface{}, error) {
+	data, err := json.Marshal(script)
+	if err != nil {
+		return nil, err
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	// Every save bumps the version and the update time, so they are
+	// never a change worth reporting
+	delete(v, "version")
+	delete(v, "updatedAt")
+	return v, nil
+}
+
+func diffValues(path string, a, b interface{}, changes *[]model.ScriptChange) {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			diffObjects(path, av, bv, changes)
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			diffLists(path, av, bv, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, model.ScriptChange{Path: path, Op: "changed", Old: a, New: b})
+	}
+}
+
+func diffObjects(path string, a, b map[string]interface{}, changes *[]model.ScriptChange) {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		p := k
+		if path != "" {
+			p = path + "." + k
+		}
+
+		av, inA := a[k]
+		bv, inB := b[k]
+		switch {
+		case !inA:
+			*changes = append(*changes, model.ScriptChange{Path: p, Op: "added", New: bv})
+		case !inB:
+			*changes = append(*changes, model.ScriptChange{Path: p, Op: "removed", Old: av})
+		default:
+			diffValues(p, av, bv, changes)
+		}
+	}
+}
+
+// diffLists matches equal elements with a longest common subsequence.
+// Between matches, elements are paired up and compared field by field;
+// the rest are added or removed. Removed elements are addressed by
+// their old index, the others by their new one.
+func diffLists(path string, a, b []interface{}, changes *[]model.ScriptChange) {
+	// lcs[i][j] is the length of the common subsequence of a[i:], b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if reflect.DeepEqual(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	index := func(i int) string { return path + "[" + strconv.Itoa(i) + "]" }
+
+	// flush reports the unmatched elements a[i:ei] and b[j:ej]
+	flush := func(i, ei, j, ej int) {
+		for ; i < ei && j < ej; i, j = i+1, j+1 {
+			diffValues(index(j), a[i], b[j], changes)
+		}
+		for ; i < ei; i++ {
+			*changes = append(*changes, model.ScriptChange{Path: index(i), Op: "removed", Old: a[i]})
+		}
+		for ; j < ej; j++ {
+			*changes = append(*changes, model.ScriptChange{Path: index(j), Op: "added", New: b[j]})
+		}
+	}
+
+	i, j := 0, 0
+	si, sj := 0, 0 // start of the current unmatched run
+	for i < len(a) && j < len(b) {
+		switch {
+		case reflect.DeepEqual(a[i], b[j]):
+			flush(si, i, sj, j)
+			i, j = i+1, j+1
+			si, sj = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	flush(si, len(a), sj, len(b))
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"k6clone/internal/core/model"
+)
+
+const (
+	maxNameLength = 200
+	maxTags       = 20
+	maxTagLength  = 50
+)
+
+func validateMetadata(script *model.Script) error {
+	if len(script.Name) > maxNameLength {
+		return errors.New("name must be at most 200 characters")
+	}
+	if len(script.Tags) > maxTags {
+		return errors.New("a script can have at most 20 tags")
+	}
+	for _, tag := range script.Tags {
+		if len(tag) > maxTagLength {
+			return errors.New("tags must be at most 50 characters")
+		}
+	}
+	return nil
+}
+
+// normalizeMetadata tidies what users type: surrounding spaces, empty
+// and repeated tags, and slashes around the project. A script without
+// a name is named after its first request's host and path.
+func normalizeMetadata(script *model.Script) {
+	script.Name = strings.TrimSpace(script.Name)
+	script.Owner = strings.TrimSpace(script.Owner)
+	script.Project = strings.Trim(strings.TrimSpace(script.Project), "/")
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range script.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	script.Tags = tags
+
+	if script.Name == "" {
+		script.Name = defaultScriptName(script)
+	}
+}
+
+func defaultScriptName(script *model.Script) string {
+	if script.Source != "" {
+		return "k6 script"
+	}
+	for _, step := range script.Steps {
+		u, err := url.Parse(step.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if path := strings.TrimSuffix(u.Path, "/"); path != "" {
+			return u.Host + path
+		}
+		return u.Host
+	}
+	return "Untitled script"
+}
@@ -1,6 +1,13 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"k6clone/internal/core/engine"
 	"k6clone/internal/core/generator"
 	"k6clone/internal/core/model"
 	"k6clone/internal/repository"
@@ -9,12 +16,23 @@ import (
 type ScriptService struct {
 	generator generator.Generator
 	repo      repository.ScriptRepository
+	versions  repository.ScriptVersionRepository
+	results   repository.TestResultRepository
 }
 
-func NewScriptService(g generator.Generator, r repository.ScriptRepository) *ScriptService {
+// NewScriptService creates the service. results may be nil when deleted
+// scripts never need their results removed.
+func NewScriptService(
+	g generator.Generator,
+	r repository.ScriptRepository,
+	versions repository.ScriptVersionRepository,
+	results repository.TestResultRepository,
+) *ScriptService {
 	return &ScriptService{
 		generator: g,
 		repo:      r,
+		versions:  versions,
+		results:   results,
 	}
 }
 
@@ -24,7 +42,7 @@ func (s *ScriptService) CreateFromURL(url string) (*model.Script, error) {
 		return nil, err
 	}
 
-	err = s.repo.Save(script)
+	err = s.save(script, "", "")
 	if err != nil {
 		return nil, err
 	}
@@ -32,6 +50,42 @@ func (s *ScriptService) CreateFromURL(url string) (*model.Script, error) {
 	return script, nil
 }
 
+// CreateFromCrawl builds a browsing script by crawling the site at url
+func (s *ScriptService) CreateFromCrawl(url string, opts generator.CrawlOptions) (*model.Script, error) {
+	crawler, ok := s.generator.(generator.Crawler)
+	if !ok {
+		return nil, errors.New("crawling is not supported")
+	}
+
+	script, err := crawler.Crawl(url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(script, "", ""); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+// Import validates and stores a script built by one of the importers
+func (s *ScriptService) Import(script *model.Script) (*model.Script, error) {
+	if script.ID == "" {
+		script.ID = uuid.NewString()
+	}
+
+	if err := ValidateScript(script); err != nil {
+		return nil, err
+	}
+
+	if err := s.save(script, "", ""); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
 func (s *ScriptService) GetByID(id string) (*model.Script, error) {
 	return s.repo.FindByID(id)
 }
@@ -39,3 +93,182 @@ func (s *ScriptService) GetByID(id string) (*model.Script, error) {
 func (s *ScriptService) GetAll() ([]*model.Script, error) {
 	return s.repo.FindAll()
 }
+
+// List returns the page of scripts q selects and how many matched
+func (s *ScriptService) List(q repository.ScriptQuery) ([]*model.Script, int, error) {
+	return s.repo.List(q)
+}
+
+// Update replaces a stored script with an edited one. expectedVersion
+// is the version the edit started from; if the script has been saved
+// since, repository.ErrVersionConflict is returned.
+func (s *ScriptService) Update(id string, script *model.Script, expectedVersion int, author string) (*model.Script, error) {
+	if script.ID != "" && script.ID != id {
+		return nil, errors.New("script id does not match the url")
+	}
+	script.ID = id
+
+	if err := ValidateScript(script); err != nil {
+		return nil, err
+	}
+
+	if err := s.update(script, expectedVersion, author, ""); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+// Delete removes a script. With cascade its test results go too;
+// otherwise they stay in the history. It returns the number of results
+// removed.
+func (s *ScriptService) Delete(id string, cascade bool) (int, error) {
+	if err := s.repo.Delete(id); err != nil {
+		return 0, err
+	}
+	if err := s.versions.DeleteByScriptID(id); err != nil {
+		return 0, err
+	}
+
+	if !cascade || s.results == nil {
+		return 0, nil
+	}
+	return s.results.DeleteByScriptID(id), nil
+}
+
+// Clone stores a copy of a script under a new ID
+func (s *ScriptService) Clone(id string, author string) (*model.Script, error) {
+	stored, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	script, err := cloneScript(stored)
+	if err != nil {
+		return nil, err
+	}
+	script.ID = uuid.NewString()
+	script.Name = stored.Name + " (copy)"
+	if author != "" {
+		script.Owner = author
+	}
+
+	note := "clone of " + id + " version " + strconv.Itoa(stored.Version)
+	if err := s.save(script, author, note); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+// Correlate runs a script once and proposes extractions for the values
+// its requests hardcode
+func (s *ScriptService) Correlate(id string) (*engine.CorrelationReport, error) {
+	script, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return engine.Correlate(nil, script)
+}
+
+// ApplyCorrelations rewrites a stored script with accepted proposals
+// and returns it with the number of values replaced
+func (s *ScriptService) ApplyCorrelations(id string, proposals []engine.Correlation) (*model.Script, int, error) {
+	stored, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Work on a copy so a rejected rewrite leaves the stored script alone
+	script, err := cloneScript(stored)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	replaced, err := engine.ApplyCorrelations(script, proposals)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := ValidateScript(script); err != nil {
+		return nil, 0, err
+	}
+	if err := s.update(script, stored.Version, "", "applied correlations"); err != nil {
+		return nil, 0, err
+	}
+
+	return script, replaced, nil
+}
+
+// save stores a new script as version 1, or as the next version when
+// it replaces one with the same ID, and records it in the history
+func (s *ScriptService) save(script *model.Script, author, note string) error {
+	now := time.Now()
+	script.Version, script.CreatedAt, script.UpdatedAt = 1, now, now
+	if existing, err := s.repo.FindByID(script.ID); err == nil {
+		script.Version = existing.Version + 1
+		script.CreatedAt = existing.CreatedAt
+	}
+	if script.Owner == "" {
+		script.Owner = author
+	}
+	normalizeMetadata(script)
+
+	if err := s.repo.Save(script); err != nil {
+		return err
+	}
+	return s.record(script, author, note)
+}
+
+// update stores the next version of a script and records it in the
+// history
+func (s *ScriptService) update(script *model.Script, expectedVersion int, author, note string) error {
+	current, err := s.repo.FindByID(script.ID)
+	if err != nil {
+		return err
+	}
+
+	// Scripts saved before versioning enter the history as they were
+	// when first changed
+	if current.Version == expectedVersion {
+		if _, err := s.versions.Find(script.ID, current.Version); errors.Is(err, repository.ErrVersionNotFound) {
+			if err := s.record(current, "", ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	script.CreatedAt = current.CreatedAt
+	script.UpdatedAt = time.Now()
+	normalizeMetadata(script)
+
+	if err := s.repo.Update(script, expectedVersion); err != nil {
+		return err
+	}
+	return s.record(script, author, note)
+}
+
+func (s *ScriptService) record(script *model.Script, author, note string) error {
+	snapshot, err := cloneScript(script)
+	if err != nil {
+		return err
+	}
+	return s.versions.Save(script.ID, model.ScriptVersion{
+		Version: script.Version,
+		SavedAt: time.Now(),
+		Author:  author,
+		Note:    note,
+		Script:  snapshot,
+	})
+}
+
+func cloneScript(script *model.Script) (*model.Script, error) {
+	data, err := json.Marshal(script)
+	if err != nil {
+		return nil, err
+	}
+	var out model.Script
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+
+	"k6clone/internal/core/model"
+)
+
+// Versions lists a script's saved versions, oldest first
+func (s *ScriptService) Versions(id string) ([]model.ScriptVersion, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, err
+	}
+	return s.versions.FindByScriptID(id)
+}
+
+// Version returns one saved version of a script
+func (s *ScriptService) Version(id string, version int) (*model.ScriptVersion, error) {
+	if _, err := s.repo.FindByID(id); err != nil {
+		return nil, err
+	}
+	return s.versions.Find(id, version)
+}
+
+// Diff compares two saved versions of a script. A to of 0 compares
+// against the current script.
+func (s *ScriptService) Diff(id string, from, to int) (*model.ScriptDiff, error) {
+	current, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if to == 0 {
+		to = current.Version
+	}
+
+	older, err := s.versionScript(current, from)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.versionScript(current, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := DiffScripts(older, newer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ScriptDiff{ScriptID: id, From: from, To: to, Changes: changes}, nil
+}
+
+// Rollback saves an old version's content as the script's next
+// version, so the rollback itself stays in the history.
+// expectedVersion guards against concurrent edits like Update; 0 rolls
+// back whichever version is current.
+func (s *ScriptService) Rollback(id string, version, expectedVersion int, author string) (*model.Script, error) {
+	current, err := s.repo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if version == current.Version {
+		return nil, errors.New("version " + strconv.Itoa(version) + " is already the current version")
+	}
+	if expectedVersion == 0 {
+		expectedVersion = current.Version
+	}
+
+	old, err := s.versions.Find(id, version)
+	if err != nil {
+		return nil, err
+	}
+	script, err := cloneScript(old.Script)
+	if err != nil {
+		return nil, err
+	}
+	script.ID = id
+
+	if err := ValidateScript(script); err != nil {
+		return nil, err
+	}
+
+	note := "rollback to version " + strconv.Itoa(version)
+	if err := s.update(script, expectedVersion, author, note); err != nil {
+		return nil, err
+	}
+
+	return script, nil
+}
+
+// versionScript returns the script at a version. The current version
+// may predate the history, so it comes from the script itself.
+func (s *ScriptService) versionScript(current *model.Script, version int) (*model.Script, error) {
+	if version == current.Version {
+		return current, nil
+	}
+	v, err := s.versions.Find(current.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	return v.Script, nil
+}
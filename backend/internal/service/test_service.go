@@ -1,26 +1,45 @@
 package service
 
 import (
+	"errors"
+
+	"github.com/google/uuid"
 	"k6clone/internal/core/engine"
 	"k6clone/internal/core/model"
 	"k6clone/internal/repository"
 )
 
+// Runner executes a script. The built-in engine and the external k6
+// runner both implement it.
+type Runner interface {
+	Run(script *model.Script, config model.TestConfig) (model.TestResult, error)
+
+	// Version identifies the engine for run snapshots
+	Version() string
+}
+
+var ErrTestNotFound = errors.New("test not found")
+
 type TestService struct {
 	scriptRepo repository.ScriptRepository
 	resultRepo repository.TestResultRepository
 	engine     *engine.LoadEngine
+	k6Runner   Runner
 }
 
+// NewTestService creates the service. k6Runner may be nil when no k6
+// binary is configured.
 func NewTestService(
 	scriptRepo repository.ScriptRepository,
 	resultRepo repository.TestResultRepository,
 	engine *engine.LoadEngine,
+	k6Runner Runner,
 ) *TestService {
 	return &TestService{
 		scriptRepo: scriptRepo,
 		resultRepo: resultRepo,
 		engine:     engine,
+		k6Runner:   k6Runner,
 	}
 }
 
@@ -36,15 +55,121 @@ func (s *TestService) RunTest(config model.TestConfig) (model.TestResult, error)
 		return model.TestResult{}, err
 	}
 
+	// k6 scripts may take VUs and duration from their options, step
+	// scripts from their scenario
+	if script.Source == "" {
+		config = applyScenario(script.Scenario, config)
+		if config.VUs <= 0 {
+			return model.TestResult{}, errors.New("vus must be greater than 0")
+		}
+		if config.Duration <= 0 && (script.Scenario == nil || script.Scenario.Iterations <= 0) {
+			return model.TestResult{}, errors.New("duration must be greater than 0")
+		}
+	}
+
+	return s.run(script, config, "")
+}
+
+// Rerun repeats a stored test with the script and config it ran with,
+// even if the script has since been edited or deleted
+func (s *TestService) Rerun(testID string) (model.TestResult, error) {
+	previous, ok := s.resultRepo.FindByTestID(testID)
+	if !ok {
+		return model.TestResult{}, ErrTestNotFound
+	}
+	if previous.Snapshot == nil {
+		return model.TestResult{}, errors.New("test " + testID + " predates run snapshots and cannot be rerun")
+	}
+
+	if err := ValidateScript(previous.Snapshot.Script); err != nil {
+		return model.TestResult{}, err
+	}
+
+	return s.run(previous.Snapshot.Script, previous.Snapshot.Config, testID)
+}
+
+// run executes a script with a complete config and saves the result
+// with a snapshot of both
+func (s *TestService) run(script *model.Script, config model.TestConfig, rerunOf string) (model.TestResult, error) {
+	runner, err := s.runner(config.Engine)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	// Copy the script first so the snapshot is what actually ran
+	snapshot, err := cloneScript(script)
+	if err != nil {
+		return model.TestResult{}, err
+	}
+
+	// Runners only name tests after the second they started, which is
+	// not unique enough to rerun or follow a test by
+	config.TestID = uuid.NewString()
+
 	// 3. Execute the test using K6
-	result := s.engine.Run(script, config)
+	result, err := runner.Run(script, config)
+	if err != nil {
+		return model.TestResult{}, err
+	}
 
-	// 4. Save the result
+	// 4. Save the result with the version that ran, so later edits
+	// don't change what it describes
+	result.TestID = config.TestID
+	config.TestID = ""
+	result.ScriptVersion = script.Version
+	result.Snapshot = &model.RunSnapshot{
+		Script:        snapshot,
+		Config:        config,
+		Executor:      engine.LoadExecutor(script, config),
+		Thresholds:    thresholdExprs(result.Thresholds),
+		EngineVersion: runner.Version(),
+	}
+	result.RerunOf = rerunOf
 	s.resultRepo.Save(result)
 
 	return result, nil
 }
 
+// thresholdExprs groups the evaluated thresholds by metric
+func thresholdExprs(results []model.ThresholdResult) map[string][]string {
+	if len(results) == 0 {
+		return nil
+	}
+	th := make(map[string][]string)
+	for _, r := range results {
+		th[r.Metric] = append(th[r.Metric], r.Threshold)
+	}
+	return th
+}
+
+// applyScenario fills the VUs and duration a test leaves unset from
+// the script's scenario
+func applyScenario(sc *model.Scenario, config model.TestConfig) model.TestConfig {
+	if sc == nil {
+		return config
+	}
+	if config.VUs == 0 {
+		config.VUs = sc.VUs
+	}
+	if config.Duration == 0 {
+		config.Duration = sc.Duration
+	}
+	return config
+}
+
+func (s *TestService) runner(name string) (Runner, error) {
+	switch name {
+	case "", model.NativeEngine:
+		return s.engine, nil
+	case model.K6Engine:
+		if s.k6Runner == nil {
+			return nil, errors.New("k6 engine is not configured")
+		}
+		return s.k6Runner, nil
+	}
+	return nil, errors.New("unknown engine: " + name)
+}
+
 // GetTestHistory retrieves all test results
 func (s *TestService) GetTestHistory() []model.TestResult {
 	return s.resultRepo.FindAll()
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"k6clone/internal/core/model"
+	"k6clone/internal/repository"
+)
+
+// secondRunner names every test after the same second, as the engines
+// do for runs started together
+type secondRunner struct {
+	startedAt time.Time
+	runs      int
+}
+
+func (r *secondRunner) Run(script *model.Script, config model.TestConfig) (model.TestResult, error) {
+	r.runs++
+	return model.TestResult{
+		TestID:        r.startedAt.Format("20060102150405"),
+		ScriptID:      config.ScriptID,
+		TotalRequests: r.runs,
+		StartedAt:     r.startedAt,
+	}, nil
+}
+
+func (r *secondRunner) Version() string { return "fake" }
+
+func TestRunsInTheSameSecondAreKeptAndRerunnable(t *testing.T) {
+	scripts := repository.NewMemoryScriptRepository()
+	script := &model.Script{ID: "s1", Steps: []model.Step{{Type: model.HTTP, Method: "GET", URL: "http://localhost/"}}}
+	if err := scripts.Save(script); err != nil {
+		t.Fatal(err)
+	}
+
+	results := repository.NewFileTestResultRepository(t.TempDir())
+	runner := &secondRunner{startedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}
+	svc := NewTestService(scripts, results, nil, runner)
+
+	config := model.TestConfig{ScriptID: "s1", VUs: 1, Duration: 1, Engine: model.K6Engine}
+	first, err := svc.RunTest(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.RunTest(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.TestID == second.TestID {
+		t.Fatalf("both runs got test ID %s", first.TestID)
+	}
+	if got := len(results.FindAll()); got != 2 {
+		t.Fatalf("stored %d results, want 2", got)
+	}
+
+	for _, previous := range []model.TestResult{first, second} {
+		stored, ok := results.FindByTestID(previous.TestID)
+		if !ok {
+			t.Fatalf("result %s not found", previous.TestID)
+		}
+		if stored.TotalRequests != previous.TotalRequests {
+			t.Errorf("result %s has %d requests, want %d", previous.TestID, stored.TotalRequests, previous.TotalRequests)
+		}
+
+		rerun, err := svc.Rerun(previous.TestID)
+		if err != nil {
+			t.Fatalf("Rerun(%s): %v", previous.TestID, err)
+		}
+		if rerun.RerunOf != previous.TestID {
+			t.Errorf("rerun of %s has RerunOf %q", previous.TestID, rerun.RerunOf)
+		}
+		if rerun.TestID == previous.TestID {
+			t.Errorf("rerun reused test ID %s", rerun.TestID)
+		}
+	}
+
+	if got := len(results.FindAll()); got != 4 {
+		t.Errorf("stored %d results after the reruns, want 4", got)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 
+	"k6clone/internal/core/engine"
 	"k6clone/internal/core/model"
 )
 
@@ -11,18 +12,73 @@ func ValidateScript(script *model.Script) error {
 		return errors.New("script not found")
 	}
 
+	if err := validateMetadata(script); err != nil {
+		return err
+	}
+
+	if script.Source != "" {
+		return engine.CompileJS(script.Source)
+	}
+
 	if len(script.Steps) == 0 {
 		return errors.New("script has no steps")
 	}
 
 	for _, step := range script.Steps {
-		if step.URL == "" {
-			return errors.New("step url is empty")
+		if err := validateStep(step); err != nil {
+			return err
 		}
-		if step.Method == "" {
-			return errors.New("step method is empty")
+	}
+
+	for i := range script.Datasets {
+		if err := engine.ValidateDataset(&script.Datasets[i]); err != nil {
+			return err
 		}
 	}
 
+	if sc := script.Scenario; sc != nil {
+		if sc.VUs < 0 || sc.Duration < 0 || sc.RampUpSeconds < 0 || sc.Iterations < 0 {
+			return errors.New("scenario settings must not be negative")
+		}
+	}
+
+	if script.Replay != nil && script.Replay.Speed < 0 {
+		return errors.New("replay speed must not be negative")
+	}
+
+	return nil
+}
+
+func validateStep(step model.Step) error {
+	if step.ThinkTimeMs < 0 {
+		return errors.New("thinkTimeMs must not be negative")
+	}
+	if step.Weight < 0 || step.OffsetMs < 0 {
+		return errors.New("weight and offsetMs must not be negative")
+	}
+	if err := validateRules(step); err != nil {
+		return err
+	}
+	return engine.ValidateStep(&step)
+}
+
+func validateRules(step model.Step) error {
+	for _, check := range step.Checks {
+		if err := validateRule(check.Type, check.Expr); err != nil {
+			return err
+		}
+	}
+	for _, ex := range step.Extract {
+		if ex.Var == "" {
+			return errors.New("extraction variable name is empty")
+		}
+		if err := validateRule(ex.Type, ex.Expr); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func validateRule(ruleType model.RuleType, expr string) error {
+	return engine.ValidateRule(ruleType, expr)
+}
@@ -117,7 +117,7 @@ export default function RunTest() {
             <option value="">Choose a script...</option>
             {scripts.map((s) => (
               <option key={s.id} value={s.id}>
-                {s.id.slice(0, 8)}... ({s.steps.length} steps)
+                {s.name || `${s.id.slice(0, 8)}...`} ({s.source ? 'k6 script' : `${(s.steps ?? []).length} steps`})
               </option>
             ))}
           </select>
@@ -99,64 +99,73 @@ export default function Scripts() {
         </div>
       ) : (
         <div className="card-list">
-          {scripts.map((script) => (
-            <div key={script.id} className="card">
-              <div className="card-header">
-                <div>
-                  <h3 style={{ fontSize: '16px', marginBottom: '4px' }}>
-                    Script {script.id.slice(0, 12)}...
-                  </h3>
-                  <p className="text-muted" style={{ fontSize: '13px' }}>
-                    {script.steps.length} step{script.steps.length !== 1 ? 's' : ''}
-                  </p>
+          {scripts.map((script) => {
+            const steps = script.steps ?? []
+            return (
+              <div key={script.id} className="card">
+                <div className="card-header">
+                  <div>
+                    <h3 style={{ fontSize: '16px', marginBottom: '4px' }}>
+                      {script.name || `Script ${script.id.slice(0, 12)}...`}
+                    </h3>
+                    <p className="text-muted" style={{ fontSize: '13px' }}>
+                      {script.source
+                        ? 'k6 script'
+                        : `${steps.length} step${steps.length !== 1 ? 's' : ''}`}
+                      {script.project && ` · ${script.project}`}
+                      {script.tags?.length > 0 && ` · ${script.tags.join(', ')}`}
+                    </p>
+                  </div>
                 </div>
-              </div>
 
-              {/* Script Steps Preview */}
-              <div style={{ margin: '16px 0', padding: '12px', background: '#0f172a', borderRadius: '6px' }}>
-                {script.steps.slice(0, 3).map((step, i) => (
-                  <div key={i} style={{ 
-                    fontSize: '13px', 
-                    color: '#94a3b8',
-                    marginBottom: i < Math.min(script.steps.length, 3) - 1 ? '8px' : '0'
-                  }}>
-                    <span style={{ 
-                      color: '#2563eb', 
-                      fontWeight: '600',
-                      marginRight: '8px'
-                    }}>
-                      {step.method}
-                    </span>
-                    <span style={{ color: '#e5e7eb' }}>
-                      {step.url.length > 50 ? step.url.slice(0, 50) + '...' : step.url}
-                    </span>
+                {/* Script Steps Preview */}
+                {steps.length > 0 && (
+                  <div style={{ margin: '16px 0', padding: '12px', background: '#0f172a', borderRadius: '6px' }}>
+                    {steps.slice(0, 3).map((step, i) => (
+                      <div key={i} style={{ 
+                        fontSize: '13px', 
+                        color: '#94a3b8',
+                        marginBottom: i < Math.min(steps.length, 3) - 1 ? '8px' : '0'
+                      }}>
+                        <span style={{ 
+                          color: '#2563eb', 
+                          fontWeight: '600',
+                          marginRight: '8px'
+                        }}>
+                          {step.method}
+                        </span>
+                        <span style={{ color: '#e5e7eb' }}>
+                          {step.url.length > 50 ? step.url.slice(0, 50) + '...' : step.url}
+                        </span>
+                      </div>
+                    ))}
+                    {steps.length > 3 && (
+                      <p className="text-muted" style={{ fontSize: '12px', marginTop: '8px' }}>
+                        +{steps.length - 3} more steps
+                      </p>
+                    )}
                   </div>
-                ))}
-                {script.steps.length > 3 && (
-                  <p className="text-muted" style={{ fontSize: '12px', marginTop: '8px' }}>
-                    +{script.steps.length - 3} more steps
-                  </p>
                 )}
-              </div>
 
-              <div className="card-actions">
-                <button 
-                  onClick={() => handleView(script)} 
-                  className="btn-secondary"
-                >
-                  <Eye size={16} />
-                  View K6 Script
-                </button>
-                <button 
-                  onClick={() => handleRunTest(script.id)} 
-                  className="btn-primary"
-                >
-                  <Play size={16} />
-                  Run Test
-                </button>
+                <div className="card-actions">
+                  <button 
+                    onClick={() => handleView(script)} 
+                    className="btn-secondary"
+                  >
+                    <Eye size={16} />
+                    View K6 Script
+                  </button>
+                  <button 
+                    onClick={() => handleRunTest(script.id)} 
+                    className="btn-primary"
+                  >
+                    <Play size={16} />
+                    Run Test
+                  </button>
+                </div>
               </div>
-            </div>
-          ))}
+            )
+          })}
         </div>
       )}
 